@@ -0,0 +1,315 @@
+// Package activitypub holds the small slice of ActivityPub this site
+// speaks: the JSON shapes of actors, objects, activities and collections,
+// WebFinger documents, HTTP Signatures, and a client that fetches remote
+// actors and delivers activities to their inboxes.
+//
+// Every request goes through a Doer so tests can point the client at
+// httptest servers; production uses a safehttp client.
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentType is the media type of ActivityPub documents.
+	ContentType = "application/activity+json"
+
+	// JRDContentType is the media type of WebFinger documents.
+	JRDContentType = "application/jrd+json"
+
+	// Public addresses an activity to everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+
+	// maxBodyBytes caps how much of a fetched document is read.
+	maxBodyBytes = 1 << 20
+)
+
+// Context is the @context of every document this site serves.
+var Context = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+// StatusError is returned when a remote server answers with a non-2xx
+// status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("activitypub: %s answered %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Permanent reports whether retrying the request is pointless: any 4xx
+// other than 408 Request Timeout and 429 Too Many Requests.
+func (e *StatusError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests
+}
+
+// PublicKey is an actor's signing key.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Endpoints lists an actor's optional endpoints.
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+// Image is an actor's icon.
+type Image struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// Actor is a Person or any other actor type.
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername,omitempty"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Icon              *Image     `json:"icon,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         *PublicKey `json:"publicKey,omitempty"`
+}
+
+// SharedInbox returns the actor's shared inbox, or "" when it has none.
+func (a Actor) SharedInbox() string {
+	if a.Endpoints == nil {
+		return ""
+	}
+	return a.Endpoints.SharedInbox
+}
+
+// Tag is a hashtag on an object.
+type Tag struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Href string `json:"href,omitempty"`
+}
+
+// Object is a Note, an Article or the Tombstone left by a deleted one.
+type Object struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Content      string   `json:"content,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	Published    string   `json:"published,omitempty"`
+	Updated      string   `json:"updated,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Cc           []string `json:"cc,omitempty"`
+	Tag          []Tag    `json:"tag,omitempty"`
+}
+
+// Activity wraps an object, which is a URL or an embedded document.
+type Activity struct {
+	Context   any      `json:"@context,omitempty"`
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Actor     string   `json:"actor"`
+	Object    any      `json:"object"`
+	Published string   `json:"published,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Cc        []string `json:"cc,omitempty"`
+}
+
+// IncomingActivity is an activity received in an inbox. The object is
+// kept raw because it may be a URL or any kind of document.
+type IncomingActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectRef is the id and type of an activity's object, whichever form
+// it was sent in.
+type ObjectRef struct {
+	ID    string
+	Type  string
+	Actor string
+	// Object is the object's own object, e.g. the followed actor of an
+	// undone Follow.
+	Object string
+}
+
+// Ref decodes the activity's object.
+func (a IncomingActivity) Ref() ObjectRef {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return ObjectRef{ID: id}
+	}
+
+	var doc struct {
+		ID     string          `json:"id"`
+		Type   string          `json:"type"`
+		Actor  string          `json:"actor"`
+		Object json.RawMessage `json:"object"`
+	}
+	if json.Unmarshal(a.Object, &doc) != nil {
+		return ObjectRef{}
+	}
+	ref := ObjectRef{ID: doc.ID, Type: doc.Type, Actor: doc.Actor}
+	if json.Unmarshal(doc.Object, &ref.Object) != nil {
+		var inner struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(doc.Object, &inner)
+		ref.Object = inner.ID
+	}
+	return ref
+}
+
+// OrderedCollection is an outbox or followers collection, or one page
+// of one.
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	First        string `json:"first,omitempty"`
+	Last         string `json:"last,omitempty"`
+	PartOf       string `json:"partOf,omitempty"`
+	Next         string `json:"next,omitempty"`
+	Prev         string `json:"prev,omitempty"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// JRD is a WebFinger JSON Resource Descriptor.
+type JRD struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+// Link is one link in a JRD.
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// IsActivityJSON reports whether an Accept or Content-Type header asks for
+// an ActivityPub document.
+func IsActivityJSON(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		if mediaType == ContentType {
+			return true
+		}
+		if mediaType == "application/ld+json" && params["profile"] == "https://www.w3.org/ns/activitystreams" {
+			return true
+		}
+	}
+	return false
+}
+
+// Doer sends an HTTP request. *http.Client satisfies it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client fetches actors and delivers activities.
+type Client struct {
+	http      Doer
+	userAgent string
+	now       func() time.Time
+}
+
+// NewClient returns a Client that makes its requests through doer.
+func NewClient(doer Doer, userAgent string) *Client {
+	return &Client{http: doer, userAgent: userAgent, now: time.Now}
+}
+
+// FetchActor fetches the actor document at actorURL. Requests are signed
+// with signer because some servers refuse unsigned fetches.
+func (c *Client) FetchActor(ctx context.Context, actorURL string, signer Signer) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType+`, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`)
+	if err := c.prepare(req, nil, signer); err != nil {
+		return Actor{}, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Actor{}, &StatusError{URL: actorURL, StatusCode: resp.StatusCode}
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodyBytes)).Decode(&actor); err != nil {
+		return Actor{}, fmt.Errorf("activitypub: decoding actor %s: %w", actorURL, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" {
+		return Actor{}, fmt.Errorf("activitypub: %s is not an actor", actorURL)
+	}
+	return actor, nil
+}
+
+// Deliver POSTs a serialized activity to inbox, signed by signer.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte, signer Signer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := c.prepare(req, activity, signer); err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: inbox, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func (c *Client) prepare(req *http.Request, body []byte, signer Signer) error {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if signer.Key == nil {
+		return errors.New("activitypub: no signing key")
+	}
+	return signer.Sign(req, body, c.now())
+}
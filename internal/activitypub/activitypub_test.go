@@ -0,0 +1,133 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientDeliver(t *testing.T) {
+	signer, public := testSigner(t)
+	activity := []byte(`{"type":"Create"}`)
+
+	var verifyErr error
+	var got []byte
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		_, verifyErr = Verify(r, got, time.Now(), func(string) (*rsa.PublicKey, error) { return public, nil })
+		if r.Header.Get("Content-Type") != ContentType || r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), "test-agent")
+	if err := client.Deliver(context.Background(), server.URL+"/inbox", activity, signer); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if verifyErr != nil || string(got) != string(activity) {
+		t.Errorf("Expected a verifiable signed delivery, got %q, %v", got, verifyErr)
+	}
+
+	status = http.StatusGone
+	var statusErr *StatusError
+	err := client.Deliver(context.Background(), server.URL+"/inbox", activity, signer)
+	if !errors.As(err, &statusErr) || !statusErr.Permanent() {
+		t.Errorf("Expected a permanent StatusError, got %v", err)
+	}
+
+	status = http.StatusTooManyRequests
+	err = client.Deliver(context.Background(), server.URL+"/inbox", activity, signer)
+	if !errors.As(err, &statusErr) || statusErr.Permanent() {
+		t.Errorf("Expected a retryable StatusError, got %v", err)
+	}
+}
+
+func TestClientFetchActor(t *testing.T) {
+	signer, _ := testSigner(t)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Signature") == "" || !IsActivityJSON(r.Header.Get("Accept")) {
+			http.Error(w, "unsigned", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/users/alice":
+			w.Header().Set("Content-Type", ContentType)
+			json.NewEncoder(w).Encode(Actor{
+				ID:        server.URL + "/users/alice",
+				Type:      "Person",
+				Inbox:     server.URL + "/users/alice/inbox",
+				Endpoints: &Endpoints{SharedInbox: server.URL + "/inbox"},
+			})
+		case "/not-an-actor":
+			w.Write([]byte(`{"type":"Note"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), "")
+	actor, err := client.FetchActor(context.Background(), server.URL+"/users/alice", signer)
+	if err != nil {
+		t.Fatalf("FetchActor: %v", err)
+	}
+	if actor.Inbox != server.URL+"/users/alice/inbox" || actor.SharedInbox() != server.URL+"/inbox" {
+		t.Errorf("Unexpected actor %+v", actor)
+	}
+
+	for _, path := range []string{"/not-an-actor", "/missing"} {
+		if _, err := client.FetchActor(context.Background(), server.URL+path, signer); err == nil {
+			t.Errorf("Expected fetching %s to fail", path)
+		}
+	}
+}
+
+func TestIncomingActivityRef(t *testing.T) {
+	tests := []struct {
+		body string
+		want ObjectRef
+	}{
+		{`{"type":"Follow","object":"https://me.example/ap/actor"}`,
+			ObjectRef{ID: "https://me.example/ap/actor"}},
+		{`{"type":"Undo","object":{"id":"https://a.example/f/1","type":"Follow","actor":"https://a.example/u","object":"https://me.example/ap/actor"}}`,
+			ObjectRef{ID: "https://a.example/f/1", Type: "Follow", Actor: "https://a.example/u", Object: "https://me.example/ap/actor"}},
+		{`{"type":"Undo","object":{"type":"Follow","object":{"id":"https://me.example/ap/actor"}}}`,
+			ObjectRef{Type: "Follow", Object: "https://me.example/ap/actor"}},
+		{`{"type":"Delete"}`, ObjectRef{}},
+	}
+	for _, tt := range tests {
+		var activity IncomingActivity
+		if err := json.Unmarshal([]byte(tt.body), &activity); err != nil {
+			t.Fatal(err)
+		}
+		if got := activity.Ref(); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestIsActivityJSON(t *testing.T) {
+	tests := map[string]bool{
+		"application/activity+json": true,
+		`application/ld+json; profile="https://www.w3.org/ns/activitystreams"`: true,
+		"text/html, application/activity+json;q=0.9":                           true,
+		"application/ld+json": false,
+		"text/html,*/*":       false,
+	}
+	for header, want := range tests {
+		if got := IsActivityJSON(header); got != want {
+			t.Errorf("IsActivityJSON(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
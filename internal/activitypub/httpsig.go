@@ -0,0 +1,276 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP Signatures as the fediverse uses them: draft-cavage-http-signatures
+// with RSA-SHA256 keys, covering the request target, host and date, plus a
+// SHA-256 Digest of the body on POSTs.
+
+const (
+	// keyBits is the size of generated actor keys.
+	keyBits = 2048
+
+	// maxClockSkew is how far a signed request's Date may be from now.
+	maxClockSkew = time.Hour
+)
+
+// ErrSignature is wrapped by every signature verification failure.
+var ErrSignature = errors.New("activitypub: invalid signature")
+
+func signatureError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrSignature, fmt.Sprintf(format, args...))
+}
+
+// GenerateKey returns a new RSA key pair as PKCS#8 and PKIX PEM blocks.
+func GenerateKey() (privatePEM, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", "", err
+	}
+
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+	return privatePEM, publicPEM, nil
+}
+
+// ParsePrivateKey reads a PEM encoded RSA private key.
+func ParsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("activitypub: no PEM block in private key")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("activitypub: private key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey reads a PEM encoded RSA public key, as found in an
+// actor's publicKeyPem.
+func ParsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("activitypub: no PEM block in public key")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("activitypub: public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+// Signer signs outgoing requests as one actor.
+type Signer struct {
+	// KeyID is the URL of the actor's public key, e.g. actor#main-key.
+	KeyID string
+	Key   *rsa.PrivateKey
+}
+
+// Sign adds Date, Digest (when body is not nil) and Signature headers to
+// req. body must be the exact bytes being sent.
+func (s Signer) Sign(req *http.Request, body []byte, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, host, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		s.KeyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// KeyLookup returns the public key for a signature's keyId.
+type KeyLookup func(keyID string) (*rsa.PublicKey, error)
+
+// Verify checks req's Signature header against body, looking the key up
+// with lookup, and returns the keyId that signed it. The signature must
+// cover the request target, host and date, and the digest when there is
+// a body.
+func Verify(req *http.Request, body []byte, now time.Time, lookup KeyLookup) (string, error) {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+
+	keyID := params["keyId"]
+	if keyID == "" || params["signature"] == "" {
+		return "", signatureError("missing keyId or signature")
+	}
+	if alg := params["algorithm"]; alg != "" && alg != "rsa-sha256" && alg != "hs2019" {
+		return "", signatureError("unsupported algorithm %q", alg)
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, name := range required {
+		if !contains(headers, name) {
+			return "", signatureError("signature does not cover %s", name)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", signatureError("bad Date header")
+	}
+	if skew := now.Sub(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", signatureError("Date is too far from now")
+	}
+
+	if len(body) > 0 && !digestMatches(req.Header.Get("Digest"), body) {
+		return "", signatureError("Digest does not match body")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", signatureError("signature is not base64")
+	}
+
+	key, err := lookup(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, req.Host, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig); err != nil {
+		return "", signatureError("signature does not verify")
+	}
+	return keyID, nil
+}
+
+func signingString(req *http.Request, host string, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		var value string
+		switch name {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = host
+		default:
+			value = strings.Join(req.Header.Values(name), ", ")
+		}
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSignature splits a Signature header into its parameters.
+func parseSignature(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, signatureError("missing Signature header")
+	}
+
+	params := map[string]string{}
+	for header != "" {
+		name, rest, ok := strings.Cut(header, "=")
+		if !ok {
+			return nil, signatureError("malformed Signature header")
+		}
+		name = strings.TrimSpace(name)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil, signatureError("unterminated value in Signature header")
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		params[name] = value
+
+		rest = strings.TrimSpace(rest)
+		if rest != "" && !strings.HasPrefix(rest, ",") {
+			return nil, signatureError("malformed Signature header")
+		}
+		header = strings.TrimPrefix(rest, ",")
+	}
+	return params, nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// digestMatches checks a Digest header, which may list several algorithms.
+func digestMatches(header string, body []byte) bool {
+	want := digest(body)
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if alg, _, ok := strings.Cut(value, "="); ok && strings.EqualFold(alg, "SHA-256") {
+			return "SHA-256="+value[len(alg)+1:] == want
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package activitypub
+
+import (
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T) (Signer, *rsa.PublicKey) {
+	t.Helper()
+
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Signer{KeyID: "https://me.example/ap/actor#main-key", Key: key}, public
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, public := testSigner(t)
+	_, otherPublic := testSigner(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"Follow"}`)
+
+	signed := func() *http.Request {
+		req := httptest.NewRequest("POST", "https://remote.example/inbox?x=1", strings.NewReader(string(body)))
+		if err := signer.Sign(req, body, now); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	lookup := func(key *rsa.PublicKey) KeyLookup {
+		return func(keyID string) (*rsa.PublicKey, error) {
+			if keyID != signer.KeyID {
+				t.Errorf("Unexpected keyId %q", keyID)
+			}
+			return key, nil
+		}
+	}
+
+	keyID, err := Verify(signed(), body, now.Add(time.Minute), lookup(public))
+	if err != nil || keyID != signer.KeyID {
+		t.Fatalf("Expected the signature to verify, got %q, %v", keyID, err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey)
+	}{
+		{"other key", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			return body, now, otherPublic
+		}},
+		{"changed body", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			return []byte(`{"type":"Undo"}`), now, public
+		}},
+		{"changed path", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			req.URL.Path = "/other"
+			return body, now, public
+		}},
+		{"changed host", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			req.Host = "evil.example"
+			return body, now, public
+		}},
+		{"stale date", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			return body, now.Add(2 * time.Hour), public
+		}},
+		{"digest not signed", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			sig := req.Header.Get("Signature")
+			req.Header.Set("Signature", strings.Replace(sig, " digest", "", 1))
+			return body, now, public
+		}},
+		{"no signature", func(req *http.Request) ([]byte, time.Time, *rsa.PublicKey) {
+			req.Header.Del("Signature")
+			return body, now, public
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signed()
+			body, now, key := tt.tamper(req)
+			if _, err := Verify(req, body, now, lookup(key)); !errors.Is(err, ErrSignature) {
+				t.Errorf("Expected ErrSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyGet(t *testing.T) {
+	signer, public := testSigner(t)
+	now := time.Now()
+
+	req := httptest.NewRequest("GET", "https://me.example/ap/actor", nil)
+	if err := signer.Sign(req, nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Digest") != "" {
+		t.Error("Expected no Digest on a GET")
+	}
+
+	lookup := func(string) (*rsa.PublicKey, error) { return public, nil }
+	if _, err := Verify(req, nil, now, lookup); err != nil {
+		t.Errorf("Expected a signed GET to verify, got %v", err)
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	params, err := parseSignature(`keyId="https://a.example/u#key", algorithm="hs2019",headers="(request-target) host date",signature="YWJj"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["keyId"] != "https://a.example/u#key" || params["algorithm"] != "hs2019" ||
+		params["headers"] != "(request-target) host date" || params["signature"] != "YWJj" {
+		t.Errorf("Unexpected params %v", params)
+	}
+
+	for _, header := range []string{"", "keyId", `keyId="open`, `keyId="a"junk`} {
+		if _, err := parseSignature(header); !errors.Is(err, ErrSignature) {
+			t.Errorf("Expected %q to be rejected, got %v", header, err)
+		}
+	}
+}
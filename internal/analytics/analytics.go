@@ -0,0 +1,193 @@
+// Package analytics counts page views without cookies and without storing
+// IP addresses. Each visitor is identified by a hash of their IP address
+// and user agent, salted with a random value that changes every UTC day,
+// so a visitor can be counted once per day but not followed across days
+// or identified from the hash.
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+// DayFormat is how days are stored.
+const DayFormat = "2006-01-02"
+
+// botSignatures are substrings of lowercased user agents that belong to
+// crawlers, link previewers, monitors and scripts rather than readers.
+var botSignatures = []string{
+	"bot", "crawl", "spider", "slurp", "archiver", "scraper", "fetcher",
+	"preview", "facebookexternalhit", "embedly", "quora link", "whatsapp",
+	"headless", "lighthouse", "pagespeed", "pingdom", "uptime", "monitor",
+	"curl", "wget", "httpie", "python-", "go-http-client", "java/", "okhttp",
+	"axios", "node-fetch", "libwww", "feed", "rss", "mastodon", "pleroma",
+	"akkoma", "misskey",
+}
+
+// IsBot reports whether userAgent looks like software rather than a
+// person. An empty user agent counts as a bot.
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+	ua := strings.ToLower(userAgent)
+	for _, sig := range botSignatures {
+		if strings.Contains(ua, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that made r. Behind a proxy
+// that is the first address in X-Forwarded-For. It is only used to tell
+// visitors apart, so a forged header does no more than skew the count.
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		if ip := strings.TrimSpace(first); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ReferrerHost returns the host a visitor came from, without "www.", or ""
+// for direct visits, links within the site at siteHost and anything that
+// isn't an http(s) URL. Only the host is kept because paths and query
+// strings can identify people.
+func ReferrerHost(referer, siteHost string) string {
+	u, err := url.Parse(referer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	site := strings.TrimPrefix(strings.ToLower(siteHost), "www.")
+	if h, _, err := net.SplitHostPort(site); err == nil {
+		site = h
+	}
+	if host == site {
+		return ""
+	}
+	return host
+}
+
+// VisitorHash identifies a visitor for one day.
+func VisitorHash(salt, ip, userAgent string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Recorder stores page views and rolls them up into daily totals.
+type Recorder struct {
+	db  *database.Queries
+	now func() time.Time
+
+	mu   sync.Mutex
+	day  string
+	salt string
+}
+
+// NewRecorder returns a Recorder that stores views in db.
+func NewRecorder(db *database.Queries) *Recorder {
+	return &Recorder{db: db, now: time.Now}
+}
+
+// Today returns the current UTC day as stored.
+func (rec *Recorder) Today() string {
+	return rec.now().UTC().Format(DayFormat)
+}
+
+// Record stores a view of path by the visitor who made r, whose site is
+// at siteHost. It reports false without storing anything for bots,
+// prefetches and requests other than GET.
+func (rec *Recorder) Record(ctx context.Context, r *http.Request, path, siteHost string) (bool, error) {
+	if r.Method != http.MethodGet || isPrefetch(r) || IsBot(r.UserAgent()) {
+		return false, nil
+	}
+
+	day, salt, err := rec.dailySalt(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = rec.db.RecordPageView(ctx, database.RecordPageViewParams{
+		Day:      day,
+		Path:     path,
+		Referrer: ReferrerHost(r.Referer(), siteHost),
+		Visitor:  VisitorHash(salt, ClientIP(r), r.UserAgent()),
+	})
+	return err == nil, err
+}
+
+func isPrefetch(r *http.Request) bool {
+	purpose := strings.ToLower(r.Header.Get("Sec-Purpose") + r.Header.Get("Purpose") + r.Header.Get("X-Moz"))
+	return strings.Contains(purpose, "prefetch")
+}
+
+// dailySalt returns today's salt, creating it the first time it's needed.
+// The salt is stored so every server process agrees on it.
+func (rec *Recorder) dailySalt(ctx context.Context) (string, string, error) {
+	day := rec.Today()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.day == day {
+		return day, rec.salt, nil
+	}
+
+	err := rec.db.CreateAnalyticsSalt(ctx, database.CreateAnalyticsSaltParams{Day: day, Salt: rand.Text()})
+	if err != nil {
+		return "", "", err
+	}
+	salt, err := rec.db.GetAnalyticsSalt(ctx, day)
+	if err != nil {
+		return "", "", err
+	}
+	if err := rec.db.DeleteAnalyticsSaltsBefore(ctx, day); err != nil {
+		return "", "", err
+	}
+
+	rec.day, rec.salt = day, salt
+	return day, salt, nil
+}
+
+// Rollup adds the stored views to the daily totals, then deletes views
+// and salts from before today. Today's views are kept so their visitors
+// can still be told apart as the day goes on; rolling up again replaces
+// today's totals. It returns the number of views deleted.
+func (rec *Recorder) Rollup(ctx context.Context) (int64, error) {
+	for _, rollup := range []func(context.Context) error{
+		rec.db.RollupSiteViews,
+		rec.db.RollupPageViews,
+		rec.db.RollupReferrerViews,
+	} {
+		if err := rollup(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	today := rec.Today()
+	if err := rec.db.DeleteAnalyticsSaltsBefore(ctx, today); err != nil {
+		return 0, err
+	}
+	return rec.db.DeletePageViewsBefore(ctx, today)
+}
@@ -0,0 +1,207 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/sql/schema"
+)
+
+const firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+
+func setupRecorder(t *testing.T) (*Recorder, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := schema.Up(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewRecorder(database.New(db)), db
+}
+
+func visit(ip, userAgent, referer string) *http.Request {
+	r := httptest.NewRequest("GET", "https://journal.example/", nil)
+	r.RemoteAddr = ip + ":51234"
+	r.Header.Set("User-Agent", userAgent)
+	if referer != "" {
+		r.Header.Set("Referer", referer)
+	}
+	return r
+}
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      bool
+	}{
+		{firefox, false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1", false},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; bingbot/2.0)", true},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0 Safari/537.36", true},
+		{"facebookexternalhit/1.1", true},
+		{"curl/8.5.0", true},
+		{"python-requests/2.31", true},
+		{"Go-http-client/2.0", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if got := IsBot(tt.userAgent); got != tt.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"https://www.Google.com/search?q=secret", "google.com"},
+		{"https://news.ycombinator.com/item?id=1", "news.ycombinator.com"},
+		{"https://journal.example/journals/2", ""},
+		{"http://www.journal.example/", ""},
+		{"android-app://com.slack", ""},
+		{"not a url", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ReferrerHost(tt.referer, "journal.example:443"); got != tt.want {
+			t.Errorf("ReferrerHost(%q) = %q, want %q", tt.referer, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := visit("10.0.0.1", firefox, "")
+	if ip := ClientIP(r); ip != "10.0.0.1" {
+		t.Errorf("Expected the remote address, got %q", ip)
+	}
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	if ip := ClientIP(r); ip != "203.0.113.7" {
+		t.Errorf("Expected the forwarded address, got %q", ip)
+	}
+}
+
+func TestVisitorHash(t *testing.T) {
+	a := VisitorHash("monday", "203.0.113.7", firefox)
+	if a != VisitorHash("monday", "203.0.113.7", firefox) {
+		t.Error("Expected the same visitor to hash the same on one day")
+	}
+	if a == VisitorHash("tuesday", "203.0.113.7", firefox) {
+		t.Error("Expected a new salt to give a new hash")
+	}
+	if a == VisitorHash("monday", "203.0.113.8", firefox) || a == VisitorHash("monday", "203.0.113.7", "Other") {
+		t.Error("Expected different visitors to hash differently")
+	}
+	if len(a) != 32 {
+		t.Errorf("Expected a 128-bit hex hash, got %q", a)
+	}
+}
+
+func TestRecordAndRollup(t *testing.T) {
+	rec, db := setupRecorder(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	rec.now = func() time.Time { return now }
+
+	record := func(r *http.Request, path string) bool {
+		t.Helper()
+		ok, err := rec.Record(ctx, r, path, "journal.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	record(visit("203.0.113.7", firefox, "https://www.google.com/search?q=x"), "/journals/1")
+	record(visit("203.0.113.7", firefox, ""), "/journals/1")
+	record(visit("203.0.113.7", firefox, ""), "/")
+	record(visit("198.51.100.2", firefox, "https://journal.example/"), "/journals/1")
+
+	if record(visit("198.51.100.3", "Googlebot/2.1", ""), "/") {
+		t.Error("Expected bots not to be counted")
+	}
+	prefetch := visit("198.51.100.3", firefox, "")
+	prefetch.Header.Set("Sec-Purpose", "prefetch;prerender")
+	if record(prefetch, "/") {
+		t.Error("Expected prefetches not to be counted")
+	}
+	head := visit("198.51.100.3", firefox, "")
+	head.Method = http.MethodHead
+	if record(head, "/") {
+		t.Error("Expected HEAD requests not to be counted")
+	}
+
+	var stored string
+	db.QueryRow("SELECT visitor FROM page_views LIMIT 1").Scan(&stored)
+	var leaked int
+	db.QueryRow("SELECT COUNT(*) FROM page_views WHERE visitor LIKE '%203.0.113.7%' OR referrer LIKE '%q=%'").Scan(&leaked)
+	if stored == "" || leaked != 0 {
+		t.Errorf("Expected only hashes and hosts to be stored, got %q", stored)
+	}
+
+	if deleted, err := rec.Rollup(ctx); err != nil || deleted != 0 {
+		t.Fatalf("Expected today's views to be kept, deleted %d: %v", deleted, err)
+	}
+
+	// The same visitor the next day is counted again under a new salt
+	now = now.Add(2 * time.Hour)
+	record(visit("203.0.113.7", firefox, ""), "/journals/1")
+	if deleted, err := rec.Rollup(ctx); err != nil || deleted != 4 {
+		t.Fatalf("Expected yesterday's views to be deleted, deleted %d: %v", deleted, err)
+	}
+	// Rolling up twice changes nothing
+	rec.Rollup(ctx)
+
+	rows, err := db.Query("SELECT day, dimension, value, views, visitors FROM page_view_rollups ORDER BY day, dimension, value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var day, dimension, value string
+		var views, visitors int
+		rows.Scan(&day, &dimension, &value, &views, &visitors)
+		got = append(got, fmt.Sprintf("%s %s %s %d/%d", day, dimension, value, views, visitors))
+	}
+	want := []string{
+		"2026-10-18 page / 1/1",
+		"2026-10-18 page /journals/1 3/2",
+		"2026-10-18 referrer google.com 1/1",
+		"2026-10-18 site  4/2",
+		"2026-10-19 page /journals/1 1/1",
+		"2026-10-19 site  1/1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Unexpected rollups %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Rollup %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	var salts int
+	db.QueryRow("SELECT COUNT(*) FROM analytics_salts").Scan(&salts)
+	if salts != 1 {
+		t.Errorf("Expected only today's salt to be kept, got %d", salts)
+	}
+}
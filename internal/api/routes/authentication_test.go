@@ -12,6 +12,7 @@ import (
 
 	"github.com/sianwa11/my-journal/internal/auth"
 	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/sql/schema"
 )
 
 func setupTestDB(t *testing.T) (*sql.DB, *database.Queries) {
@@ -21,65 +22,11 @@ func setupTestDB(t *testing.T) (*sql.DB, *database.Queries) {
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
+	// Every connection to :memory: gets its own database, so keep to one
+	db.SetMaxOpenConns(1)
 
-	// Create the users table and other necessary tables
-	schema := `
-		CREATE TABLE users (
-			id INTEGER PRIMARY KEY,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			name TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL DEFAULT 'unset'
-		, bio TEXT, email TEXT DEFAULT '', github TEXT DEFAULT '', linkedin TEXT DEFAULT '');
-		CREATE TABLE journal_entries (
-			id INTEGER PRIMARY KEY,
-			title TEXT NOT NULL,
-			content TEXT NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			user_id INTEGER NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-		CREATE TABLE refresh_tokens(
-			id INTEGER PRIMARY KEY,
-			token TEXT NOT NULL,
-			user_id INTEGER NOT NULL,
-			expires_at TIMESTAMP NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-		CREATE TABLE projects(
-			id INTEGER PRIMARY KEY,
-			title TEXT NOT NULL,
-			description TEXT NOT NULL,
-			image_url TEXT,
-			link TEXT,
-			github TEXT,
-			status TEXT CHECK(status IN ('in_progress','completed','archived')) DEFAULT 'completed',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			user_id INTEGER NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE
-			CASCADE
-		);
-		CREATE TABLE tags(
-			id INTEGER PRIMARY KEY,
-			name TEXT UNIQUE NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-		CREATE TABLE project_tags(
-			project_id INTEGER NOT NULL,
-			tag_id INTEGER NOT NULL,
-			FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
-			FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
-			PRIMARY KEY (project_id, tag_id)
-		);
-	`
-
-	_, err = db.Exec(schema)
-	if err != nil {
-		t.Fatalf("Failed to create tables: %v", err)
+	if err := schema.Up(context.Background(), db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
 	queries := database.New(db)
@@ -260,3 +207,64 @@ func TestLoginHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestViewerCookie(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "owner", "password")
+
+	req := httptest.NewRequest("POST", "/api/login", bytes.NewBufferString(`{"name": "owner", "password": "password"}`))
+	rr := httptest.NewRecorder()
+	apiCfg.handleLogin(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var login struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&login)
+
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == viewerCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Expected an HttpOnly, SameSite=Lax viewer cookie, got %+v", cookie)
+	}
+
+	// Pages opened in the browser carry the cookie but no bearer token
+	page := httptest.NewRequest("GET", "/journals/1", nil)
+	page.AddCookie(cookie)
+	if id := apiCfg.viewerID(page); id != int(user.ID) {
+		t.Errorf("Expected the cookie to identify the owner, got %d", id)
+	}
+
+	forged := httptest.NewRequest("GET", "/journals/1", nil)
+	forged.AddCookie(&http.Cookie{Name: viewerCookie, Value: "not-a-jwt"})
+	if id := apiCfg.viewerID(forged); id != 0 {
+		t.Errorf("Expected an invalid cookie to be ignored, got %d", id)
+	}
+
+	// The cookie is not a login for routes that change data
+	called := false
+	protected := apiCfg.middlewareMustBeLoggedIn(func(w http.ResponseWriter, r *http.Request) { called = true })
+	post := httptest.NewRequest("POST", "/api/journals", nil)
+	post.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	protected(rr, post)
+	if called || rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the cookie alone to be refused, got %d", rr.Code)
+	}
+
+	// Logging out clears it
+	revoke := httptest.NewRequest("POST", "/api/revoke", nil)
+	revoke.Header.Set("Authorization", "Bearer "+login.RefreshToken)
+	rr = httptest.NewRecorder()
+	apiCfg.handleRevokeToken(rr, revoke)
+	if cleared := rr.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("Expected the cookie to be cleared, got %+v", cleared)
+	}
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/render"
+)
+
+// safeHTML sanitizes s for display inside a template.
+func safeHTML(s string) template.HTML {
+	sanitized := render.Sanitize(s)
+
+	// Safe to convert to template.HTML after sanitization
+	// #nosec G203 - XSS prevention: content sanitized with bluemonday UGC policy before HTML conversion
+	return template.HTML(sanitized)
+}
+
+// ensureRendered fills in the entry's sanitized HTML and summary fields,
+// rendering and caching them on the row if that hasn't happened yet.
+// Clearing content_html forces a re-render, e.g. after the renderer
+// changes. Content that renders to nothing is left unstored, since an
+// empty content_html would only be rendered again on the next view.
+// Encrypted entries are never rendered.
+func (cfg *apiConfig) ensureRendered(ctx context.Context, journal *database.JournalEntry) {
+	if journal.ContentHtml != "" || journal.Encrypted {
+		return
+	}
+
+	html, err := render.HTML(journal.Format, journal.Content)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to render journal", "journal_id", journal.ID, "error", err)
+		journal.ContentHtml = render.Sanitize(journal.Content)
+		return
+	}
+
+	summary := render.Summarize(html)
+	journal.ContentHtml = html
+	journal.WordCount = int64(summary.WordCount)
+	journal.ReadingMinutes = int64(summary.ReadingMinutes)
+	journal.Excerpt = summary.Excerpt
+	if html == "" {
+		return
+	}
+
+	err = cfg.DB.SetJournalRendered(ctx, database.SetJournalRenderedParams{
+		ContentHtml:    journal.ContentHtml,
+		WordCount:      journal.WordCount,
+		ReadingMinutes: journal.ReadingMinutes,
+		Excerpt:        journal.Excerpt,
+		ID:             journal.ID,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to cache rendered journal", "journal_id", journal.ID, "error", err)
+	}
+}
@@ -0,0 +1,218 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestSafeHTMLKeepsTaskLists(t *testing.T) {
+	got := string(safeHTML(`<ul><li><input checked="" disabled="" type="checkbox"> done</li></ul>` +
+		`<input type="text" value="x"><script>alert(1)</script>`))
+
+	if !strings.Contains(got, `<input checked="" disabled="" type="checkbox">`) {
+		t.Errorf("task list checkbox was stripped: %s", got)
+	}
+	if strings.Contains(got, `type="text"`) || strings.Contains(got, "<script>") {
+		t.Errorf("unsafe markup survived sanitization: %s", got)
+	}
+}
+
+func TestPostJournalEntryMarkdown(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "mduser", "password")
+
+	tests := []struct {
+		name       string
+		format     string
+		wantStatus int
+	}{
+		{name: "markdown", format: "markdown", wantStatus: http.StatusCreated},
+		{name: "default format", format: "", wantStatus: http.StatusCreated},
+		{name: "unknown format", format: "rst", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, _ := json.Marshal(map[string]string{
+				"title":   "Notes",
+				"content": "## Heading\n\n- [x] done",
+				"format":  tt.format,
+			})
+			req := httptest.NewRequest("POST", "/api/journals", bytes.NewBuffer(payload))
+			req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+			rr := httptest.NewRecorder()
+
+			apiCfg.postJournalEntry(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	var format, html string
+	err := db.QueryRow(`SELECT format, content_html FROM journal_entries ORDER BY id LIMIT 1`).Scan(&format, &html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "markdown" || !strings.Contains(html, `<h2 id="heading">Heading`) {
+		t.Errorf("Expected rendered markdown to be stored, got format=%q html=%q", format, html)
+	}
+}
+
+func TestEditKeepsFormat(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "mdeditor", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Notes", Content: "*old*", UserID: user.ID, Format: "markdown", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A client that doesn't send format edits the Markdown as Markdown
+	payload, _ := json.Marshal(map[string]any{"id": journal.ID, "title": "Notes", "content": "*new*"})
+	req := httptest.NewRequest("PUT", "/api/journals", bytes.NewBuffer(payload))
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+	rr := httptest.NewRecorder()
+	apiCfg.editJournalEntry(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected the edit to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	edited, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Format != "markdown" || !strings.Contains(edited.ContentHtml, "<em>new</em>") {
+		t.Errorf("Expected the entry to stay Markdown, got format=%q html=%q", edited.Format, edited.ContentHtml)
+	}
+}
+
+func TestEnsureRenderedFillsCache(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "cacheuser", "password")
+
+	// Rows written before the renderer existed have no cached HTML
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Old", Content: "*emphasis*", UserID: user.ID, Format: "markdown", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiCfg.ensureRendered(ctx, &journal)
+	if !strings.Contains(journal.ContentHtml, "<em>emphasis</em>") || journal.WordCount != 1 {
+		t.Errorf("ensureRendered left html=%q words=%d", journal.ContentHtml, journal.WordCount)
+	}
+
+	stored, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stored.ContentHtml, "<em>emphasis</em>") || stored.WordCount != 1 ||
+		stored.ReadingMinutes != 1 || stored.Excerpt != "emphasis" {
+		t.Errorf("rendered fields were not cached, got %+v", stored)
+	}
+}
+
+func TestEnsureRenderedSkipsEmptyRender(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "emptyuser", "password")
+
+	// Nothing survives sanitizing, so the cached HTML stays empty
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Empty", Content: "<script>alert(1)</script>", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after int
+	db.QueryRow("SELECT total_changes()").Scan(&before)
+	for range 3 {
+		view := journal
+		apiCfg.ensureRendered(ctx, &view)
+		if view.ContentHtml != "" || view.WordCount != 0 {
+			t.Errorf("Expected an empty render, got html=%q words=%d", view.ContentHtml, view.WordCount)
+		}
+	}
+	db.QueryRow("SELECT total_changes()").Scan(&after)
+	if after != before {
+		t.Errorf("Expected viewing an empty entry not to write, got %d changes", after-before)
+	}
+}
+
+func TestSafeHTMLKeepsHighlighting(t *testing.T) {
+	got := string(safeHTML(`<pre class="chroma"><code><span class="line"><span class="cl"><span class="kd">func</span></span></span></code></pre>` +
+		`<span class="fixed inset-0">overlay</span>`))
+
+	for _, want := range []string{`<pre class="chroma">`, `<span class="line">`, `<span class="kd">func</span>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sanitized highlighting missing %q: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "inset-0") {
+		t.Errorf("arbitrary classes should be stripped: %s", got)
+	}
+}
+
+func TestGetJournalEntryTOC(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "tocuser", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(context.Background(), database.CreateJournalEntryParams{
+		Title: "Guide", Content: "## Setup\n\n### Install\n\n## Usage", UserID: user.ID, Format: "markdown", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/journals/1", nil)
+	req.SetPathValue("journalID", strconv.FormatInt(journal.ID, 10))
+	rr := httptest.NewRecorder()
+	apiCfg.getJournalEntry(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var resp struct {
+		Title string `json:"title"`
+		TOC   []struct {
+			Level int    `json:"level"`
+			ID    string `json:"id"`
+			Text  string `json:"text"`
+		} `json:"toc"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Title != "Guide" || len(resp.TOC) != 3 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.TOC[1].Level != 3 || resp.TOC[1].ID != "install" || resp.TOC[1].Text != "Install" {
+		t.Errorf("unexpected TOC entry: %+v", resp.TOC[1])
+	}
+}
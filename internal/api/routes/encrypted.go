@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/sianwa11/my-journal/internal/envelope"
+)
+
+var errEncryptedNotPrivate = errors.New("encrypted entries must be private")
+
+// sealedContent checks an encrypted entry before it is stored and returns
+// its envelope in canonical form. Encrypted entries are always private:
+// there's nothing a visitor could read, and nothing to put in a feed or
+// search index.
+func sealedContent(content, visibility string) (string, error) {
+	if visibility != visibilityPrivate {
+		return "", errEncryptedNotPrivate
+	}
+
+	env, err := envelope.Parse(content)
+	if err != nil {
+		return "", err
+	}
+	return env.String(), nil
+}
@@ -0,0 +1,631 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/activitypub"
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+)
+
+const (
+	actorPath     = "/ap/actor"
+	inboxPath     = "/ap/inbox"
+	outboxPath    = "/ap/outbox"
+	followersPath = "/ap/followers"
+
+	// outboxPageSize is how many activities one outbox page holds.
+	outboxPageSize = 20
+
+	// noteMaxWords is the longest entry federated as a Note; longer ones
+	// become Articles, which most servers show as a title and a link.
+	noteMaxWords = 150
+
+	maxInboxBytes = 256 << 10
+
+	// activityPubAttempts is how many times an activity is sent to an
+	// inbox. With the queue's backoff that spans about a day.
+	activityPubAttempts = 30
+
+	activityPubUserAgent = "my-journal-activitypub/1.0"
+)
+
+// federates reports whether the site takes part in ActivityPub. It needs
+// BASE_URL: actor ids, key ids and the WebFinger subject are permanent
+// identities that other servers cache, so they must never be taken from
+// a request's Host header.
+func (cfg *apiConfig) federates() bool {
+	return cfg.baseURL != ""
+}
+
+var nonUsernameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// actorKeyCache holds the parsed signing key so it is read from the
+// database, or generated, only once.
+type actorKeyCache struct {
+	mu        sync.Mutex
+	key       *rsa.PrivateKey
+	publicPEM string
+}
+
+// actorKey returns the site owner's signing key and its public half as
+// PEM, generating and storing a key pair the first time it is needed.
+func (cfg *apiConfig) actorKey(ctx context.Context) (*rsa.PrivateKey, string, error) {
+	cfg.actorKeys.mu.Lock()
+	defer cfg.actorKeys.mu.Unlock()
+
+	if cfg.actorKeys.key != nil {
+		return cfg.actorKeys.key, cfg.actorKeys.publicPEM, nil
+	}
+
+	stored, err := cfg.DB.GetActorKey(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		privatePEM, publicPEM, genErr := activitypub.GenerateKey()
+		if genErr != nil {
+			return nil, "", genErr
+		}
+		err = cfg.DB.CreateActorKey(ctx, database.CreateActorKeyParams{
+			PrivateKeyPem: privatePEM,
+			PublicKeyPem:  publicPEM,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		// Another instance may have won the race; use whichever was stored
+		stored, err = cfg.DB.GetActorKey(ctx)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := activitypub.ParsePrivateKey(stored.PrivateKeyPem)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg.actorKeys.key = key
+	cfg.actorKeys.publicPEM = stored.PublicKeyPem
+	return key, stored.PublicKeyPem, nil
+}
+
+// signerFor returns a Signer for the actor at actorURL.
+func (cfg *apiConfig) signerFor(ctx context.Context, actorURL string) (activitypub.Signer, error) {
+	key, _, err := cfg.actorKey(ctx)
+	if err != nil {
+		return activitypub.Signer{}, err
+	}
+	return activitypub.Signer{KeyID: actorURL + "#main-key", Key: key}, nil
+}
+
+// siteOwner returns the user the site belongs to.
+func (cfg *apiConfig) siteOwner(ctx context.Context) (database.User, error) {
+	users, err := cfg.DB.ListUser(ctx)
+	if err != nil {
+		return database.User{}, err
+	}
+	if len(users) == 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	return users[0], nil
+}
+
+// actorUsername turns the owner's name into the handle used in WebFinger
+// and preferredUsername: lowercase letters, digits and underscores.
+func actorUsername(name string) string {
+	username := strings.Trim(nonUsernameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if username == "" {
+		return "me"
+	}
+	return username
+}
+
+func respondWithActivity(w http.ResponseWriter, contentType string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		requestLogger(w).Error("failed to encode activity", "error", err)
+		http.Error(w, "Failed to encode document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// handleWebFinger answers acct: lookups for the site owner, pointing
+// fediverse servers at the actor document.
+func (cfg *apiConfig) handleWebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		http.Error(w, "resource is required", http.StatusBadRequest)
+		return
+	}
+
+	owner, err := cfg.siteOwner(r.Context())
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	site, err := url.Parse(cfg.baseURL)
+	if err != nil {
+		http.Error(w, "Bad site URL", http.StatusInternalServerError)
+		return
+	}
+	subject := "acct:" + actorUsername(owner.Name) + "@" + site.Host
+	actorURL := cfg.baseURL + actorPath
+
+	switch {
+	case strings.EqualFold(resource, subject), resource == actorURL, resource == site.String():
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	respondWithActivity(w, activitypub.JRDContentType, activitypub.JRD{
+		Subject: subject,
+		Aliases: []string{actorURL, site.String()},
+		Links: []activitypub.Link{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURL},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: site.String()},
+		},
+	})
+}
+
+// handleActor serves the site owner as a Person.
+func (cfg *apiConfig) handleActor(w http.ResponseWriter, r *http.Request) {
+	owner, err := cfg.siteOwner(r.Context())
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	_, publicPEM, err := cfg.actorKey(r.Context())
+	if err != nil {
+		requestLogger(w).Error("failed to load actor key", "error", err)
+		http.Error(w, "Failed to load actor", http.StatusInternalServerError)
+		return
+	}
+
+	actorURL := cfg.baseURL + actorPath
+	respondWithActivity(w, activitypub.ContentType, activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actorURL,
+		Type:              "Person",
+		PreferredUsername: actorUsername(owner.Name),
+		Name:              owner.Name,
+		Summary:           owner.Bio.String,
+		URL:               cfg.baseURL,
+		Inbox:             cfg.baseURL + inboxPath,
+		Outbox:            cfg.baseURL + outboxPath,
+		Followers:         cfg.baseURL + followersPath,
+		Endpoints:         &activitypub.Endpoints{SharedInbox: cfg.baseURL + inboxPath},
+		PublicKey: &activitypub.PublicKey{
+			ID:           actorURL + "#main-key",
+			Owner:        actorURL,
+			PublicKeyPem: publicPEM,
+		},
+	})
+}
+
+// handleOutbox lists public journal entries as Create activities, newest
+// first. Without a page parameter it returns only the collection summary.
+func (cfg *apiConfig) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	total, err := cfg.DB.GetAllJournalsCount(r.Context(), 0)
+	if err != nil {
+		http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+		return
+	}
+
+	outboxURL := cfg.baseURL + outboxPath
+	page := newPagination(pageFromRequest(r), outboxPageSize, int(total))
+
+	if r.URL.Query().Get("page") == "" {
+		respondWithActivity(w, activitypub.ContentType, activitypub.OrderedCollection{
+			Context:    activitypub.Context,
+			ID:         outboxURL,
+			Type:       "OrderedCollection",
+			TotalItems: total,
+			First:      outboxURL + "?page=1",
+			Last:       outboxURL + "?page=" + strconv.Itoa(page.TotalPages),
+		})
+		return
+	}
+	if page.OutOfRange() {
+		http.NotFound(w, r)
+		return
+	}
+
+	journals, err := cfg.DB.GetJournals(r.Context(), database.GetJournalsParams{
+		Limit:  int64(page.PerPage),
+		Offset: int64(page.Offset()),
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+		return
+	}
+
+	actorURL := cfg.baseURL + actorPath
+	items := make([]any, 0, len(journals))
+	for _, journal := range journals {
+		object, err := cfg.journalObject(r, journal)
+		if err != nil {
+			http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, activitypub.Activity{
+			ID:        object.ID + "#create",
+			Type:      "Create",
+			Actor:     actorURL,
+			Object:    object,
+			Published: object.Published,
+			To:        object.To,
+			Cc:        object.Cc,
+		})
+	}
+
+	collection := activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           outboxURL + "?page=" + strconv.Itoa(page.Page),
+		Type:         "OrderedCollectionPage",
+		TotalItems:   total,
+		PartOf:       outboxURL,
+		OrderedItems: items,
+	}
+	if page.HasNext() {
+		collection.Next = outboxURL + "?page=" + strconv.Itoa(page.NextPage())
+	}
+	if page.HasPrev() {
+		collection.Prev = outboxURL + "?page=" + strconv.Itoa(page.PrevPage())
+	}
+	respondWithActivity(w, activitypub.ContentType, collection)
+}
+
+// handleFollowers reports how many followers there are without listing
+// them.
+func (cfg *apiConfig) handleFollowers(w http.ResponseWriter, r *http.Request) {
+	total, err := cfg.DB.CountFollowers(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to count followers", http.StatusInternalServerError)
+		return
+	}
+	respondWithActivity(w, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.baseURL + followersPath,
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	})
+}
+
+// journalObject represents a public journal entry as a Note or an
+// Article. Its id is the entry's page, which serves the same document to
+// clients asking for activity+json.
+func (cfg *apiConfig) journalObject(r *http.Request, journal database.JournalEntry) (activitypub.Object, error) {
+	cfg.ensureRendered(r.Context(), &journal)
+
+	tags, err := cfg.DB.ListJournalTagNames(r.Context(), journal.ID)
+	if err != nil {
+		return activitypub.Object{}, err
+	}
+
+	id := fmt.Sprintf("%s/journals/%d", cfg.baseURL, journal.ID)
+	object := activitypub.Object{
+		ID:           id,
+		Type:         "Note",
+		AttributedTo: cfg.baseURL + actorPath,
+		Content:      journal.ContentHtml,
+		URL:          id,
+		Published:    journal.CreatedAt.Time.UTC().Format(time.RFC3339),
+		To:           []string{activitypub.Public},
+		Cc:           []string{cfg.baseURL + followersPath},
+	}
+	if journal.WordCount > noteMaxWords {
+		object.Type = "Article"
+		object.Name = journal.Title
+	}
+	if journal.UpdatedAt.Valid && journal.UpdatedAt.Time.After(journal.CreatedAt.Time) {
+		object.Updated = journal.UpdatedAt.Time.UTC().Format(time.RFC3339)
+	}
+	for _, tag := range tags {
+		object.Tag = append(object.Tag, activitypub.Tag{Type: "Hashtag", Name: "#" + tag})
+	}
+	return object, nil
+}
+
+// serveJournalObject answers a request for a journal page that asked for
+// activity+json.
+func (cfg *apiConfig) serveJournalObject(w http.ResponseWriter, r *http.Request, journal database.JournalEntry) {
+	object, err := cfg.journalObject(r, journal)
+	if err != nil {
+		http.Error(w, "Failed to fetch journal", http.StatusInternalServerError)
+		return
+	}
+	object.Context = activitypub.Context
+	respondWithActivity(w, activitypub.ContentType, object)
+}
+
+// handleInbox receives activities from other servers. Every request must
+// be signed by the actor it claims to come from. Follow and Undo Follow
+// are acted on; anything else is accepted and ignored.
+func (cfg *apiConfig) handleInbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboxBytes))
+	if err != nil {
+		http.Error(w, "Body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var activity activitypub.IncomingActivity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Type == "" || activity.Actor == "" {
+		http.Error(w, "Invalid activity", http.StatusBadRequest)
+		return
+	}
+
+	actorURL := cfg.baseURL + actorPath
+	signer, err := cfg.signerFor(r.Context(), actorURL)
+	if err != nil {
+		requestLogger(w).Error("failed to load actor key", "error", err)
+		http.Error(w, "Failed to load actor", http.StatusInternalServerError)
+		return
+	}
+
+	var sender activitypub.Actor
+	lookup := func(keyID string) (*rsa.PublicKey, error) {
+		owner, _, _ := strings.Cut(keyID, "#")
+		sender, err = cfg.activityPub.FetchActor(r.Context(), owner, signer)
+		if err != nil {
+			return nil, err
+		}
+		if sender.PublicKey == nil || sender.PublicKey.ID != keyID {
+			return nil, fmt.Errorf("%w: key %s not found on its actor", activitypub.ErrSignature, keyID)
+		}
+		return activitypub.ParsePublicKey(sender.PublicKey.PublicKeyPem)
+	}
+
+	_, err = activitypub.Verify(r, body, time.Now(), lookup)
+	var statusErr *activitypub.StatusError
+	switch {
+	case err == nil:
+	case activity.Type == "Delete" && errors.As(err, &statusErr) && statusErr.Permanent():
+		// Deleted accounts announce themselves with a key that can no
+		// longer be fetched; there is nothing to do for them
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		requestLogger(w).Info("rejected inbox activity", "actor", activity.Actor, "type", activity.Type, "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if sender.ID != activity.Actor {
+		http.Error(w, "Activity actor does not match the signature", http.StatusUnauthorized)
+		return
+	}
+
+	ref := activity.Ref()
+	switch activity.Type {
+	case "Follow":
+		if ref.ID != actorURL {
+			break
+		}
+		if err := cfg.acceptFollow(r.Context(), sender, activity, body, actorURL); err != nil {
+			requestLogger(w).Error("failed to accept follow", "actor", sender.ID, "error", err)
+			http.Error(w, "Failed to accept follow", http.StatusInternalServerError)
+			return
+		}
+		requestLogger(w).Info("new follower", "actor", sender.ID)
+	case "Undo":
+		if ref.Type != "Follow" || (ref.Actor != "" && ref.Actor != sender.ID) {
+			break
+		}
+		removed, err := cfg.DB.DeleteFollower(r.Context(), sender.ID)
+		if err != nil {
+			requestLogger(w).Error("failed to remove follower", "actor", sender.ID, "error", err)
+			http.Error(w, "Failed to undo follow", http.StatusInternalServerError)
+			return
+		}
+		if removed > 0 {
+			requestLogger(w).Info("lost follower", "actor", sender.ID)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// acceptFollow stores a follower and queues the Accept telling their
+// server the follow went through.
+func (cfg *apiConfig) acceptFollow(ctx context.Context, follower activitypub.Actor, follow activitypub.IncomingActivity, raw []byte, actorURL string) error {
+	accept := activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      actorURL + "#accept-" + rand.Text(),
+		Type:    "Accept",
+		Actor:   actorURL,
+		Object:  json.RawMessage(raw),
+	}
+	activity, err := json.Marshal(accept)
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.UpsertFollower(ctx, database.UpsertFollowerParams{
+		ActorID:     follower.ID,
+		Inbox:       follower.Inbox,
+		SharedInbox: follower.SharedInbox(),
+		FollowID:    follow.ID,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cfg.jobKinds.deliverActivity.EnqueueTx(ctx, qtx, activityJob{
+		Inbox:    follower.Inbox,
+		Activity: activity,
+	}, jobs.MaxAttempts(activityPubAttempts))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.jobs.Wake()
+	return nil
+}
+
+// isPublished reports whether followers can see journal. The zero
+// JournalEntry stands for an entry that doesn't exist.
+func isPublished(journal database.JournalEntry) bool {
+	return journal.ID != 0 && journal.Visibility == visibilityPublic && !journal.DeletedAt.Valid
+}
+
+// journalChanged tells the rest of the web about a journal entry that was
+// created, edited, trashed or restored. before and after are the entry on
+// either side of the change, with the zero JournalEntry standing in for
+// an entry that didn't exist or is in the trash. Linked sites get
+// Webmentions, followers get a Create, Update or Delete, and webhooks
+// subscribed to journal events are queued.
+func (cfg *apiConfig) journalChanged(r *http.Request, before, after database.JournalEntry) {
+	id := after.ID
+	if id == 0 {
+		id = before.ID
+	}
+
+	// Sites the entry stopped linking to, or can no longer see, are told
+	// too so they can drop the mention
+	var beforeHTML, afterHTML string
+	if isPublished(before) {
+		beforeHTML = before.ContentHtml
+	}
+	if isPublished(after) {
+		afterHTML = after.ContentHtml
+	}
+	cfg.sendWebmentions(r, id, beforeHTML, afterHTML)
+
+	if err := cfg.federateJournal(r, before, after); err != nil {
+		slog.ErrorContext(r.Context(), "failed to queue activities", "journal_id", id, "error", err)
+	}
+
+	cfg.emitJournalEvent(r, before, after)
+}
+
+// federateJournal queues the activity describing a change to a journal
+// entry for every follower's inbox.
+func (cfg *apiConfig) federateJournal(r *http.Request, before, after database.JournalEntry) error {
+	if !cfg.federates() {
+		return nil
+	}
+
+	var kind string
+	switch {
+	case !isPublished(before) && isPublished(after):
+		kind = "Create"
+	case isPublished(before) && isPublished(after):
+		kind = "Update"
+	case isPublished(before) && !isPublished(after):
+		kind = "Delete"
+	default:
+		return nil
+	}
+
+	inboxes, err := cfg.DB.ListFollowerInboxes(r.Context())
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+
+	actorURL := cfg.baseURL + actorPath
+	now := time.Now().UTC()
+	activity := activitypub.Activity{
+		Context:   activitypub.Context,
+		Type:      kind,
+		Actor:     actorURL,
+		Published: now.Format(time.RFC3339),
+		To:        []string{activitypub.Public},
+		Cc:        []string{cfg.baseURL + followersPath},
+	}
+
+	if kind == "Delete" {
+		id := fmt.Sprintf("%s/journals/%d", cfg.baseURL, before.ID)
+		activity.Object = activitypub.Object{ID: id, Type: "Tombstone"}
+		activity.ID = fmt.Sprintf("%s#delete-%d", id, now.Unix())
+	} else {
+		object, err := cfg.journalObject(r, after)
+		if err != nil {
+			return err
+		}
+		activity.Object = object
+		activity.ID = fmt.Sprintf("%s#%s-%d", object.ID, strings.ToLower(kind), now.Unix())
+	}
+
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	for _, inbox := range inboxes {
+		_, err := cfg.jobKinds.deliverActivity.EnqueueTx(r.Context(), qtx, activityJob{
+			Inbox:    inbox,
+			Activity: data,
+		}, jobs.MaxAttempts(activityPubAttempts))
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.jobs.Wake()
+	return nil
+}
+
+// activityJob asks for an activity to be POSTed to a follower's inbox.
+type activityJob struct {
+	Inbox    string          `json:"inbox"`
+	Activity json.RawMessage `json:"activity"`
+}
+
+// runDeliverActivity sends one activity, signed as its actor. Servers
+// that reject it outright aren't asked again.
+func (cfg *apiConfig) runDeliverActivity(ctx context.Context, job activityJob) error {
+	var activity struct {
+		Actor string `json:"actor"`
+	}
+	if err := json.Unmarshal(job.Activity, &activity); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	signer, err := cfg.signerFor(ctx, activity.Actor)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.activityPub.Deliver(ctx, job.Inbox, job.Activity, signer)
+	var statusErr *activitypub.StatusError
+	if errors.As(err, &statusErr) && statusErr.Permanent() {
+		return jobs.Permanent(err)
+	}
+	return err
+}
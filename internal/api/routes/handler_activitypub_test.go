@@ -0,0 +1,442 @@
+package routes
+
+import (
+	"context"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/activitypub"
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+)
+
+// remoteServer is a stub fediverse server with one actor, alice, whose
+// inboxes record what they receive.
+type remoteServer struct {
+	*httptest.Server
+	signer activitypub.Signer
+	public string
+
+	mu       sync.Mutex
+	received []receivedActivity
+	status   int
+}
+
+type receivedActivity struct {
+	Inbox    string
+	Activity map[string]any
+	KeyID    string
+}
+
+func newRemoteServer(t *testing.T, verifyKey func() *rsa.PublicKey) *remoteServer {
+	t.Helper()
+
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := activitypub.ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := &remoteServer{public: publicPEM, status: http.StatusAccepted}
+	remote.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/users/alice":
+			w.Header().Set("Content-Type", activitypub.ContentType)
+			json.NewEncoder(w).Encode(remote.actor())
+		case r.Method == "POST":
+			body, _ := io.ReadAll(r.Body)
+			keyID, err := activitypub.Verify(r, body, time.Now(), func(string) (*rsa.PublicKey, error) {
+				return verifyKey(), nil
+			})
+			if err != nil {
+				t.Errorf("Delivery to %s did not verify: %v", r.URL.Path, err)
+			}
+
+			var activity map[string]any
+			json.Unmarshal(body, &activity)
+
+			remote.mu.Lock()
+			defer remote.mu.Unlock()
+			remote.received = append(remote.received, receivedActivity{Inbox: r.URL.Path, Activity: activity, KeyID: keyID})
+			w.WriteHeader(remote.status)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	remote.signer = activitypub.Signer{KeyID: remote.URL + "/users/alice#main-key", Key: key}
+	t.Cleanup(remote.Close)
+	return remote
+}
+
+func (s *remoteServer) actor() activitypub.Actor {
+	return activitypub.Actor{
+		ID:        s.URL + "/users/alice",
+		Type:      "Person",
+		Inbox:     s.URL + "/users/alice/inbox",
+		Endpoints: &activitypub.Endpoints{SharedInbox: s.URL + "/inbox"},
+		PublicKey: &activitypub.PublicKey{
+			ID:           s.URL + "/users/alice#main-key",
+			Owner:        s.URL + "/users/alice",
+			PublicKeyPem: s.public,
+		},
+	}
+}
+
+// take returns and forgets everything received so far.
+func (s *remoteServer) take() []receivedActivity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	received := s.received
+	s.received = nil
+	return received
+}
+
+func (s *remoteServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func setupActivityPubConfig(t *testing.T) (*apiConfig, *sql.DB, *remoteServer) {
+	t.Helper()
+
+	apiCfg, db := setupTestAPIConfig(t)
+	apiCfg.baseURL = "https://me.example"
+	createTestUser(t, apiCfg.DB, "Sianwa Atemi", "password")
+
+	remote := newRemoteServer(t, func() *rsa.PublicKey {
+		key, _, err := apiCfg.actorKey(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &key.PublicKey
+	})
+	apiCfg.activityPub = activitypub.NewClient(remote.Client(), "")
+	apiCfg.registerJobs(jobs.New(apiCfg.DB, jobs.Options{}))
+	return apiCfg, db, remote
+}
+
+func TestWebFingerAndActor(t *testing.T) {
+	apiCfg, db, _ := setupActivityPubConfig(t)
+	defer db.Close()
+
+	tests := []struct {
+		resource   string
+		wantStatus int
+	}{
+		{"acct:sianwa_atemi@me.example", http.StatusOK},
+		{"https://me.example/ap/actor", http.StatusOK},
+		{"acct:someone@me.example", http.StatusNotFound},
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/.well-known/webfinger?resource="+tt.resource, nil)
+		rr := httptest.NewRecorder()
+		apiCfg.handleWebFinger(rr, req)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%q: expected %d, got %d", tt.resource, tt.wantStatus, rr.Code)
+			continue
+		}
+		if rr.Code != http.StatusOK {
+			continue
+		}
+
+		var jrd activitypub.JRD
+		json.NewDecoder(rr.Body).Decode(&jrd)
+		if jrd.Subject != "acct:sianwa_atemi@me.example" || jrd.Links[0].Href != "https://me.example/ap/actor" {
+			t.Errorf("Unexpected JRD %+v", jrd)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	apiCfg.handleActor(rr, httptest.NewRequest("GET", "/ap/actor", nil))
+	if rr.Header().Get("Content-Type") != activitypub.ContentType {
+		t.Errorf("Unexpected Content-Type %q", rr.Header().Get("Content-Type"))
+	}
+
+	var actor activitypub.Actor
+	json.NewDecoder(rr.Body).Decode(&actor)
+	if actor.ID != "https://me.example/ap/actor" || actor.PreferredUsername != "sianwa_atemi" ||
+		actor.Inbox != "https://me.example/ap/inbox" || actor.PublicKey == nil {
+		t.Fatalf("Unexpected actor %+v", actor)
+	}
+
+	public, err := activitypub.ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, _ := apiCfg.actorKey(context.Background())
+	if !public.Equal(&key.PublicKey) {
+		t.Error("Expected the actor to publish the stored signing key")
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	apiCfg, db, _ := setupActivityPubConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	long := "<p>" + strings.Repeat("word ", noteMaxWords+1) + "</p>"
+	for _, entry := range []struct {
+		html       string
+		words      int64
+		visibility string
+	}{
+		{"<p>Short one</p>", 2, visibilityPublic},
+		{long, noteMaxWords + 1, visibilityPublic},
+		{"<p>Hidden</p>", 1, visibilityPrivate},
+	} {
+		_, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+			Title:       "Entry",
+			Content:     entry.html,
+			UserID:      1,
+			Format:      "html",
+			ContentHtml: entry.html,
+			WordCount:   entry.words,
+			Visibility:  entry.visibility,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(target string) activitypub.OrderedCollection {
+		rr := httptest.NewRecorder()
+		apiCfg.handleOutbox(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected %s to return 200, got %d", target, rr.Code)
+		}
+		var collection activitypub.OrderedCollection
+		json.NewDecoder(rr.Body).Decode(&collection)
+		return collection
+	}
+
+	outbox := get("/ap/outbox")
+	if outbox.TotalItems != 2 || outbox.First != "https://me.example/ap/outbox?page=1" || len(outbox.OrderedItems) != 0 {
+		t.Errorf("Unexpected outbox %+v", outbox)
+	}
+
+	page := get("/ap/outbox?page=1")
+	if len(page.OrderedItems) != 2 || page.Next != "" {
+		t.Fatalf("Unexpected outbox page %+v", page)
+	}
+	var types []string
+	for _, item := range page.OrderedItems {
+		activity := item.(map[string]any)
+		object := activity["object"].(map[string]any)
+		types = append(types, activity["type"].(string)+" "+object["type"].(string))
+	}
+	if strings.Join(types, ", ") != "Create Article, Create Note" {
+		t.Errorf("Unexpected outbox items %v", types)
+	}
+}
+
+func TestInboxFollowAndDelivery(t *testing.T) {
+	apiCfg, db, remote := setupActivityPubConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	inbox := func(activity map[string]any, signer activitypub.Signer) int {
+		body, _ := json.Marshal(activity)
+		req := httptest.NewRequest("POST", "https://me.example/ap/inbox", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", activitypub.ContentType)
+		if signer.Key != nil {
+			if err := signer.Sign(req, body, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rr := httptest.NewRecorder()
+		apiCfg.handleInbox(rr, req)
+		return rr.Code
+	}
+	deliver := func() []receivedActivity {
+		if _, err := apiCfg.jobs.RunDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return remote.take()
+	}
+
+	alice := remote.URL + "/users/alice"
+	follow := map[string]any{
+		"id":     alice + "/follows/1",
+		"type":   "Follow",
+		"actor":  alice,
+		"object": "https://me.example/ap/actor",
+	}
+
+	if code := inbox(follow, activitypub.Signer{}); code != http.StatusUnauthorized {
+		t.Errorf("Expected an unsigned follow to return 401, got %d", code)
+	}
+	forged := map[string]any{"id": "x", "type": "Follow", "actor": remote.URL + "/users/bob", "object": "https://me.example/ap/actor"}
+	if code := inbox(forged, remote.signer); code != http.StatusUnauthorized {
+		t.Errorf("Expected a follow signed by someone else to return 401, got %d", code)
+	}
+
+	if code := inbox(follow, remote.signer); code != http.StatusAccepted {
+		t.Fatalf("Expected follow to return 202, got %d", code)
+	}
+	if count, _ := apiCfg.DB.CountFollowers(ctx); count != 1 {
+		t.Fatalf("Expected 1 follower, got %d", count)
+	}
+
+	received := deliver()
+	if len(received) != 1 || received[0].Inbox != "/users/alice/inbox" || received[0].Activity["type"] != "Accept" {
+		t.Fatalf("Expected an Accept in alice's inbox, got %+v", received)
+	}
+	if object, _ := received[0].Activity["object"].(map[string]any); object["id"] != follow["id"] {
+		t.Errorf("Expected the Accept to carry the Follow, got %v", received[0].Activity["object"])
+	}
+	if received[0].KeyID != "https://me.example/ap/actor#main-key" {
+		t.Errorf("Unexpected keyId %q", received[0].KeyID)
+	}
+
+	// Publishing, editing and unpublishing reach the shared inbox
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title:       "Hello fediverse",
+		Content:     "<p>Hello</p>",
+		UserID:      1,
+		Format:      "html",
+		ContentHtml: "<p>Hello</p>",
+		WordCount:   1,
+		Visibility:  visibilityPublic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/journals", nil)
+	private := journal
+	private.Visibility = visibilityPrivate
+
+	steps := []struct {
+		before, after database.JournalEntry
+		want          string
+	}{
+		{database.JournalEntry{}, journal, "Create"},
+		{journal, journal, "Update"},
+		{journal, private, "Delete"},
+		{private, database.JournalEntry{}, ""},
+	}
+	for _, step := range steps {
+		apiCfg.journalChanged(req, step.before, step.after)
+		received := deliver()
+		if step.want == "" {
+			if len(received) != 0 {
+				t.Errorf("Expected nothing to be delivered, got %+v", received)
+			}
+			continue
+		}
+		if len(received) != 1 || received[0].Inbox != "/inbox" || received[0].Activity["type"] != step.want {
+			t.Fatalf("Expected a %s in the shared inbox, got %+v", step.want, received)
+		}
+		object := received[0].Activity["object"].(map[string]any)
+		if object["id"] != "https://me.example/journals/1" {
+			t.Errorf("Unexpected object %v", object)
+		}
+	}
+
+	undo := map[string]any{
+		"id":     alice + "/follows/1/undo",
+		"type":   "Undo",
+		"actor":  alice,
+		"object": follow,
+	}
+	if code := inbox(undo, remote.signer); code != http.StatusAccepted {
+		t.Fatalf("Expected undo to return 202, got %d", code)
+	}
+	if count, _ := apiCfg.DB.CountFollowers(ctx); count != 0 {
+		t.Errorf("Expected the follower to be removed, got %d", count)
+	}
+}
+
+func TestFederationRequiresBaseURL(t *testing.T) {
+	apiCfg, db, _ := setupActivityPubConfig(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Identities come from BASE_URL, never from the request
+	req := httptest.NewRequest("GET", "/ap/actor", nil)
+	req.Host = "evil.example"
+	rr := httptest.NewRecorder()
+	apiCfg.handleActor(rr, req)
+	if strings.Contains(rr.Body.String(), "evil.example") {
+		t.Errorf("Expected the actor to ignore the Host header, got %s", rr.Body.String())
+	}
+
+	err := apiCfg.DB.UpsertFollower(ctx, database.UpsertFollowerParams{
+		ActorID: "https://remote.example/users/a", Inbox: "https://remote.example/inbox", FollowID: "https://remote.example/follow/1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := database.JournalEntry{ID: 1, Title: "Hello", Visibility: visibilityPublic}
+
+	apiCfg.baseURL = ""
+	if err := apiCfg.federateJournal(httptest.NewRequest("POST", "/api/journals", nil), database.JournalEntry{}, journal); err != nil {
+		t.Fatal(err)
+	}
+	var queued int
+	db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&queued)
+	if queued != 0 {
+		t.Errorf("Expected nothing to be federated without BASE_URL, got %d deliveries", queued)
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	apiCfg, db, remote := setupActivityPubConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	id, err := apiCfg.jobKinds.deliverActivity.Enqueue(ctx, activityJob{
+		Inbox:    remote.URL + "/inbox",
+		Activity: json.RawMessage(`{"type":"Create","actor":"https://me.example/ap/actor"}`),
+	}, jobs.MaxAttempts(activityPubAttempts))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := func() (string, int) {
+		var status string
+		var attempts int
+		if err := db.QueryRow("SELECT status, attempts FROM jobs WHERE id = ?", id).Scan(&status, &attempts); err != nil {
+			t.Fatal(err)
+		}
+		return status, attempts
+	}
+
+	remote.setStatus(http.StatusServiceUnavailable)
+	if _, err := apiCfg.jobs.RunDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, attempts := state(); status != jobs.StatusPending || attempts != 1 {
+		t.Fatalf("Expected a pending retry, got %s after %d attempts", status, attempts)
+	}
+
+	// Not due again until the backoff has passed
+	if attempted, _ := apiCfg.jobs.RunDue(ctx); attempted != 0 {
+		t.Errorf("Expected no attempts before the retry is due, got %d", attempted)
+	}
+
+	db.Exec("UPDATE jobs SET run_at = '2000-01-01 00:00:00'")
+	remote.setStatus(http.StatusGone)
+	if attempted, _ := apiCfg.jobs.RunDue(ctx); attempted != 1 {
+		t.Errorf("Expected the retry to be attempted, got %d", attempted)
+	}
+	if status, attempts := state(); status != jobs.StatusDead || attempts != 2 {
+		t.Errorf("Expected a rejected delivery to be given up on, got %s after %d attempts", status, attempts)
+	}
+	if len(remote.take()) != 2 {
+		t.Error("Expected two delivery attempts")
+	}
+}
@@ -0,0 +1,223 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+type ArchiveMonth struct {
+	Year  int        `json:"year"`
+	Month time.Month `json:"month"`
+	Count int        `json:"count"`
+}
+
+// Name is the month's English name, for templates.
+func (m ArchiveMonth) Name() string {
+	return m.Month.String()
+}
+
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int            `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// archiveEntry is a journal entry on an archive page, with its creation
+// time already in the site timezone.
+type archiveEntry struct {
+	database.GetJournalsBetweenRow
+	Created time.Time
+}
+
+// archiveMonthEntries is a month of a year page with its entries.
+type archiveMonthEntries struct {
+	ArchiveMonth
+	Journals []archiveEntry
+}
+
+// buildArchive buckets creation times into years and months in loc, newest
+// first. dates must already be sorted newest first.
+func buildArchive(dates []time.Time, loc *time.Location) []ArchiveYear {
+	years := []ArchiveYear{}
+	for _, date := range dates {
+		local := date.In(loc)
+		year, month := local.Year(), local.Month()
+
+		if n := len(years); n == 0 || years[n-1].Year != year {
+			years = append(years, ArchiveYear{Year: year, Months: []ArchiveMonth{}})
+		}
+		y := &years[len(years)-1]
+		y.Count++
+
+		if n := len(y.Months); n == 0 || y.Months[n-1].Month != month {
+			y.Months = append(y.Months, ArchiveMonth{Year: year, Month: month})
+		}
+		y.Months[len(y.Months)-1].Count++
+	}
+	return years
+}
+
+// archive returns the year/month histogram of journal entries in the site
+// timezone. The dates come off the created_at index, so this never reads
+// the entries themselves.
+func (cfg *apiConfig) archive(ctx context.Context, loc *time.Location) ([]ArchiveYear, error) {
+	rows, err := cfg.DB.ListJournalDates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		if row.Valid {
+			dates = append(dates, row.Time)
+		}
+	}
+
+	return buildArchive(dates, loc), nil
+}
+
+// journalsBetween lists the entries created in [start, end), newest first.
+// The bounds' location is the one the entries are reported in.
+func (cfg *apiConfig) journalsBetween(ctx context.Context, start, end time.Time) ([]archiveEntry, error) {
+	rows, err := cfg.DB.GetJournalsBetween(ctx, database.GetJournalsBetweenParams{
+		Start: sqliteTimestamp(start),
+		End:   sqliteTimestamp(end),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]archiveEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, archiveEntry{
+			GetJournalsBetweenRow: row,
+			Created:               row.CreatedAt.Time.In(start.Location()),
+		})
+	}
+	return entries, nil
+}
+
+// parseArchiveYear reads the {year} path segment.
+func parseArchiveYear(r *http.Request) (int, bool) {
+	year, err := strconv.Atoi(r.PathValue("year"))
+	return year, err == nil && year >= 1 && year <= 9999
+}
+
+func (cfg *apiConfig) getJournalArchive(w http.ResponseWriter, r *http.Request) {
+	years, err := cfg.archive(r.Context(), cfg.siteSettings(r.Context()).Location())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get journal archive", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, years)
+}
+
+func (cfg *apiConfig) handleArchive(w http.ResponseWriter, r *http.Request) {
+	data := cfg.baseTemplateData(r.Context(), "Archive", "journals")
+	settings := data["Settings"].(SiteSettings)
+
+	years, err := cfg.archive(r.Context(), settings.Location())
+	if err != nil {
+		http.Error(w, "Failed to fetch archive", http.StatusInternalServerError)
+		return
+	}
+
+	total := 0
+	for _, y := range years {
+		total += y.Count
+	}
+
+	data["Years"] = years
+	data["Total"] = total
+	data["SEO"] = cfg.pageMeta(r, settings, "Archive", "Every journal entry, by year and month.")
+
+	cfg.renderArchive(w, data)
+}
+
+func (cfg *apiConfig) handleArchiveYear(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseArchiveYear(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := cfg.baseTemplateData(r.Context(), fmt.Sprintf("Archive: %d", year), "journals")
+	settings := data["Settings"].(SiteSettings)
+	loc := settings.Location()
+
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	journals, err := cfg.journalsBetween(r.Context(), start, start.AddDate(1, 0, 0))
+	if err != nil {
+		http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+		return
+	}
+	if len(journals) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var months []archiveMonthEntries
+	for _, journal := range journals {
+		month := journal.Created.Month()
+		if n := len(months); n == 0 || months[n-1].Month != month {
+			months = append(months, archiveMonthEntries{ArchiveMonth: ArchiveMonth{Year: year, Month: month}})
+		}
+		m := &months[len(months)-1]
+		m.Count++
+		m.Journals = append(m.Journals, journal)
+	}
+
+	data["ArchiveYear"] = year
+	data["Months"] = months
+	data["Total"] = len(journals)
+	data["SEO"] = cfg.pageMeta(r, settings, fmt.Sprintf("Journal entries from %d", year),
+		fmt.Sprintf("All %d journal entries written in %d.", len(journals), year))
+
+	cfg.renderArchive(w, data)
+}
+
+func (cfg *apiConfig) handleArchiveMonth(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseArchiveYear(r)
+	month, err := strconv.Atoi(r.PathValue("month"))
+	if !ok || err != nil || month < 1 || month > 12 {
+		http.NotFound(w, r)
+		return
+	}
+
+	period := ArchiveMonth{Year: year, Month: time.Month(month)}
+	data := cfg.baseTemplateData(r.Context(), fmt.Sprintf("Archive: %s %d", period.Name(), year), "journals")
+	settings := data["Settings"].(SiteSettings)
+
+	start := time.Date(year, period.Month, 1, 0, 0, 0, 0, settings.Location())
+	journals, err := cfg.journalsBetween(r.Context(), start, start.AddDate(0, 1, 0))
+	if err != nil {
+		http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+		return
+	}
+	if len(journals) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	period.Count = len(journals)
+
+	data["ArchiveYear"] = year
+	data["Month"] = period
+	data["Journals"] = journals
+	data["Total"] = len(journals)
+	data["SEO"] = cfg.pageMeta(r, settings, fmt.Sprintf("Journal entries from %s %d", period.Name(), year),
+		fmt.Sprintf("All %d journal entries written in %s %d.", len(journals), period.Name(), year))
+
+	cfg.renderArchive(w, data)
+}
+
+func (cfg *apiConfig) renderArchive(w http.ResponseWriter, data map[string]interface{}) {
+	if err := cfg.templates.ExecuteTemplate(w, "archive.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
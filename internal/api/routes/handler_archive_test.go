@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJournalArchive(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "archiveuser", "password")
+
+	// Stored the way CURRENT_TIMESTAMP stores them: UTC, second precision
+	for _, createdAt := range []string{
+		"2024-12-31 23:30:00", // already 2025 in Nairobi (UTC+3)
+		"2025-01-15 10:00:00",
+		"2025-03-01 09:00:00",
+		"2025-03-31 22:00:00", // April in Nairobi
+	} {
+		_, err := db.Exec(`INSERT INTO journal_entries (title, content, user_id, created_at) VALUES (?, ?, ?, ?)`,
+			"Entry "+createdAt, "content", user.ID, createdAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("histogram in UTC", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/journals/archive", nil)
+		rr := httptest.NewRecorder()
+		apiCfg.getJournalArchive(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var years []ArchiveYear
+		if err := json.NewDecoder(rr.Body).Decode(&years); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ArchiveYear{
+			{Year: 2025, Count: 3, Months: []ArchiveMonth{{2025, time.March, 2}, {2025, time.January, 1}}},
+			{Year: 2024, Count: 1, Months: []ArchiveMonth{{2024, time.December, 1}}},
+		}
+		assertArchive(t, years, want)
+	})
+
+	nairobi, err := time.LoadLocation("Africa/Nairobi")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+
+	t.Run("histogram in site timezone", func(t *testing.T) {
+		years, err := apiCfg.archive(context.Background(), nairobi)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []ArchiveYear{
+			{Year: 2025, Count: 4, Months: []ArchiveMonth{
+				{2025, time.April, 1}, {2025, time.March, 1}, {2025, time.January, 2},
+			}},
+		}
+		assertArchive(t, years, want)
+	})
+
+	t.Run("month range in site timezone", func(t *testing.T) {
+		start := time.Date(2025, time.March, 1, 0, 0, 0, 0, nairobi)
+		entries, err := apiCfg.journalsBetween(context.Background(), start, start.AddDate(0, 1, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 1 || entries[0].Title != "Entry 2025-03-01 09:00:00" {
+			t.Fatalf("Expected only the early March entry, got %+v", entries)
+		}
+		if got := entries[0].Created.Format("2006-01-02 15:04"); got != "2025-03-01 12:00" {
+			t.Errorf("Expected local creation time, got %s", got)
+		}
+	})
+}
+
+func assertArchive(t *testing.T, got, want []ArchiveYear) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d years, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].Year != want[i].Year || got[i].Count != want[i].Count || len(got[i].Months) != len(want[i].Months) {
+			t.Fatalf("Year %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+		for j := range want[i].Months {
+			if got[i].Months[j] != want[i].Months[j] {
+				t.Errorf("Year %d month %d: expected %+v, got %+v", want[i].Year, j, want[i].Months[j], got[i].Months[j])
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sianwa11/my-journal/internal/auth"
@@ -14,6 +15,17 @@ type contextKey string
 
 const userIDKey contextKey = "user_id"
 
+const (
+	accessTokenLifetime = time.Hour
+
+	// viewerCookie carries the access token to pages, which the browser
+	// loads without an Authorization header, so the owner can open their
+	// own private entries. Only viewerID reads it, on routes that show
+	// more to the owner but change nothing; everything else needs the
+	// bearer token, so the cookie is no use for cross-site requests.
+	viewerCookie = "viewer"
+)
+
 func (cfg *apiConfig) middlewareMustBeLoggedIn(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -35,6 +47,52 @@ func (cfg *apiConfig) middlewareMustBeLoggedIn(next http.HandlerFunc) http.Handl
 	})
 }
 
+// viewerID returns the user behind the request's bearer token or viewer
+// cookie, or 0 when there is neither. Use it on public routes that show
+// owners more; routes that require a login use middlewareMustBeLoggedIn
+// instead.
+func (cfg *apiConfig) viewerID(r *http.Request) int {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		cookie, cookieErr := r.Cookie(viewerCookie)
+		if cookieErr != nil {
+			return 0
+		}
+		token = cookie.Value
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+// setViewerCookie stores token in the viewer cookie for as long as it is
+// valid.
+func (cfg *apiConfig) setViewerCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewerCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(accessTokenLifetime / time.Second),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.siteURL(r), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (cfg *apiConfig) clearViewerCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewerCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.siteURL(r), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	type Params struct {
 		Name     string `json:"name"`
@@ -55,17 +113,19 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := cfg.DB.GetUser(r.Context(), params.Name)
 	if err != nil {
+		cfg.metrics.loginAttempt(false)
 		respondWithError(w, http.StatusInternalServerError, "failed to get user", err)
 		return
 	}
 
 	err = auth.CheckPasswordHash(params.Password, user.Password)
 	if err != nil {
+		cfg.metrics.loginAttempt(false)
 		respondWithError(w, http.StatusInternalServerError, "Incorrect password", err)
 		return
 	}
 
-	jwt, err := auth.MakeJWT(int(user.ID), cfg.jwtSecret, 1*time.Hour)
+	jwt, err := auth.MakeJWT(int(user.ID), cfg.jwtSecret, accessTokenLifetime)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to create jwt", err)
 		return
@@ -87,6 +147,9 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg.metrics.loginAttempt(true)
+	cfg.setViewerCookie(w, r, jwt)
+
 	respondWithJson(w, http.StatusOK, struct {
 		ID           int       `json:"id"`
 		Name         string    `json:"name"`
@@ -115,11 +178,12 @@ func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(int(userId), cfg.jwtSecret, 1*time.Hour)
+	accessToken, err := auth.MakeJWT(int(userId), cfg.jwtSecret, accessTokenLifetime)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to create jwt", err)
 		return
 	}
+	cfg.setViewerCookie(w, r, accessToken)
 
 	respondWithJson(w, http.StatusOK, struct {
 		Token string `json:"token"`
@@ -129,6 +193,9 @@ func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request)
 }
 
 func (cfg *apiConfig) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	// Logging out ends the page session even if the token can't be revoked
+	cfg.clearViewerCookie(w, r)
+
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "invalid refresh token", err)
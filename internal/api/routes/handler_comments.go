@@ -0,0 +1,536 @@
+package routes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/render"
+)
+
+// Comment moderation states. Reader comments start out pending; only
+// approved ones are shown.
+const (
+	commentPending  = "pending"
+	commentApproved = "approved"
+	commentRejected = "rejected"
+	commentSpam     = "spam"
+)
+
+const (
+	// minCommentDelay is the least time a person could take to fill in
+	// the form. Anything quicker is a bot and goes straight to spam.
+	minCommentDelay = 3 * time.Second
+
+	// maxCommentFormAge is how long a rendered form stays valid.
+	maxCommentFormAge = 24 * time.Hour
+
+	maxCommentNameLength = 100
+	maxCommentBodyLength = 5000
+	maxCommentFormBytes  = 64 << 10
+
+	// commentHoneypotField is hidden from people; bots fill it in.
+	commentHoneypotField = "company"
+)
+
+var commentStatuses = []string{commentPending, commentApproved, commentRejected, commentSpam}
+
+func validCommentStatus(status string) bool {
+	for _, s := range commentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Comment is a comment as the admin API shows it.
+type Comment struct {
+	ID           int       `json:"id"`
+	JournalID    int       `json:"journal_id"`
+	JournalTitle string    `json:"journal_title,omitempty"`
+	ParentID     *int      `json:"parent_id,omitempty"`
+	AuthorName   string    `json:"author_name"`
+	AuthorEmail  string    `json:"author_email,omitempty"`
+	AuthorURL    string    `json:"author_url,omitempty"`
+	Body         string    `json:"body"`
+	HTML         string    `json:"html"`
+	Status       string    `json:"status"`
+	IsOwner      bool      `json:"is_owner"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CommentsResponse struct {
+	Comments []Comment      `json:"comments"`
+	Counts   map[string]int `json:"counts"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	Limit    int            `json:"limit"`
+	HasMore  bool           `json:"has_more"`
+}
+
+func commentFromDB(c database.Comment) Comment {
+	comment := Comment{
+		ID:          int(c.ID),
+		JournalID:   int(c.JournalID),
+		AuthorName:  c.AuthorName,
+		AuthorEmail: c.AuthorEmail,
+		AuthorURL:   c.AuthorUrl,
+		Body:        c.Body,
+		HTML:        render.Comment(c.Body),
+		Status:      c.Status,
+		IsOwner:     c.IsOwner,
+		CreatedAt:   c.CreatedAt.Time,
+	}
+	if c.ParentID.Valid {
+		parentID := int(c.ParentID.Int64)
+		comment.ParentID = &parentID
+	}
+	return comment
+}
+
+// commentThread is an approved comment and the owner's replies to it, as
+// view-journal.html shows them.
+type commentThread struct {
+	database.Comment
+	Created time.Time
+	HTML    template.HTML
+	Replies []commentThread
+}
+
+// threadComments nests replies under the comment they answer, keeping the
+// order comments were given in, with times shown in loc. Replies whose
+// parent isn't in the list, e.g. because it was rejected later, are left
+// out.
+func threadComments(comments []database.Comment, loc *time.Location) []commentThread {
+	replies := map[int64][]commentThread{}
+	var threads []commentThread
+
+	for _, c := range comments {
+		t := commentThread{
+			Comment: c,
+			Created: c.CreatedAt.Time.In(loc),
+			// #nosec G203 - render.Comment sanitizes with a bluemonday policy
+			HTML: template.HTML(render.Comment(c.Body)),
+		}
+		if c.ParentID.Valid {
+			replies[c.ParentID.Int64] = append(replies[c.ParentID.Int64], t)
+			continue
+		}
+		threads = append(threads, t)
+	}
+
+	for i := range threads {
+		threads[i].Replies = replies[threads[i].ID]
+	}
+	return threads
+}
+
+// commentForm is the state of the comment form on a journal page: what the
+// reader typed, and why it was turned away if it was.
+type commentForm struct {
+	Started   string
+	Name      string
+	Email     string
+	Website   string
+	Body      string
+	Error     string
+	Submitted bool
+}
+
+// commentFormToken signs the time a journal's comment form was rendered,
+// so a submission can be timed without keeping any state.
+func (cfg *apiConfig) commentFormToken(journalID int64, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return ts + "." + cfg.commentFormSignature(journalID, ts)
+}
+
+func (cfg *apiConfig) commentFormSignature(journalID int64, ts string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.jwtSecret))
+	fmt.Fprintf(mac, "comment-form:%d:%s", journalID, ts)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// commentFormAge reports how long ago token was issued for journalID. It
+// returns false if the token is forged, for another entry, or expired.
+func (cfg *apiConfig) commentFormAge(journalID int64, token string, now time.Time) (time.Duration, bool) {
+	ts, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(cfg.commentFormSignature(journalID, ts))) {
+		return 0, false
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age < 0 || age > maxCommentFormAge {
+		return 0, false
+	}
+	return age, true
+}
+
+// validate checks the reader's input, returning the message to show them
+// if something is wrong.
+func (form commentForm) validate() string {
+	switch {
+	case form.Name == "":
+		return "Please enter your name."
+	case utf8.RuneCountInString(form.Name) > maxCommentNameLength:
+		return fmt.Sprintf("Names can be at most %d characters.", maxCommentNameLength)
+	case form.Body == "":
+		return "Please write a comment."
+	case utf8.RuneCountInString(form.Body) > maxCommentBodyLength:
+		return fmt.Sprintf("Comments can be at most %d characters.", maxCommentBodyLength)
+	}
+
+	if form.Email != "" {
+		addr, err := mail.ParseAddress(form.Email)
+		if err != nil || addr.Address != form.Email {
+			return "Please enter a valid email address, or leave it blank."
+		}
+	}
+
+	if form.Website != "" {
+		u, err := url.Parse(form.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "Websites must start with http:// or https://."
+		}
+	}
+
+	return ""
+}
+
+// handlePostComment takes a comment from the form on a public journal page
+// and queues it for moderation.
+func (cfg *apiConfig) handlePostComment(w http.ResponseWriter, r *http.Request) {
+	journalID, err := strconv.Atoi(r.PathValue("ID"))
+	if err != nil {
+		http.Error(w, "Invalid journal ID", http.StatusBadRequest)
+		return
+	}
+
+	journal, err := cfg.DB.GetJournalEntry(r.Context(), int64(journalID))
+	if err != nil || journal.Visibility != visibilityPublic {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCommentFormBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid comment form", http.StatusBadRequest)
+		return
+	}
+
+	pendingURL := fmt.Sprintf("/journals/%d?comment=pending#comments", journal.ID)
+
+	// Bots get the same response as everyone else so they learn nothing
+	if r.PostFormValue(commentHoneypotField) != "" {
+		requestLogger(w).Info("dropped comment caught by honeypot", "journal_id", journal.ID)
+		http.Redirect(w, r, pendingURL, http.StatusSeeOther)
+		return
+	}
+
+	form := commentForm{
+		Name:    strings.TrimSpace(r.PostFormValue("name")),
+		Email:   strings.TrimSpace(r.PostFormValue("email")),
+		Website: strings.TrimSpace(r.PostFormValue("website")),
+		Body:    strings.TrimSpace(r.PostFormValue("body")),
+	}
+
+	age, ok := cfg.commentFormAge(journal.ID, r.PostFormValue("started"), time.Now())
+	if !ok {
+		form.Error = "This form has expired. Please try again."
+	} else {
+		form.Error = form.validate()
+	}
+	if form.Error != "" {
+		cfg.renderJournalPage(w, r, journal, form)
+		return
+	}
+
+	status := commentPending
+	if age < minCommentDelay {
+		status = commentSpam
+	}
+
+	comment, err := cfg.DB.CreateComment(r.Context(), database.CreateCommentParams{
+		JournalID:   journal.ID,
+		AuthorName:  form.Name,
+		AuthorEmail: form.Email,
+		AuthorUrl:   form.Website,
+		Body:        form.Body,
+		Status:      status,
+	})
+	if err != nil {
+		requestLogger(w).Error("failed to save comment", "journal_id", journal.ID, "error", err)
+		http.Error(w, "Failed to save comment", http.StatusInternalServerError)
+		return
+	}
+	if status != commentSpam {
+		cfg.emitCommentEvent(r, eventCommentCreated, comment)
+	}
+
+	http.Redirect(w, r, pendingURL, http.StatusSeeOther)
+}
+
+// getComments lists comments in one moderation state, pending by default,
+// newest first.
+func (cfg *apiConfig) getComments(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = commentPending
+	}
+	if !validCommentStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "status must be pending, approved, rejected or spam", nil)
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "invalid limit parameter", err)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset parameter", err)
+			return
+		}
+		offset = n
+	}
+
+	counts, err := cfg.DB.CountCommentsByStatus(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count comments", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListCommentsByStatus(r.Context(), database.ListCommentsByStatusParams{
+		Status: status,
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list comments", err)
+		return
+	}
+
+	resp := CommentsResponse{
+		Comments: []Comment{},
+		Counts:   map[string]int{},
+		Page:     offset/limit + 1,
+		Limit:    limit,
+	}
+	for _, s := range commentStatuses {
+		resp.Counts[s] = 0
+	}
+	for _, c := range counts {
+		resp.Counts[c.Status] = int(c.Count)
+	}
+	resp.Total = resp.Counts[status]
+	resp.HasMore = offset+len(rows) < resp.Total
+
+	for _, row := range rows {
+		comment := commentFromDB(database.Comment{
+			ID:          row.ID,
+			JournalID:   row.JournalID,
+			ParentID:    row.ParentID,
+			AuthorName:  row.AuthorName,
+			AuthorEmail: row.AuthorEmail,
+			AuthorUrl:   row.AuthorUrl,
+			Body:        row.Body,
+			Status:      row.Status,
+			IsOwner:     row.IsOwner,
+			CreatedAt:   row.CreatedAt,
+		})
+		comment.JournalTitle = row.JournalTitle
+		resp.Comments = append(resp.Comments, comment)
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+func commentIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.Atoi(r.PathValue("commentID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid comment ID", err)
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// setCommentStatus approves, rejects or marks a comment as spam.
+func (cfg *apiConfig) setCommentStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := commentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var params struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+	if !validCommentStatus(params.Status) {
+		respondWithError(w, http.StatusBadRequest, "status must be pending, approved, rejected or spam", nil)
+		return
+	}
+
+	n, err := cfg.DB.SetCommentStatus(r.Context(), database.SetCommentStatusParams{
+		Status: params.Status,
+		ID:     id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update comment", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "comment not found", nil)
+		return
+	}
+	if comment, err := cfg.DB.GetComment(r.Context(), id); err == nil {
+		cfg.emitCommentEvent(r, eventCommentUpdated, comment)
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{
+		"message": "comment updated successfully",
+	})
+}
+
+// deleteComment removes a comment and any replies to it for good.
+func (cfg *apiConfig) deleteComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := commentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.DB.DeleteComment(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete comment", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "comment not found", nil)
+		return
+	}
+	cfg.emitCommentEvent(r, eventCommentDeleted, database.Comment{ID: id})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replyToComment posts the owner's reply under a comment. Threads are one
+// level deep, so replying to a reply answers its parent. Replying to a
+// pending comment approves it.
+func (cfg *apiConfig) replyToComment(w http.ResponseWriter, r *http.Request) {
+	id, ok := commentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var params struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+	params.Body = strings.TrimSpace(params.Body)
+	if params.Body == "" {
+		respondWithError(w, http.StatusBadRequest, "reply body is required", nil)
+		return
+	}
+
+	parent, err := cfg.DB.GetComment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "comment not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get comment", err)
+		return
+	}
+	if parent.ParentID.Valid {
+		parent, err = cfg.DB.GetComment(r.Context(), parent.ParentID.Int64)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to get comment", err)
+			return
+		}
+	}
+	if parent.Status == commentRejected || parent.Status == commentSpam {
+		respondWithError(w, http.StatusBadRequest, "cannot reply to a rejected comment", nil)
+		return
+	}
+
+	owner, err := cfg.DB.ListUser(r.Context())
+	if err != nil || len(owner) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "failed to get user", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save reply", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	if parent.Status == commentPending {
+		_, err = qtx.SetCommentStatus(r.Context(), database.SetCommentStatusParams{
+			Status: commentApproved,
+			ID:     parent.ID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "failed to approve comment", err)
+			return
+		}
+	}
+
+	reply, err := qtx.CreateComment(r.Context(), database.CreateCommentParams{
+		JournalID:  parent.JournalID,
+		ParentID:   sql.NullInt64{Int64: parent.ID, Valid: true},
+		AuthorName: owner[0].Name,
+		Body:       params.Body,
+		Status:     commentApproved,
+		IsOwner:    true,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save reply", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save reply", err)
+		return
+	}
+	if parent.Status == commentPending {
+		parent.Status = commentApproved
+		cfg.emitCommentEvent(r, eventCommentUpdated, parent)
+	}
+	cfg.emitCommentEvent(r, eventCommentCreated, reply)
+
+	respondWithJson(w, http.StatusCreated, commentFromDB(reply))
+}
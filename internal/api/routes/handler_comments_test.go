@@ -0,0 +1,242 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestCommentFormAge(t *testing.T) {
+	apiCfg := &apiConfig{jwtSecret: "test-secret-key"}
+	issued := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	token := apiCfg.commentFormToken(7, issued)
+
+	if age, ok := apiCfg.commentFormAge(7, token, issued.Add(time.Minute)); !ok || age != time.Minute {
+		t.Errorf("Expected a one minute old form, got %v, %v", age, ok)
+	}
+
+	forged := strconv.FormatInt(issued.Add(-time.Hour).Unix(), 10) + token[strings.Index(token, "."):]
+
+	tests := []struct {
+		name      string
+		journalID int64
+		token     string
+		now       time.Time
+	}{
+		{"other journal", 8, token, issued.Add(time.Minute)},
+		{"expired", 7, token, issued.Add(maxCommentFormAge + time.Second)},
+		{"from the future", 7, token, issued.Add(-time.Minute)},
+		{"tampered", 7, forged, issued.Add(time.Minute)},
+		{"garbage", 7, "nonsense", issued},
+	}
+
+	for _, tt := range tests {
+		if _, ok := apiCfg.commentFormAge(tt.journalID, tt.token, tt.now); ok {
+			t.Errorf("Expected %s token to be rejected", tt.name)
+		}
+	}
+}
+
+func TestPostComment(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	apiCfg.templates = template.Must(template.New("view-journal.html").Parse(`{{ .CommentForm.Error }}`))
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "commentuser", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Open for comments", Content: "content", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalID := strconv.FormatInt(journal.ID, 10)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/journals/"+journalID+"/comments", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("ID", journalID)
+		rr := httptest.NewRecorder()
+		apiCfg.handlePostComment(rr, req)
+		return rr
+	}
+	form := func(started time.Time, fields ...string) url.Values {
+		v := url.Values{
+			"started": {apiCfg.commentFormToken(journal.ID, started)},
+			"name":    {"Reader"},
+			"body":    {"Lovely post"},
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			v.Set(fields[i], fields[i+1])
+		}
+		return v
+	}
+	statuses := func() map[string]int {
+		counts, err := apiCfg.DB.CountCommentsByStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := map[string]int{}
+		for _, c := range counts {
+			m[c.Status] = int(c.Count)
+		}
+		return m
+	}
+
+	earlier := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantBody   string
+	}{
+		{"honeypot", form(earlier, "company", "Acme"), http.StatusSeeOther, ""},
+		{"too fast", form(time.Now()), http.StatusSeeOther, ""},
+		{"missing name", form(earlier, "name", " "), http.StatusBadRequest, "Please enter your name."},
+		{"bad email", form(earlier, "email", "not an email"), http.StatusBadRequest, "valid email"},
+		{"bad website", form(earlier, "website", "javascript:alert(1)"), http.StatusBadRequest, "http://"},
+		{"forged form", form(earlier, "started", "1.abc"), http.StatusBadRequest, "expired"},
+		{"valid", form(earlier, "email", "reader@example.com", "website", "https://example.com"), http.StatusSeeOther, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := post(tt.form)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("Expected %q in response, got %s", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+
+	// The honeypot hit is dropped, the rushed one kept as spam
+	if got := statuses(); got[commentPending] != 1 || got[commentSpam] != 1 || len(got) != 2 {
+		t.Errorf("Unexpected comment counts: %v", got)
+	}
+
+	private, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Closed", Content: "content", UserID: user.ID, Format: "html", Visibility: "private",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(form(earlier).Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("ID", strconv.FormatInt(private.ID, 10))
+	rr := httptest.NewRecorder()
+	apiCfg.handlePostComment(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected comments on private entries to 404, got %d", rr.Code)
+	}
+}
+
+func TestCommentModeration(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "moderator", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Entry", Content: "content", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err := apiCfg.DB.CreateComment(ctx, database.CreateCommentParams{
+		JournalID: journal.ID, AuthorName: "Reader", Body: "First!", Status: commentPending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID := strconv.FormatInt(comment.ID, 10)
+
+	call := func(handler http.HandlerFunc, method, target string, body any, values map[string]string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, target, &buf)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := call(apiCfg.getComments, "GET", "/api/comments", nil, nil)
+	var queue CommentsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&queue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.Comments) != 1 || queue.Comments[0].JournalTitle != "Entry" || queue.Counts[commentPending] != 1 {
+		t.Fatalf("Unexpected moderation queue: %+v", queue)
+	}
+
+	if rr := call(apiCfg.setCommentStatus, "PUT", "/", map[string]string{"status": "bogus"}, map[string]string{"commentID": commentID}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown status to return 400, got %d", rr.Code)
+	}
+
+	// Replying approves the comment and threads the reply under it
+	rr = call(apiCfg.replyToComment, "POST", "/", map[string]string{"body": "Thanks!"}, map[string]string{"commentID": commentID})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected reply to return 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var reply Comment
+	if err := json.NewDecoder(rr.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.IsOwner || reply.AuthorName != "moderator" || reply.ParentID == nil || *reply.ParentID != int(comment.ID) {
+		t.Errorf("Unexpected reply: %+v", reply)
+	}
+
+	// A reply to the reply still hangs off the original comment
+	rr = call(apiCfg.replyToComment, "POST", "/", map[string]string{"body": "Also"}, map[string]string{"commentID": strconv.Itoa(reply.ID)})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected reply to return 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	approved, err := apiCfg.DB.ListApprovedComments(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threads := threadComments(approved, time.UTC)
+	if len(threads) != 1 || len(threads[0].Replies) != 2 {
+		t.Fatalf("Expected one thread with two replies, got %+v", threads)
+	}
+
+	rr = call(apiCfg.setCommentStatus, "PUT", "/", map[string]string{"status": commentSpam}, map[string]string{"commentID": commentID})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status change to return 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	approved, _ = apiCfg.DB.ListApprovedComments(ctx, journal.ID)
+	if threads := threadComments(approved, time.UTC); len(threads) != 0 {
+		t.Errorf("Expected replies to a spam comment to be hidden, got %+v", threads)
+	}
+
+	if rr := call(apiCfg.deleteComment, "DELETE", "/", nil, map[string]string{"commentID": commentID}); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected delete to return 204, got %d", rr.Code)
+	}
+	if counts, _ := apiCfg.DB.CountCommentsByStatus(ctx); len(counts) != 0 {
+		t.Errorf("Expected the comment and its replies to be gone, got %+v", counts)
+	}
+	if rr := call(apiCfg.deleteComment, "DELETE", "/", nil, map[string]string{"commentID": commentID}); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting again to return 404, got %d", rr.Code)
+	}
+}
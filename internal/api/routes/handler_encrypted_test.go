@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestEncryptedJournalEntries(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "cryptuser", "password")
+
+	// The server only checks the envelope's shape, so random bytes stand in
+	// for real ciphertext.
+	envelope := `{"v":1,"kdf":"argon2id","m":65536,"t":3,"p":1,` +
+		`"salt":"AAECAwQFBgcICQoLDA0ODw==","alg":"A256GCM","iv":"AAECAwQFBgcICQoL",` +
+		`"ct":"3q2+796tvu/erb7v3q2+796tvu8="}`
+
+	post := func(payload map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/api/journals", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		rr := httptest.NewRecorder()
+		apiCfg.postJournalEntry(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]any
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "plaintext content",
+			payload:    map[string]any{"title": "Secret", "content": "dear diary", "encrypted": true},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid encryption envelope",
+		},
+		{
+			name:       "public visibility",
+			payload:    map[string]any{"title": "Secret", "content": envelope, "encrypted": true, "visibility": "public"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "encrypted entries must be private",
+		},
+		{
+			name:       "valid envelope",
+			payload:    map[string]any{"title": "Secret", "content": envelope, "encrypted": true},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := post(tt.payload)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantError != "" && !strings.Contains(rr.Body.String(), tt.wantError) {
+				t.Errorf("Expected error %q, got %s", tt.wantError, rr.Body.String())
+			}
+		})
+	}
+
+	journals, err := apiCfg.DB.GetJournals(ctx, database.GetJournalsParams{
+		ViewerID: user.ID, Limit: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journals) != 1 {
+		t.Fatalf("Expected one stored entry, got %d", len(journals))
+	}
+
+	journal := journals[0]
+	apiCfg.ensureRendered(ctx, &journal)
+	if !journal.Encrypted || journal.Visibility != visibilityPrivate {
+		t.Errorf("Expected an encrypted private entry, got encrypted=%v visibility=%s", journal.Encrypted, journal.Visibility)
+	}
+	if journal.ContentHtml != "" || journal.Excerpt != "" || journal.WordCount != 0 {
+		t.Errorf("Expected no rendered content or summary, got %q, %q, %d", journal.ContentHtml, journal.Excerpt, journal.WordCount)
+	}
+	if !strings.HasPrefix(journal.Content, `{"v":1,`) {
+		t.Errorf("Expected the envelope to be stored, got %s", journal.Content)
+	}
+}
+
+func TestEditKeepsEncryption(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "cryptedit", "password")
+
+	envelope := `{"v":1,"kdf":"argon2id","m":65536,"t":3,"p":1,` +
+		`"salt":"AAECAwQFBgcICQoLDA0ODw==","alg":"A256GCM","iv":"AAECAwQFBgcICQoL",` +
+		`"ct":"3q2+796tvu/erb7v3q2+796tvu8="}`
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Secret", Content: envelope, UserID: user.ID, Format: "html",
+		Visibility: visibilityPrivate, Encrypted: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit := func(content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"id": journal.ID, "title": "Renamed", "content": content})
+		req := httptest.NewRequest("PUT", "/api/journals", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		rr := httptest.NewRecorder()
+		apiCfg.editJournalEntry(rr, req)
+		return rr
+	}
+
+	// A client that doesn't send encrypted can rename the entry without
+	// publishing the envelope
+	if rr := edit(envelope); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the edit to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	edited, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Title != "Renamed" || !edited.Encrypted || edited.Visibility != visibilityPrivate || edited.ContentHtml != "" {
+		t.Errorf("Expected the entry to stay encrypted, got encrypted=%v visibility=%s html=%q",
+			edited.Encrypted, edited.Visibility, edited.ContentHtml)
+	}
+
+	// Plaintext in place of the envelope is refused
+	rr := edit("dear diary")
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "invalid encryption envelope") {
+		t.Fatalf("Expected plaintext to be rejected, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if stored, _ := apiCfg.DB.GetJournalEntry(ctx, journal.ID); !stored.Encrypted || stored.Content == "dear diary" {
+		t.Errorf("Expected the rejected edit not to be stored, got %+v", stored)
+	}
+}
+
+func TestEncryptedJournalMeta(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	journal := database.JournalEntry{
+		ID: 1, Title: "Secret", Encrypted: true, Visibility: visibilityPrivate,
+		Content: `{"v":1,"kdf":"argon2id","ct":"3q2+796tvu/erb7v3q2+796tvu8="}`,
+	}
+	data := map[string]interface{}{"Settings": defaultSiteSettings, "Name": "Owner"}
+	req := httptest.NewRequest("GET", "/journals/1", nil)
+
+	// The envelope never ends up in the description or share tags
+	if meta := apiCfg.journalMeta(req, data, journal); meta.Description != "Encrypted entry" {
+		t.Errorf("Expected a fixed description, got %q", meta.Description)
+	}
+
+	journal.SeoDescription.String, journal.SeoDescription.Valid = "Notes to self", true
+	if meta := apiCfg.journalMeta(req, data, journal); meta.Description != "Notes to self" {
+		t.Errorf("Expected the SEO description to be used, got %q", meta.Description)
+	}
+}
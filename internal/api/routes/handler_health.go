@@ -1,10 +1,145 @@
 package routes
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/sianwa11/my-journal/internal/sql/schema"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// requiredTemplates are the pages the site cannot render without.
+var requiredTemplates = []string{
+	"me.html",
+	"list-journals.html",
+	"view-journal.html",
+	"list-projects.html",
+	"view-project.html",
+	"login.html",
+	"index.html",
+}
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// healthCheck is the liveness probe: it only reports that the process is up.
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	respondWithJson(w, http.StatusOK, map[string]string{
 		"status":  "healthy",
 		"service": "my-journal",
 	})
 }
+
+// readinessCheck reports whether the instance can serve traffic. It responds
+// with 503 when any critical dependency is unavailable.
+func (cfg *apiConfig) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := []struct {
+		name     string
+		critical bool
+		run      func(context.Context) error
+	}{
+		{"database", true, cfg.checkDatabase},
+		{"migrations", true, cfg.checkMigrations},
+		{"templates", true, cfg.checkTemplates},
+		{"media_storage", false, cfg.checkMediaStorage},
+	}
+
+	resp := readinessResponse{
+		Status: "ready",
+		Checks: map[string]checkResult{},
+	}
+	code := http.StatusOK
+
+	for _, check := range checks {
+		start := time.Now()
+		err := check.run(ctx)
+
+		result := checkResult{
+			Status:   "ok",
+			Critical: check.critical,
+			Latency:  time.Since(start).String(),
+		}
+		if err != nil {
+			result.Status = "failing"
+			result.Error = err.Error()
+			requestLogger(w).Warn("readiness check failed", "check", check.name, "error", err)
+
+			if check.critical {
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+			}
+		}
+		resp.Checks[check.name] = result
+	}
+
+	respondWithJson(w, code, resp)
+}
+
+func (cfg *apiConfig) checkDatabase(ctx context.Context) error {
+	if cfg.dbConn == nil {
+		return fmt.Errorf("database not configured")
+	}
+	return cfg.dbConn.PingContext(ctx)
+}
+
+func (cfg *apiConfig) checkMigrations(ctx context.Context) error {
+	if cfg.dbConn == nil {
+		return fmt.Errorf("database not configured")
+	}
+
+	pending, err := schema.Pending(ctx, cfg.dbConn)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, first is %d", len(pending), pending[0])
+	}
+	return nil
+}
+
+func (cfg *apiConfig) checkTemplates(ctx context.Context) error {
+	if cfg.templates == nil {
+		return fmt.Errorf("templates not parsed")
+	}
+
+	for _, name := range requiredTemplates {
+		if cfg.templates.Lookup(name) == nil {
+			return fmt.Errorf("template %s not found", name)
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) checkMediaStorage(ctx context.Context) error {
+	if err := os.MkdirAll(cfg.mediaDir, 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(cfg.mediaDir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/sql/schema"
+)
+
+func TestReadinessCheck(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	apiCfg.mediaDir = t.TempDir()
+	apiCfg.templates = template.New("")
+	for _, name := range requiredTemplates {
+		template.Must(apiCfg.templates.New(name).Parse("ok"))
+	}
+
+	check := func(wantStatus int) readinessResponse {
+		t.Helper()
+
+		rr := httptest.NewRecorder()
+		apiCfg.readinessCheck(rr, httptest.NewRequest("GET", "/api/readyz", nil))
+
+		if rr.Code != wantStatus {
+			t.Fatalf("Expected status %d, got %d. Response: %s", wantStatus, rr.Code, rr.Body.String())
+		}
+
+		var resp readinessResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to parse response JSON: %v", err)
+		}
+		return resp
+	}
+
+	// No goose_db_version table yet, so migrations are not current
+	resp := check(http.StatusServiceUnavailable)
+	if resp.Checks["migrations"].Status != "failing" {
+		t.Errorf("Expected migrations check to fail, got %+v", resp.Checks["migrations"])
+	}
+	if resp.Checks["database"].Status != "ok" {
+		t.Errorf("Expected database check to pass, got %+v", resp.Checks["database"])
+	}
+
+	_, err := db.Exec(`CREATE TABLE goose_db_version (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		version_id INTEGER NOT NULL,
+		is_applied INTEGER NOT NULL
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create goose table: %v", err)
+	}
+
+	versions, err := schema.Versions()
+	if err != nil {
+		t.Fatalf("Failed to read migration versions: %v", err)
+	}
+	for _, version := range versions {
+		if _, err := db.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, 1)", version); err != nil {
+			t.Fatalf("Failed to record migration: %v", err)
+		}
+	}
+
+	resp = check(http.StatusOK)
+	if resp.Status != "ready" {
+		t.Errorf("Expected status ready, got %s", resp.Status)
+	}
+
+	// A failing non-critical check is reported but does not fail readiness
+	apiCfg.mediaDir = "/dev/null/media"
+	resp = check(http.StatusOK)
+	if resp.Checks["media_storage"].Status != "failing" {
+		t.Errorf("Expected media storage check to fail, got %+v", resp.Checks["media_storage"])
+	}
+}
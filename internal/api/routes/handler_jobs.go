@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/webmention"
+)
+
+const (
+	defaultJobConcurrency = 2
+
+	// webmentionAttempts is how many times sending a Webmention is tried.
+	// With the queue's backoff that spans about an hour.
+	webmentionAttempts = 8
+)
+
+var jobStatuses = []string{jobs.StatusPending, jobs.StatusRunning, jobs.StatusDead}
+
+// jobConcurrencyFromEnv reads JOB_CONCURRENCY, the number of background
+// jobs run at once.
+func jobConcurrencyFromEnv(value string) int {
+	if value == "" {
+		return defaultJobConcurrency
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		slog.Warn("invalid JOB_CONCURRENCY, using default", "value", value)
+		return defaultJobConcurrency
+	}
+	return n
+}
+
+// backgroundJobs are the kinds of job the server enqueues.
+type backgroundJobs struct {
+	sendWebmention    jobs.Kind[webmentionJob]
+	confirmSubscriber jobs.Kind[subscriberJob]
+	sendDigest        jobs.Kind[digestJob]
+	deliverActivity   jobs.Kind[activityJob]
+	deliverWebhook    jobs.Kind[webhookJob]
+}
+
+// registerJobs sets up the job queue and its handlers. Call it before
+// starting the queue.
+func (cfg *apiConfig) registerJobs(queue *jobs.Queue) {
+	cfg.jobs = queue
+	cfg.jobKinds.sendWebmention = jobs.Register(queue, "webmention.send", cfg.runSendWebmention)
+	cfg.jobKinds.confirmSubscriber = jobs.Register(queue, "newsletter.confirm", cfg.runConfirmSubscriber)
+	cfg.jobKinds.sendDigest = jobs.Register(queue, "newsletter.digest", cfg.runSendDigest)
+	cfg.jobKinds.deliverActivity = jobs.Register(queue, "activitypub.deliver", cfg.runDeliverActivity)
+	cfg.jobKinds.deliverWebhook = jobs.Register(queue, "webhook.deliver", cfg.runDeliverWebhook)
+}
+
+type webmentionJob struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// runSendWebmention notifies one linked page. Pages without an endpoint
+// have nothing to notify, so that counts as done.
+func (cfg *apiConfig) runSendWebmention(ctx context.Context, job webmentionJob) error {
+	err := cfg.webmentions.Send(ctx, job.Source, job.Target)
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "sent webmention", "source", job.Source, "target", job.Target)
+		return nil
+	case errors.Is(err, webmention.ErrNoEndpoint):
+		return nil
+	default:
+		return err
+	}
+}
+
+// Job is a queued, running or dead background job as the admin API shows
+// it.
+type Job struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	Payload     string    `json:"payload"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	RunAt       time.Time `json:"run_at"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type JobsResponse struct {
+	Jobs    []Job          `json:"jobs"`
+	Counts  map[string]int `json:"counts"`
+	Total   int            `json:"total"`
+	Page    int            `json:"page"`
+	Limit   int            `json:"limit"`
+	HasMore bool           `json:"has_more"`
+}
+
+func validJobStatus(status string) bool {
+	for _, s := range jobStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getJobs lists background jobs in one state, dead by default, most
+// recently updated first.
+func (cfg *apiConfig) getJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = jobs.StatusDead
+	}
+	if !validJobStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "status must be pending, running or dead", nil)
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "invalid limit parameter", err)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset parameter", err)
+			return
+		}
+		offset = n
+	}
+
+	counts, err := cfg.DB.CountJobsByStatus(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count jobs", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListJobsByStatus(r.Context(), database.ListJobsByStatusParams{
+		Status: status,
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list jobs", err)
+		return
+	}
+
+	resp := JobsResponse{
+		Jobs:   []Job{},
+		Counts: map[string]int{},
+		Page:   offset/limit + 1,
+		Limit:  limit,
+	}
+	for _, s := range jobStatuses {
+		resp.Counts[s] = 0
+	}
+	for _, c := range counts {
+		resp.Counts[c.Status] = int(c.Count)
+	}
+	resp.Total = resp.Counts[status]
+	resp.HasMore = offset+len(rows) < resp.Total
+
+	for _, row := range rows {
+		resp.Jobs = append(resp.Jobs, Job{
+			ID:          int(row.ID),
+			Kind:        row.Kind,
+			Payload:     row.Payload,
+			Status:      row.Status,
+			Attempts:    int(row.Attempts),
+			MaxAttempts: int(row.MaxAttempts),
+			RunAt:       row.RunAt,
+			LastError:   row.LastError,
+			CreatedAt:   row.CreatedAt.Time,
+			UpdatedAt:   row.UpdatedAt.Time,
+		})
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+func jobIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.Atoi(r.PathValue("jobID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid job ID", err)
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// retryJob puts a dead job back in the queue with its attempts reset.
+func (cfg *apiConfig) retryJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.DB.RequeueDeadJob(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to retry job", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "dead job not found", nil)
+		return
+	}
+	if cfg.jobs != nil {
+		cfg.jobs.Wake()
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{
+		"message": "job queued for retry",
+	})
+}
+
+// deleteJob discards a dead job.
+func (cfg *apiConfig) deleteJob(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.DB.DeleteDeadJob(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete job", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "dead job not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/jobs"
+)
+
+func TestJobsAPI(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "owner", "password")
+	queue := jobs.New(apiCfg.DB, jobs.Options{})
+	apiCfg.registerJobs(queue)
+
+	fail := true
+	broken := jobs.Register(queue, "broken", func(context.Context, struct{}) error {
+		if fail {
+			return jobs.Permanent(errors.New("no such file"))
+		}
+		return nil
+	})
+	id, err := broken.Enqueue(ctx, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.RunDue(ctx)
+
+	call := func(handler http.HandlerFunc, method, target string, values map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, &bytes.Buffer{})
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := call(apiCfg.getJobs, "GET", "/api/jobs", nil)
+	var list JobsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Jobs) != 1 || list.Jobs[0].Kind != "broken" || list.Jobs[0].LastError != "no such file" || list.Counts[jobs.StatusDead] != 1 {
+		t.Fatalf("Unexpected dead jobs: %+v", list)
+	}
+
+	if rr := call(apiCfg.getJobs, "GET", "/api/jobs?status=done", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown status to return 400, got %d", rr.Code)
+	}
+
+	jobID := map[string]string{"jobID": strconv.FormatInt(id, 10)}
+	if rr := call(apiCfg.retryJob, "POST", "/", jobID); rr.Code != http.StatusOK {
+		t.Fatalf("Expected retry to return 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(apiCfg.retryJob, "POST", "/", jobID); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected retrying a queued job to return 404, got %d", rr.Code)
+	}
+	if rr := call(apiCfg.deleteJob, "DELETE", "/", jobID); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting a queued job to return 404, got %d", rr.Code)
+	}
+
+	fail = false
+	if ran, _ := queue.RunDue(ctx); ran != 1 {
+		t.Errorf("Expected the retried job to run, got %d", ran)
+	}
+
+	// Dead jobs can be discarded
+	id, _ = broken.Enqueue(ctx, struct{}{})
+	fail = true
+	queue.RunDue(ctx)
+	if rr := call(apiCfg.deleteJob, "DELETE", "/", map[string]string{"jobID": strconv.FormatInt(id, 10)}); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected delete to return 204, got %d", rr.Code)
+	}
+	var left int
+	db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&left)
+	if left != 0 {
+		t.Errorf("Expected no jobs left, got %d", left)
+	}
+}
+
+func TestJobConcurrencyFromEnv(t *testing.T) {
+	tests := map[string]int{"": defaultJobConcurrency, "4": 4, "0": defaultJobConcurrency, "lots": defaultJobConcurrency}
+	for value, want := range tests {
+		if got := jobConcurrencyFromEnv(value); got != want {
+			t.Errorf("jobConcurrencyFromEnv(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
@@ -8,23 +8,66 @@ import (
 	"strconv"
 
 	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/render"
 )
 
 type Journal struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	UserID    int    `json:"user_id"`
+	ID             int    `json:"id"`
+	Title          string `json:"title"`
+	Content        string `json:"content"`
+	Format         string `json:"format"`
+	WordCount      int    `json:"word_count"`
+	ReadingMinutes int    `json:"reading_minutes"`
+	Excerpt        string `json:"excerpt"`
+	Visibility     string `json:"visibility"`
+	ShareURL       string `json:"share_url,omitempty"`
+	Encrypted      bool   `json:"encrypted"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	UserID         int    `json:"user_id"`
+	SeoTitle       string `json:"seo_title,omitempty"`
+	SeoDescription string `json:"seo_description,omitempty"`
 }
 
+// JournalDetail is a single entry with its table of contents.
+type JournalDetail struct {
+	Journal
+	TOC []render.Heading `json:"toc"`
+}
+
+// JournalsResponse lists entries. Journals holds Journal values, or
+// trimmed-down objects when the request named ?fields=.
 type JournalsResponse struct {
-	Journals []Journal `json:"journals"`
-	Total    int       `json:"total"`
-	Page     int       `json:"page"`
-	Limit    int       `json:"limit"`
-	HasMore  bool      `json:"has_more"`
+	Journals []interface{} `json:"journals"`
+	Total    int           `json:"total"`
+	Page     int           `json:"page"`
+	Limit    int           `json:"limit"`
+	HasMore  bool          `json:"has_more"`
+}
+
+// journalFromDB converts a row for the API. The share link is only
+// included for the entry's owner.
+func journalFromDB(journal database.JournalEntry, viewerID int) Journal {
+	j := Journal{
+		ID:             int(journal.ID),
+		Title:          journal.Title,
+		Content:        journal.Content,
+		Format:         journal.Format,
+		WordCount:      int(journal.WordCount),
+		ReadingMinutes: int(journal.ReadingMinutes),
+		Excerpt:        journal.Excerpt,
+		Visibility:     journal.Visibility,
+		Encrypted:      journal.Encrypted,
+		CreatedAt:      journal.CreatedAt.Time.String(),
+		UpdatedAt:      journal.UpdatedAt.Time.String(),
+		UserID:         int(journal.UserID),
+		SeoTitle:       journal.SeoTitle.String,
+		SeoDescription: journal.SeoDescription.String,
+	}
+	if journal.ShareToken.Valid && viewerID != 0 && journal.UserID == int64(viewerID) {
+		j.ShareURL = sharePath(journal.ShareToken.String)
+	}
+	return j
 }
 
 func (cfg *apiConfig) getJournalEntries(w http.ResponseWriter, r *http.Request) {
@@ -51,31 +94,45 @@ func (cfg *apiConfig) getJournalEntries(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	totalCount, err := cfg.DB.GetAllJournalsCount(r.Context())
+	fields, err := parseFields(r, Journal{})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid fields parameter", err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+
+	totalCount, err := cfg.DB.GetAllJournalsCount(r.Context(), int64(viewerID))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to get journals count", err)
 		return
 	}
 
 	journals, err := cfg.DB.GetJournals(r.Context(), database.GetJournalsParams{
-		Limit:  int64(limitInt),
-		Offset: int64(offsetInt),
+		ViewerID: int64(viewerID),
+		Limit:    int64(limitInt),
+		Offset:   int64(offsetInt),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "could not fetch journals", err)
 		return
 	}
 
-	journalEntries := []Journal{}
+	journalEntries := []interface{}{}
 	for _, journal := range journals {
-		journalEntries = append(journalEntries, Journal{
-			ID:        int(journal.ID),
-			Title:     journal.Title,
-			Content:   journal.Content,
-			CreatedAt: journal.CreatedAt.Time.String(),
-			UpdatedAt: journal.UpdatedAt.Time.String(),
-			UserID:    int(journal.UserID),
-		})
+		cfg.ensureRendered(r.Context(), &journal)
+
+		if fields == nil {
+			journalEntries = append(journalEntries, journalFromDB(journal, viewerID))
+			continue
+		}
+
+		picked, err := pickFields(journalFromDB(journal, viewerID), fields)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "could not encode journals", err)
+			return
+		}
+		journalEntries = append(journalEntries, picked)
 	}
 
 	// Calculate pagination info
@@ -109,21 +166,30 @@ func (cfg *apiConfig) getJournalEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJson(w, http.StatusOK, Journal{
-		ID:        int(journalEntry.ID),
-		Title:     journalEntry.Title,
-		Content:   journalEntry.Content,
-		CreatedAt: journalEntry.CreatedAt.Time.String(),
-		UpdatedAt: journalEntry.CreatedAt.Time.String(),
-		UserID:    int(journalEntry.UserID),
+	viewerID := cfg.viewerID(r)
+	if !canViewJournal(journalEntry, viewerID) {
+		respondWithError(w, http.StatusNotFound, "journal not found", nil)
+		return
+	}
+
+	cfg.ensureRendered(r.Context(), &journalEntry)
+
+	respondWithJson(w, http.StatusOK, JournalDetail{
+		Journal: journalFromDB(journalEntry, viewerID),
+		TOC:     render.TableOfContents(journalEntry.ContentHtml),
 	})
 
 }
 
 func (cfg *apiConfig) postJournalEntry(w http.ResponseWriter, r *http.Request) {
 	type Req struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
+		Title          string `json:"title"`
+		Content        string `json:"content"`
+		Format         string `json:"format"`
+		Visibility     string `json:"visibility"`
+		Encrypted      bool   `json:"encrypted"`
+		SeoTitle       string `json:"seo_title"`
+		SeoDescription string `json:"seo_description"`
 	}
 
 	var req Req
@@ -133,37 +199,100 @@ func (cfg *apiConfig) postJournalEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Format == "" {
+		req.Format = render.FormatHTML
+	}
+	if !render.ValidFormat(req.Format) {
+		respondWithError(w, http.StatusBadRequest, "format must be html or markdown", nil)
+		return
+	}
+
+	if req.Visibility == "" {
+		req.Visibility = visibilityPublic
+		if req.Encrypted {
+			req.Visibility = visibilityPrivate
+		}
+	}
+	if !validVisibility(req.Visibility) {
+		respondWithError(w, http.StatusBadRequest, "visibility must be public, unlisted or private", nil)
+		return
+	}
+	shareToken := shareTokenFor(req.Visibility, "")
+
+	var contentHTML string
+	var summary render.Summary
+	if req.Encrypted {
+		req.Content, err = sealedContent(req.Content, req.Visibility)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	} else {
+		contentHTML, err = render.HTML(req.Format, req.Content)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "could not render content", err)
+			return
+		}
+		summary = render.Summarize(contentHTML)
+	}
+
 	userIdInt := r.Context().Value(userIDKey).(int)
 	userId := int64(userIdInt)
 
 	journal, err := cfg.DB.CreateJournalEntry(r.Context(), database.CreateJournalEntryParams{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  userId,
+		Title:          req.Title,
+		Content:        req.Content,
+		UserID:         userId,
+		SeoTitle:       sql.NullString{String: req.SeoTitle, Valid: req.SeoTitle != ""},
+		SeoDescription: sql.NullString{String: req.SeoDescription, Valid: req.SeoDescription != ""},
+		Format:         req.Format,
+		ContentHtml:    contentHTML,
+		WordCount:      int64(summary.WordCount),
+		ReadingMinutes: int64(summary.ReadingMinutes),
+		Excerpt:        summary.Excerpt,
+		Visibility:     req.Visibility,
+		ShareToken:     sql.NullString{String: shareToken, Valid: shareToken != ""},
+		Encrypted:      req.Encrypted,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to create journal entry", err)
+		return
 	}
 
+	cfg.journalChanged(r, database.JournalEntry{}, journal)
+
 	respondWithJson(w, http.StatusCreated, struct {
-		Title     string `json:"title"`
-		Content   string `json:"content"`
-		CreatedAt string `json:"created_at"`
-		UserID    int    `json:"user_id"`
+		Title      string `json:"title"`
+		Content    string `json:"content"`
+		Format     string `json:"format"`
+		Visibility string `json:"visibility"`
+		ShareURL   string `json:"share_url,omitempty"`
+		Encrypted  bool   `json:"encrypted"`
+		CreatedAt  string `json:"created_at"`
+		UserID     int    `json:"user_id"`
 	}{
-		Title:     journal.Title,
-		Content:   journal.Content,
-		CreatedAt: journal.CreatedAt.Time.String(),
-		UserID:    int(journal.UserID),
+		Title:      journal.Title,
+		Content:    journal.Content,
+		Format:     journal.Format,
+		Visibility: journal.Visibility,
+		ShareURL:   journalFromDB(journal, userIdInt).ShareURL,
+		Encrypted:  journal.Encrypted,
+		CreatedAt:  journal.CreatedAt.Time.String(),
+		UserID:     int(journal.UserID),
 	})
 
 }
 
 func (cfg *apiConfig) editJournalEntry(w http.ResponseWriter, r *http.Request) {
 	type Params struct {
-		ID      int    `json:"id"`
-		Title   string `json:"title"`
-		Content string `json:"content"`
+		ID             int    `json:"id"`
+		Title          string `json:"title"`
+		Content        string `json:"content"`
+		Format         string `json:"format"`
+		Visibility     string `json:"visibility"`
+		Encrypted      *bool  `json:"encrypted"`
+		SeoTitle       string `json:"seo_title"`
+		SeoDescription string `json:"seo_description"`
 	}
 
 	var params Params
@@ -178,21 +307,139 @@ func (cfg *apiConfig) editJournalEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	existing, err := cfg.DB.GetJournalEntry(r.Context(), int64(params.ID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "journal not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get journal", err)
+		return
+	}
+
+	// Leaving format out keeps the entry's, so Markdown isn't re-read as HTML
+	if params.Format == "" {
+		params.Format = existing.Format
+	}
+	if !render.ValidFormat(params.Format) {
+		respondWithError(w, http.StatusBadRequest, "format must be html or markdown", nil)
+		return
+	}
+
+	// Leaving encrypted out keeps the entry as it was, so an edit from an
+	// older client can't publish an envelope as a normal post
+	encrypted := existing.Encrypted
+	if params.Encrypted != nil {
+		encrypted = *params.Encrypted
+	}
+
+	// Leaving visibility out keeps the entry as it was, so an edit from an
+	// older client can't publish a private entry
+	if params.Visibility == "" {
+		params.Visibility = existing.Visibility
+		if encrypted {
+			params.Visibility = visibilityPrivate
+		}
+	}
+	if !validVisibility(params.Visibility) {
+		respondWithError(w, http.StatusBadRequest, "visibility must be public, unlisted or private", nil)
+		return
+	}
+	shareToken := shareTokenFor(params.Visibility, existing.ShareToken.String)
+
+	var contentHTML string
+	var summary render.Summary
+	if encrypted {
+		params.Content, err = sealedContent(params.Content, params.Visibility)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	} else {
+		contentHTML, err = render.HTML(params.Format, params.Content)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "could not render content", err)
+			return
+		}
+		summary = render.Summarize(contentHTML)
+	}
+
 	err = cfg.DB.UpdateJournalEntry(r.Context(), database.UpdateJournalEntryParams{
-		Title:   params.Title,
-		Content: params.Content,
-		ID:      int64(params.ID),
+		Title:          params.Title,
+		Content:        params.Content,
+		SeoTitle:       sql.NullString{String: params.SeoTitle, Valid: params.SeoTitle != ""},
+		SeoDescription: sql.NullString{String: params.SeoDescription, Valid: params.SeoDescription != ""},
+		Format:         params.Format,
+		ContentHtml:    contentHTML,
+		WordCount:      int64(summary.WordCount),
+		ReadingMinutes: int64(summary.ReadingMinutes),
+		Excerpt:        summary.Excerpt,
+		Visibility:     params.Visibility,
+		ShareToken:     sql.NullString{String: shareToken, Valid: shareToken != ""},
+		Encrypted:      encrypted,
+		ID:             int64(params.ID),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to update journal", err)
 		return
 	}
 
+	updated, err := cfg.DB.GetJournalEntry(r.Context(), existing.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get journal", err)
+		return
+	}
+	cfg.journalChanged(r, existing, updated)
+
 	respondWithJson(w, http.StatusOK, map[string]string{
 		"message": "journal updated successfully",
 	})
 }
 
+// rotateShareToken replaces an unlisted entry's share link, so anyone
+// holding the old one loses access.
+func (cfg *apiConfig) rotateShareToken(w http.ResponseWriter, r *http.Request) {
+	journalID, err := strconv.Atoi(r.PathValue("journalID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid journal ID", err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	journal, err := cfg.DB.GetUsersJournal(r.Context(), database.GetUsersJournalParams{
+		ID:     int64(journalID),
+		UserID: int64(userID),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "journal not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get journal", err)
+		return
+	}
+
+	if journal.Visibility != visibilityUnlisted {
+		respondWithError(w, http.StatusBadRequest, "only unlisted entries have a share link", nil)
+		return
+	}
+
+	token := shareTokenFor(visibilityUnlisted, "")
+	err = cfg.DB.SetJournalShareToken(r.Context(), database.SetJournalShareTokenParams{
+		ShareToken: sql.NullString{String: token, Valid: true},
+		ID:         journal.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update share link", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{
+		"share_url": sharePath(token),
+	})
+}
+
 func (cfg *apiConfig) deleteJournalEntry(w http.ResponseWriter, r *http.Request) {
 	journalIDString := r.PathValue("journalID")
 	journalID, err := strconv.Atoi(journalIDString)
@@ -217,7 +464,9 @@ func (cfg *apiConfig) deleteJournalEntry(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.DB.DeleteJournalEntry(r.Context(), database.DeleteJournalEntryParams{
+	// Entries go to the trash; they keep their series place in case
+	// they're restored
+	err = cfg.DB.TrashJournalEntry(r.Context(), database.TrashJournalEntryParams{
 		ID:     int64(journalID),
 		UserID: int64(userID),
 	})
@@ -225,6 +474,7 @@ func (cfg *apiConfig) deleteJournalEntry(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "failed to delete journal", err)
 		return
 	}
+	cfg.journalChanged(r, journal, database.JournalEntry{})
 
 	w.WriteHeader(http.StatusNoContent)
 }
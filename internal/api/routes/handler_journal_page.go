@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/render"
+)
+
+// renderJournalPage renders view-journal.html for an entry the caller has
+// already checked the visitor may see. form carries a rejected comment
+// back to the reader; pass the zero value otherwise.
+func (cfg *apiConfig) renderJournalPage(w http.ResponseWriter, r *http.Request, journal database.JournalEntry, form commentForm) {
+	data := cfg.baseTemplateData(r.Context(), "Journal Entry", "journals")
+	cfg.ensureRendered(r.Context(), &journal)
+
+	data["Journal"] = journal
+	data["TOC"] = render.TableOfContents(journal.ContentHtml)
+
+	meta := cfg.journalMeta(r, data, journal)
+
+	if journal.Visibility == visibilityPublic {
+		nextAndPrev, err := cfg.DB.GetPrevAndNextJournalIDs(r.Context(), journal.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch navigation data", http.StatusInternalServerError)
+			return
+		}
+		data["NextJournalID"] = nextAndPrev.NextID
+		data["PrevJournalID"] = nextAndPrev.PreviousID
+
+		// Entries in a series step through the series instead
+		series, err := cfg.DB.GetJournalSeriesNav(r.Context(), journal.ID)
+		switch {
+		case err == nil:
+			data["Series"] = series
+			data["NextJournalID"] = series.NextID
+			data["PrevJournalID"] = series.PreviousID
+		case !errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Failed to fetch navigation data", http.StatusInternalServerError)
+			return
+		}
+
+		// Only public entries take comments
+		comments, err := cfg.DB.ListApprovedComments(r.Context(), journal.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+			return
+		}
+		if form.Started == "" {
+			form.Started = cfg.commentFormToken(journal.ID, time.Now())
+		}
+		form.Submitted = r.URL.Query().Get("comment") == commentPending
+		data["Comments"] = threadComments(comments, data["Location"].(*time.Location))
+		data["CommentCount"] = len(comments)
+		data["CommentForm"] = form
+
+		mentions, err := cfg.DB.ListVerifiedWebmentions(r.Context(), journal.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch mentions", http.StatusInternalServerError)
+			return
+		}
+		endpoint := cfg.absoluteURL(r, "/webmention")
+		w.Header().Add("Link", "<"+endpoint+`>; rel="webmention"`)
+		data["Mentions"] = mentionViews(mentions, data["Location"].(*time.Location))
+		data["WebmentionEndpoint"] = endpoint
+	} else {
+		// Hidden entries stand alone: no links into the public list, no
+		// share card and nothing for search engines
+		meta.Image = cfg.absoluteURL(r, data["Settings"].(SiteSettings).DefaultOGImage)
+		meta.ImageWidth, meta.ImageHeight = 0, 0
+		meta.JSONLD = nil
+		meta.NoIndex = true
+	}
+	data["SEO"] = meta
+
+	if form.Error != "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := cfg.templates.ExecuteTemplate(w, "view-journal.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleSharedJournal serves an unlisted entry to anyone holding its link.
+func (cfg *apiConfig) handleSharedJournal(w http.ResponseWriter, r *http.Request) {
+	journal, err := cfg.DB.GetJournalByShareToken(r.Context(), sql.NullString{String: r.PathValue("token"), Valid: true})
+	if err != nil {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	cfg.renderJournalPage(w, r, journal, commentForm{})
+}
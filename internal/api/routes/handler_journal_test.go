@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetJournalEntriesFields(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "fieldsuser", "password")
+	payload, _ := json.Marshal(map[string]string{
+		"title":   "Counting",
+		"content": "## One two\n\nthree four five",
+		"format":  "markdown",
+	})
+	req := httptest.NewRequest("POST", "/api/journals", bytes.NewBuffer(payload))
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+	rr := httptest.NewRecorder()
+	apiCfg.postJournalEntry(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to create journal: %d %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []string
+		absentKeys []string
+	}{
+		{
+			name:       "all fields",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"content", "word_count", "reading_minutes", "excerpt"},
+		},
+		{
+			name:       "subset",
+			query:      "?fields=id,title,word_count,reading_minutes,excerpt",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"id", "title", "word_count", "excerpt"},
+			absentKeys: []string{"content", "created_at"},
+		},
+		{
+			name:       "unknown field",
+			query:      "?fields=id,body",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/journals"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			apiCfg.getJournalEntries(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Journals []map[string]interface{} `json:"journals"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Journals) != 1 {
+				t.Fatalf("Expected 1 journal, got %d", len(resp.Journals))
+			}
+
+			journal := resp.Journals[0]
+			for _, key := range tt.wantKeys {
+				if _, ok := journal[key]; !ok {
+					t.Errorf("Expected key %q in %v", key, journal)
+				}
+			}
+			for _, key := range tt.absentKeys {
+				if _, ok := journal[key]; ok {
+					t.Errorf("Did not expect key %q in %v", key, journal)
+				}
+			}
+			if journal["word_count"] != float64(5) || journal["reading_minutes"] != float64(1) {
+				t.Errorf("Unexpected stats: %v", journal)
+			}
+		})
+	}
+}
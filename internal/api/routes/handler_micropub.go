@@ -0,0 +1,659 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/auth"
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/micropub"
+	"github.com/sianwa11/my-journal/internal/render"
+	"github.com/sianwa11/my-journal/internal/seo"
+)
+
+const (
+	// micropubTokenTTL is how long a token minted for a Micropub client
+	// lasts. Changing SECRET revokes every token early.
+	micropubTokenTTL = 90 * 24 * time.Hour
+
+	maxMicropubBytes       = 1 << 20
+	maxMediaBytes          = 10 << 20
+	maxMicropubUploadBytes = 4 * maxMediaBytes
+
+	// micropubTitleLength is the longest title made from the content of
+	// a post that has no name, in runes.
+	micropubTitleLength = 60
+
+	// mediaUploadsDir is where the media endpoint keeps files, under
+	// MEDIA_DIR. They are served from /media/uploads/.
+	mediaUploadsDir = "uploads"
+)
+
+// mediaExtensions are the file types the media endpoint accepts, by
+// sniffed content type.
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var errUnsupportedMedia = errors.New("only JPEG, PNG, GIF and WebP images can be uploaded")
+
+// micropubError writes an error in the shape the Micropub spec defines.
+func micropubError(w http.ResponseWriter, status int, code, description string) {
+	respondWithJson(w, status, struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}{code, description})
+}
+
+func invalidMicropubRequest(w http.ResponseWriter, err error) {
+	description := strings.TrimPrefix(err.Error(), micropub.ErrInvalidRequest.Error()+": ")
+	micropubError(w, http.StatusBadRequest, "invalid_request", description)
+}
+
+// micropubUser authenticates a Micropub request with a JWT, sent either
+// as a bearer token or in an access_token form field but not both. Forms
+// must already be parsed. It writes the error response itself.
+func (cfg *apiConfig) micropubUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header, _ := auth.GetBearerToken(r.Header)
+	field := r.PostForm.Get("access_token")
+
+	token := header
+	switch {
+	case header != "" && field != "":
+		micropubError(w, http.StatusBadRequest, "invalid_request", "send the access token in the header or the body, not both")
+		return 0, false
+	case header == "" && field == "":
+		micropubError(w, http.StatusUnauthorized, "unauthorized", "missing access token")
+		return 0, false
+	case header == "":
+		token = field
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		micropubError(w, http.StatusForbidden, "forbidden", "invalid or expired access token")
+		return 0, false
+	}
+	return userID, true
+}
+
+// createMicropubToken mints a long-lived token to paste into a Micropub
+// client.
+func (cfg *apiConfig) createMicropubToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(userIDKey).(int)
+
+	token, err := auth.MakeJWT(userID, cfg.jwtSecret, micropubTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to create token", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusCreated, map[string]any{
+		"access_token": token,
+		"expires_at":   time.Now().Add(micropubTokenTTL).UTC(),
+		"endpoint":     cfg.absoluteURL(r, "/micropub"),
+	})
+}
+
+// handleMicropubQuery answers GET /micropub: q=config, q=source and
+// q=syndicate-to.
+func (cfg *apiConfig) handleMicropubQuery(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.micropubUser(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.URL.Query().Get("q") {
+	case "config":
+		respondWithJson(w, http.StatusOK, map[string]any{
+			"media-endpoint": cfg.absoluteURL(r, "/micropub/media"),
+			"syndicate-to":   []any{},
+			"q":              []string{"config", "source", "syndicate-to"},
+		})
+	case "syndicate-to":
+		respondWithJson(w, http.StatusOK, map[string]any{"syndicate-to": []any{}})
+	case "source":
+		cfg.micropubSource(w, r, userID)
+	default:
+		micropubError(w, http.StatusBadRequest, "invalid_request", "q must be config, source or syndicate-to")
+	}
+}
+
+func (cfg *apiConfig) micropubSource(w http.ResponseWriter, r *http.Request, userID int) {
+	query := r.URL.Query()
+	journal, ok := cfg.micropubJournal(w, r, userID, query.Get("url"))
+	if !ok {
+		return
+	}
+	if journal.Encrypted {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "encrypted entries can't be read over Micropub")
+		return
+	}
+
+	tags, err := cfg.DB.ListJournalTagNames(r.Context(), journal.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get tags", err)
+		return
+	}
+	props := cfg.micropubProperties(r, journal, tags)
+
+	names := append(query["properties[]"], query["properties"]...)
+	if len(names) > 0 {
+		respondWithJson(w, http.StatusOK, map[string]any{"properties": props.Filter(names)})
+		return
+	}
+	respondWithJson(w, http.StatusOK, map[string]any{
+		"type":       []string{"h-entry"},
+		"properties": props,
+	})
+}
+
+// micropubJournal finds the caller's entry at rawURL, writing an error if
+// there is none.
+func (cfg *apiConfig) micropubJournal(w http.ResponseWriter, r *http.Request, userID int, rawURL string) (database.JournalEntry, bool) {
+	id, ok := cfg.micropubJournalID(w, r, rawURL)
+	if !ok {
+		return database.JournalEntry{}, false
+	}
+
+	journal, err := cfg.DB.GetUsersJournal(r.Context(), database.GetUsersJournalParams{
+		ID:     id,
+		UserID: int64(userID),
+	})
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "no journal entry at that url")
+		return database.JournalEntry{}, false
+	}
+	return journal, true
+}
+
+func (cfg *apiConfig) micropubJournalID(w http.ResponseWriter, r *http.Request, rawURL string) (int64, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "a journal entry url is required")
+		return 0, false
+	}
+	id, ok := cfg.journalIDFromURL(r, u)
+	if !ok {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "no journal entry at that url")
+		return 0, false
+	}
+	return id, true
+}
+
+// micropubProperties describes a journal entry as h-entry properties.
+// Markdown content is plain text; HTML is sent as {"html": ...}.
+func (cfg *apiConfig) micropubProperties(r *http.Request, journal database.JournalEntry, tags []string) micropub.Properties {
+	props := micropub.Properties{
+		"name":       {journal.Title},
+		"published":  {journal.CreatedAt.Time.UTC().Format(time.RFC3339)},
+		"url":        {cfg.absoluteURL(r, fmt.Sprintf("/journals/%d", journal.ID))},
+		"visibility": {journal.Visibility},
+	}
+
+	if journal.Format == render.FormatMarkdown {
+		props["content"] = []any{journal.Content}
+	} else {
+		props["content"] = []any{map[string]any{"html": journal.Content}}
+	}
+	if journal.SeoDescription.Valid {
+		props["summary"] = []any{journal.SeoDescription.String}
+	}
+	for _, tag := range tags {
+		props["category"] = append(props["category"], tag)
+	}
+	return props
+}
+
+// micropubEntry is what a set of h-entry properties means for a journal
+// entry. Properties without a journal equivalent are ignored.
+type micropubEntry struct {
+	Title      string
+	Content    string
+	Format     string
+	Summary    string
+	Visibility string
+	Tags       []string
+
+	ContentHTML string
+	Stats       render.Summary
+}
+
+// micropubEntryFrom maps h-entry properties onto a journal entry and
+// renders its content. Photos are appended to the content; a post without
+// a name is titled from the start of its text.
+func micropubEntryFrom(props micropub.Properties) (micropubEntry, error) {
+	content, _ := props.Content()
+	entry := micropubEntry{
+		Title:   strings.TrimSpace(props.String("name")),
+		Content: content.Text,
+		Format:  render.FormatMarkdown,
+		Summary: strings.TrimSpace(props.String("summary")),
+	}
+	if content.HTML != "" {
+		entry.Content = content.HTML
+		entry.Format = render.FormatHTML
+	}
+
+	for _, photo := range props.Photos() {
+		u, err := url.Parse(photo.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return micropubEntry{}, fmt.Errorf("photo %q must be an http or https URL", photo.URL)
+		}
+		if entry.Format == render.FormatHTML {
+			entry.Content += fmt.Sprintf("\n<p><img src=\"%s\" alt=\"%s\"></p>", html.EscapeString(photo.URL), html.EscapeString(photo.Alt))
+		} else {
+			alt := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(photo.Alt)
+			entry.Content += fmt.Sprintf("\n\n![%s](<%s>)", alt, photo.URL)
+		}
+	}
+	entry.Content = strings.TrimSpace(entry.Content)
+	if entry.Content == "" {
+		return micropubEntry{}, errors.New("content or a photo is required")
+	}
+
+	entry.Visibility = props.String("visibility")
+	if entry.Visibility == "" {
+		entry.Visibility = visibilityPublic
+		if props.String("post-status") == "draft" {
+			entry.Visibility = visibilityPrivate
+		}
+	}
+	if !validVisibility(entry.Visibility) {
+		return micropubEntry{}, errors.New("visibility must be public, unlisted or private")
+	}
+
+	seen := map[string]bool{}
+	for _, tag := range props.Strings("category") {
+		// Person tags are URLs, not topics
+		tag = strings.TrimSpace(tag)
+		if tag == "" || strings.Contains(tag, "://") || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		entry.Tags = append(entry.Tags, tag)
+	}
+
+	var err error
+	entry.ContentHTML, err = render.HTML(entry.Format, entry.Content)
+	if err != nil {
+		return micropubEntry{}, errors.New("could not render content")
+	}
+	entry.Stats = render.Summarize(entry.ContentHTML)
+
+	if entry.Title == "" {
+		entry.Title = seo.Excerpt(entry.ContentHTML, micropubTitleLength)
+	}
+	if entry.Title == "" {
+		entry.Title = "Untitled"
+	}
+	return entry, nil
+}
+
+// setJournalTags replaces an entry's tags, creating any that are new.
+func setJournalTags(ctx context.Context, qtx *database.Queries, journalID int64, tags []string) error {
+	if err := qtx.DeleteJournalTags(ctx, journalID); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		tag, err := qtx.UpsertTag(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = qtx.CreateJournalTagIfNotExists(ctx, database.CreateJournalTagIfNotExistsParams{
+			JournalID: journalID,
+			TagID:     tag.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleMicropub is the Micropub endpoint. It takes form-encoded,
+// multipart and JSON requests.
+func (cfg *apiConfig) handleMicropub(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var err error
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		r.Body = http.MaxBytesReader(w, r.Body, maxMicropubBytes)
+		err = r.ParseForm()
+	case "multipart/form-data":
+		r.Body = http.MaxBytesReader(w, r.Body, maxMicropubUploadBytes)
+		err = r.ParseMultipartForm(maxMediaBytes)
+		if err == nil {
+			defer r.MultipartForm.RemoveAll()
+		}
+	case "application/json":
+		r.Body = http.MaxBytesReader(w, r.Body, maxMicropubBytes)
+	default:
+		micropubError(w, http.StatusUnsupportedMediaType, "invalid_request", "send form-encoded, multipart or JSON requests")
+		return
+	}
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "could not read the request body")
+		return
+	}
+
+	userID, ok := cfg.micropubUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req micropub.Request
+	if mediaType == "application/json" {
+		req, err = micropub.ParseJSON(r.Body)
+	} else {
+		req, err = micropub.ParseForm(r.PostForm)
+	}
+	if err != nil {
+		invalidMicropubRequest(w, err)
+		return
+	}
+
+	switch req.Action {
+	case micropub.ActionCreate:
+		cfg.micropubCreate(w, r, userID, req)
+	case micropub.ActionUpdate:
+		cfg.micropubUpdate(w, r, userID, req)
+	case micropub.ActionDelete:
+		cfg.micropubDelete(w, r, userID, req)
+	case micropub.ActionUndelete:
+		cfg.micropubUndelete(w, r, req)
+	}
+}
+
+func (cfg *apiConfig) micropubCreate(w http.ResponseWriter, r *http.Request, userID int, req micropub.Request) {
+	if req.Type != "h-entry" {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "only h-entry posts are supported")
+		return
+	}
+
+	// Photos uploaded with the post go through the media endpoint's storage
+	if r.MultipartForm != nil {
+		files := append(r.MultipartForm.File["photo"], r.MultipartForm.File["photo[]"]...)
+		for _, file := range files {
+			location, err := cfg.saveMedia(r, file)
+			if err != nil {
+				micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+				return
+			}
+			req.Properties["photo"] = append(req.Properties["photo"], location)
+		}
+	}
+
+	entry, err := micropubEntryFrom(req.Properties)
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	shareToken := shareTokenFor(entry.Visibility, "")
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	journal, err := qtx.CreateJournalEntry(r.Context(), database.CreateJournalEntryParams{
+		Title:          entry.Title,
+		Content:        entry.Content,
+		UserID:         int64(userID),
+		SeoDescription: sql.NullString{String: entry.Summary, Valid: entry.Summary != ""},
+		Format:         entry.Format,
+		ContentHtml:    entry.ContentHTML,
+		WordCount:      int64(entry.Stats.WordCount),
+		ReadingMinutes: int64(entry.Stats.ReadingMinutes),
+		Excerpt:        entry.Stats.Excerpt,
+		Visibility:     entry.Visibility,
+		ShareToken:     sql.NullString{String: shareToken, Valid: shareToken != ""},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to create journal entry", err)
+		return
+	}
+	if err := setJournalTags(r.Context(), qtx, journal.ID, entry.Tags); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save tags", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	cfg.journalChanged(r, database.JournalEntry{}, journal)
+
+	w.Header().Set("Location", cfg.absoluteURL(r, fmt.Sprintf("/journals/%d", journal.ID)))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (cfg *apiConfig) micropubUpdate(w http.ResponseWriter, r *http.Request, userID int, req micropub.Request) {
+	journal, ok := cfg.micropubJournal(w, r, userID, req.URL)
+	if !ok {
+		return
+	}
+	if journal.Encrypted {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "encrypted entries can't be edited over Micropub")
+		return
+	}
+
+	tags, err := cfg.DB.ListJournalTagNames(r.Context(), journal.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get tags", err)
+		return
+	}
+
+	props := cfg.micropubProperties(r, journal, tags)
+	req.Apply(props)
+
+	entry, err := micropubEntryFrom(props)
+	if err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	shareToken := shareTokenFor(entry.Visibility, journal.ShareToken.String)
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.UpdateJournalEntry(r.Context(), database.UpdateJournalEntryParams{
+		Title:          entry.Title,
+		Content:        entry.Content,
+		SeoTitle:       journal.SeoTitle,
+		SeoDescription: sql.NullString{String: entry.Summary, Valid: entry.Summary != ""},
+		Format:         entry.Format,
+		ContentHtml:    entry.ContentHTML,
+		WordCount:      int64(entry.Stats.WordCount),
+		ReadingMinutes: int64(entry.Stats.ReadingMinutes),
+		Excerpt:        entry.Stats.Excerpt,
+		Visibility:     entry.Visibility,
+		ShareToken:     sql.NullString{String: shareToken, Valid: shareToken != ""},
+		ID:             journal.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update journal", err)
+		return
+	}
+	if err := setJournalTags(r.Context(), qtx, journal.ID, entry.Tags); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save tags", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	updated, err := cfg.DB.GetJournalEntry(r.Context(), journal.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get journal", err)
+		return
+	}
+	cfg.journalChanged(r, journal, updated)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// micropubDelete moves an entry to the trash, where undelete can find it.
+func (cfg *apiConfig) micropubDelete(w http.ResponseWriter, r *http.Request, userID int, req micropub.Request) {
+	journal, ok := cfg.micropubJournal(w, r, userID, req.URL)
+	if !ok {
+		return
+	}
+
+	err := cfg.DB.TrashJournalEntry(r.Context(), database.TrashJournalEntryParams{
+		ID:     journal.ID,
+		UserID: int64(userID),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete journal", err)
+		return
+	}
+	cfg.journalChanged(r, journal, database.JournalEntry{})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// micropubUndelete restores an entry from the trash.
+func (cfg *apiConfig) micropubUndelete(w http.ResponseWriter, r *http.Request, req micropub.Request) {
+	id, ok := cfg.micropubJournalID(w, r, req.URL)
+	if !ok {
+		return
+	}
+
+	restored, err := cfg.DB.RestoreJournalEntry(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to restore journal", err)
+		return
+	}
+	if restored == 0 {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "no deleted journal entry at that url")
+		return
+	}
+	if journal, err := cfg.DB.GetJournalEntry(r.Context(), id); err == nil {
+		cfg.journalChanged(r, database.JournalEntry{}, journal)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMicropubMedia is the Micropub media endpoint: it stores one
+// uploaded image and answers with its URL.
+func (cfg *apiConfig) handleMicropubMedia(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaBytes+maxMicropubBytes)
+	if err := r.ParseMultipartForm(maxMediaBytes); err != nil {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "send a multipart upload with a file field")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if _, ok := cfg.micropubUser(w, r); !ok {
+		return
+	}
+
+	files := r.MultipartForm.File["file"]
+	if len(files) != 1 {
+		micropubError(w, http.StatusBadRequest, "invalid_request", "send exactly one file in the file field")
+		return
+	}
+
+	location, err := cfg.saveMedia(r, files[0])
+	if errors.Is(err, errUnsupportedMedia) {
+		micropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to save upload", err)
+		return
+	}
+
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// saveMedia stores an uploaded image under a random name and returns its
+// public URL. The type is sniffed from the content, not trusted from the
+// client.
+func (cfg *apiConfig) saveMedia(r *http.Request, file *multipart.FileHeader) (string, error) {
+	if file.Size > maxMediaBytes {
+		return "", fmt.Errorf("uploads can be at most %d MB", maxMediaBytes>>20)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", errUnsupportedMedia
+	}
+	head = head[:n]
+
+	ext, ok := mediaExtensions[http.DetectContentType(head)]
+	if !ok {
+		return "", errUnsupportedMedia
+	}
+
+	dir := filepath.Join(cfg.mediaDir, mediaUploadsDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+
+	name := strings.ToLower(rand.Text()) + ext
+	dst, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, io.MultiReader(bytes.NewReader(head), src)); err != nil {
+		dst.Close()
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		return "", err
+	}
+
+	return cfg.absoluteURL(r, "/media/"+mediaUploadsDir+"/"+name), nil
+}
+
+// serveMediaUpload serves a file saved by the media endpoint. Names are
+// random, so the files never change and can be cached for good.
+func (cfg *apiConfig) serveMediaUpload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, filepath.Join(cfg.mediaDir, mediaUploadsDir, name))
+}
@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/auth"
+	"github.com/sianwa11/my-journal/internal/render"
+)
+
+func TestMicropubAuth(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	token, err := auth.MakeJWT(1, apiCfg.jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		field      string
+		wantStatus int
+		wantError  string
+	}{
+		{"no token", "", "", http.StatusUnauthorized, "unauthorized"},
+		{"bad token", "Bearer nonsense", "", http.StatusForbidden, "forbidden"},
+		{"header and field", "Bearer " + token, token, http.StatusBadRequest, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"h": {"entry"}, "content": {"hi"}}
+			if tt.field != "" {
+				form.Set("access_token", tt.field)
+			}
+			req := httptest.NewRequest("POST", "/micropub", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			apiCfg.handleMicropub(rr, req)
+
+			var body struct{ Error string }
+			json.NewDecoder(rr.Body).Decode(&body)
+			if rr.Code != tt.wantStatus || body.Error != tt.wantError {
+				t.Errorf("Expected %d %s, got %d %s", tt.wantStatus, tt.wantError, rr.Code, body.Error)
+			}
+		})
+	}
+}
+
+func TestMicropubPosts(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.baseURL = "https://me.example"
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "micropub", "password")
+	token, err := auth.MakeJWT(int(user.ID), apiCfg.jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forms can carry the token in the body
+	form := url.Values{
+		"h":            {"entry"},
+		"content":      {"Posted from my **phone** today"},
+		"category[]":   {"indieweb", "go"},
+		"access_token": {token},
+	}
+	req := httptest.NewRequest("POST", "/micropub", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	apiCfg.handleMicropub(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected create to return 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	location := rr.Header().Get("Location")
+	id, err := strconv.ParseInt(strings.TrimPrefix(location, "https://me.example/journals/"), 10, 64)
+	if err != nil {
+		t.Fatalf("Unexpected Location %q", location)
+	}
+
+	journal, err := apiCfg.DB.GetJournalEntry(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Title != "Posted from my phone today" || journal.Format != render.FormatMarkdown || journal.Visibility != visibilityPublic {
+		t.Errorf("Unexpected entry %+v", journal)
+	}
+	if !strings.Contains(journal.ContentHtml, "<strong>phone</strong>") {
+		t.Errorf("Expected rendered markdown, got %q", journal.ContentHtml)
+	}
+
+	call := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rr := httptest.NewRecorder()
+		if method == "GET" {
+			apiCfg.handleMicropubQuery(rr, req)
+		} else {
+			apiCfg.handleMicropub(rr, req)
+		}
+		return rr
+	}
+	source := func(props ...string) map[string][]any {
+		q := url.Values{"q": {"source"}, "url": {location}, "properties[]": props}
+		rr := call("GET", "/micropub?"+q.Encode(), "", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected q=source to return 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body struct{ Properties map[string][]any }
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Properties
+	}
+
+	if got := source("category"); !reflect.DeepEqual(got, map[string][]any{"category": {"go", "indieweb"}}) {
+		t.Errorf("Unexpected categories from q=source: %v", got)
+	}
+
+	update := `{"action": "update", "url": "` + location + `",
+		"replace": {"name": ["Named now"], "content": [{"html": "<p>Rewritten</p>"}]},
+		"add": {"category": ["phone"]},
+		"delete": {"category": ["go"]}}`
+	if rr := call("POST", "/micropub", "application/json", update); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected update to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	props := source()
+	if props["name"][0] != "Named now" || !reflect.DeepEqual(props["category"], []any{"indieweb", "phone"}) {
+		t.Errorf("Unexpected properties after update: %v", props)
+	}
+	if content, _ := props["content"][0].(map[string]any); content["html"] != "<p>Rewritten</p>" {
+		t.Errorf("Expected HTML content after update, got %v", props["content"])
+	}
+
+	if rr := call("POST", "/micropub", "application/json", `{"action": "delete", "url": "`+location+`"}`); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected delete to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := apiCfg.DB.GetJournalEntry(ctx, id); err == nil {
+		t.Error("Expected the entry to be in the trash")
+	}
+
+	undelete := url.Values{"action": {"undelete"}, "url": {location}}.Encode()
+	if rr := call("POST", "/micropub", "application/x-www-form-urlencoded", undelete); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected undelete to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := apiCfg.DB.GetJournalEntry(ctx, id); err != nil {
+		t.Errorf("Expected the entry to be restored: %v", err)
+	}
+
+	rr = call("GET", "/micropub?q=config", "", "")
+	var config map[string]any
+	json.NewDecoder(rr.Body).Decode(&config)
+	if config["media-endpoint"] != "https://me.example/micropub/media" {
+		t.Errorf("Unexpected config %v", config)
+	}
+
+	invalid := []string{
+		`{"type": ["h-event"], "properties": {"name": ["Party"]}}`,
+		`{"type": ["h-entry"], "properties": {"name": ["No content"]}}`,
+		`{"type": ["h-entry"], "properties": {"content": ["x"], "visibility": ["secret"]}}`,
+		`{"action": "update", "url": "https://else.example/journals/1", "replace": {"name": ["x"]}}`,
+	}
+	for _, body := range invalid {
+		if rr := call("POST", "/micropub", "application/json", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected %s to return 400, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestMicropubMedia(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.baseURL = "https://me.example"
+	apiCfg.mediaDir = t.TempDir()
+
+	token, err := auth.MakeJWT(1, apiCfg.jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(filename string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", filename)
+		part.Write(content)
+		mw.Close()
+
+		req := httptest.NewRequest("POST", "/micropub/media", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		apiCfg.handleMicropubMedia(rr, req)
+		return rr
+	}
+
+	rr := upload("dot.png", pngData.Bytes())
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected upload to return 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	location := rr.Header().Get("Location")
+	name, ok := strings.CutPrefix(location, "https://me.example/media/uploads/")
+	if !ok || !strings.HasSuffix(name, ".png") {
+		t.Fatalf("Unexpected Location %q", location)
+	}
+
+	req := httptest.NewRequest("GET", "/media/uploads/"+name, nil)
+	req.SetPathValue("file", name)
+	rr = httptest.NewRecorder()
+	apiCfg.serveMediaUpload(rr, req)
+	if got, _ := io.ReadAll(rr.Body); rr.Code != http.StatusOK || !bytes.Equal(got, pngData.Bytes()) {
+		t.Errorf("Expected the upload to be served back, got %d", rr.Code)
+	}
+
+	// The extension is ignored; the content decides
+	if rr := upload("evil.png", []byte("<script>alert(1)</script>")); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a non-image upload to return 400, got %d", rr.Code)
+	}
+}
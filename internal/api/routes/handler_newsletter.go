@@ -0,0 +1,687 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/mailer"
+	"github.com/sianwa11/my-journal/internal/seo"
+)
+
+// Subscriber states. Addresses stay pending until the reader follows the
+// link in the confirmation email; only confirmed ones get digests.
+const (
+	subscriberPending      = "pending"
+	subscriberConfirmed    = "confirmed"
+	subscriberUnsubscribed = "unsubscribed"
+)
+
+// Digest states.
+const (
+	newsletterSending = "sending"
+	newsletterSent    = "sent"
+)
+
+const (
+	// confirmLinkLifetime is how long the link in a confirmation email
+	// works.
+	confirmLinkLifetime = 7 * 24 * time.Hour
+
+	// confirmResendInterval stops the subscribe form being used to flood
+	// someone's inbox: a pending address is sent at most one confirmation
+	// email per interval.
+	confirmResendInterval = 10 * time.Minute
+
+	// firstDigestWindow is how far back the first digest looks. Later ones
+	// pick up where the previous one ended.
+	firstDigestWindow = 7 * 24 * time.Hour
+
+	// digestBatchSize is how many subscribers are read at a time while
+	// sending a digest.
+	digestBatchSize = 50
+
+	maxEmailLength         = 254
+	maxSubscribeFormBytes  = 16 << 10
+	maxNewsletterSubject   = 200
+	unsubscribeOneClickArg = "List-Unsubscribe=One-Click"
+)
+
+var subscriberStatuses = []string{subscriberPending, subscriberConfirmed, subscriberUnsubscribed}
+
+// mailerFromEnv returns an SMTP mailer when SMTP_HOST is set, and one that
+// only logs messages otherwise.
+func mailerFromEnv() mailer.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return mailer.Log{}
+	}
+
+	port := 0
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 65535 {
+			slog.Warn("invalid SMTP_PORT, using default", "value", v)
+		} else {
+			port = n
+		}
+	}
+
+	smtp, err := mailer.NewSMTP(mailer.SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	})
+	if err != nil {
+		slog.Warn("invalid mail settings, emails will only be logged", "error", err)
+		return mailer.Log{}
+	}
+	return smtp
+}
+
+// errNoBaseURL is returned by the newsletter jobs when BASE_URL isn't set.
+// Links in emails are only ever built from BASE_URL: taking them from the
+// request would let anyone with a forged Host header have confirmation
+// emails sent with links to their own site.
+var errNoBaseURL = errors.New("BASE_URL must be set to send newsletter emails")
+
+// subscriberJob asks for a confirmation email.
+type subscriberJob struct {
+	SubscriberID int64 `json:"subscriber_id"`
+}
+
+type digestJob struct {
+	SendID int64 `json:"send_id"`
+}
+
+// subscribePage is the state of /newsletter and the pages the links in
+// newsletter emails lead to.
+type subscribePage struct {
+	Email string
+	Error string
+
+	// State is "" for the form, or one of subscribed, confirmed, invalid,
+	// unsubscribe and unsubscribed.
+	State string
+	Token string
+}
+
+func validSubscriberEmail(email string) bool {
+	if email == "" || len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+func (cfg *apiConfig) renderSubscribePage(w http.ResponseWriter, r *http.Request, status int, page subscribePage) {
+	data := cfg.baseTemplateData(r.Context(), "Newsletter", "newsletter")
+	settings := data["Settings"].(SiteSettings)
+	data["Newsletter"] = page
+	data["SEO"] = cfg.pageMeta(r, settings, "Newsletter",
+		"Get new journal entries from "+settings.SiteTitle+" by email.")
+
+	// Token-bearing pages shouldn't be indexed or leak through referrers
+	if page.Token != "" {
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+	w.WriteHeader(status)
+	if err := cfg.templates.ExecuteTemplate(w, "subscribe.html", data); err != nil {
+		requestLogger(w).Error("failed to render newsletter page", "error", err)
+	}
+}
+
+// handleSubscribePage shows the subscribe form, or thanks the reader once
+// they have used it.
+func (cfg *apiConfig) handleSubscribePage(w http.ResponseWriter, r *http.Request) {
+	page := subscribePage{}
+	if r.URL.Query().Get("subscribed") != "" {
+		page.State = "subscribed"
+	}
+	cfg.renderSubscribePage(w, r, http.StatusOK, page)
+}
+
+// handleSubscribe takes an address from the subscribe form and emails it a
+// confirmation link. Every accepted address gets the same response, so the
+// form can't be used to find out who is subscribed.
+func (cfg *apiConfig) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubscribeFormBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid subscribe form", http.StatusBadRequest)
+		return
+	}
+
+	doneURL := "/newsletter?subscribed=1"
+
+	if r.PostFormValue(commentHoneypotField) != "" {
+		requestLogger(w).Info("dropped subscription caught by honeypot")
+		http.Redirect(w, r, doneURL, http.StatusSeeOther)
+		return
+	}
+
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	if cfg.baseURL == "" {
+		requestLogger(w).Error("refusing to subscribe", "error", errNoBaseURL)
+		cfg.renderSubscribePage(w, r, http.StatusServiceUnavailable, subscribePage{
+			Email: email,
+			Error: "Subscriptions aren't available right now. Please try again later.",
+		})
+		return
+	}
+	if !validSubscriberEmail(email) {
+		cfg.renderSubscribePage(w, r, http.StatusBadRequest, subscribePage{
+			Email: email,
+			Error: "Please enter a valid email address.",
+		})
+		return
+	}
+
+	subscriber, send, err := cfg.subscribe(r.Context(), email)
+	if err != nil {
+		requestLogger(w).Error("failed to save subscriber", "error", err)
+		http.Error(w, "Failed to subscribe", http.StatusInternalServerError)
+		return
+	}
+	if send {
+		_, err := cfg.jobKinds.confirmSubscriber.Enqueue(r.Context(), subscriberJob{SubscriberID: subscriber.ID})
+		if err != nil {
+			requestLogger(w).Error("failed to queue confirmation email", "subscriber_id", subscriber.ID, "error", err)
+			http.Error(w, "Failed to subscribe", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.Redirect(w, r, doneURL, http.StatusSeeOther)
+}
+
+// subscribe adds email as a pending subscriber, or starts an unsubscribed
+// or unconfirmed one over with a new confirmation link. It reports whether
+// a confirmation email should be sent.
+func (cfg *apiConfig) subscribe(ctx context.Context, email string) (database.Subscriber, bool, error) {
+	subscriber, err := cfg.DB.GetSubscriberByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		subscriber, err = cfg.DB.CreateSubscriber(ctx, database.CreateSubscriberParams{
+			Email:            email,
+			ConfirmToken:     rand.Text(),
+			UnsubscribeToken: rand.Text(),
+		})
+		return subscriber, err == nil, err
+	}
+	if err != nil {
+		return subscriber, false, err
+	}
+
+	switch {
+	case subscriber.Status == subscriberConfirmed:
+		return subscriber, false, nil
+	case subscriber.Status == subscriberPending && subscriber.UpdatedAt.Valid &&
+		time.Since(subscriber.UpdatedAt.Time) < confirmResendInterval:
+		return subscriber, false, nil
+	}
+
+	subscriber, err = cfg.DB.ResubscribeSubscriber(ctx, database.ResubscribeSubscriberParams{
+		ConfirmToken: rand.Text(),
+		ID:           subscriber.ID,
+	})
+	return subscriber, err == nil, err
+}
+
+// handleConfirmSubscription confirms the address a confirmation link was
+// sent to.
+func (cfg *apiConfig) handleConfirmSubscription(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	page := subscribePage{State: "invalid", Token: token}
+	if token == "" {
+		cfg.renderSubscribePage(w, r, http.StatusNotFound, page)
+		return
+	}
+
+	n, err := cfg.DB.ConfirmSubscriber(r.Context(), database.ConfirmSubscriberParams{
+		Token:       token,
+		IssuedAfter: sqliteTimestamp(time.Now().Add(-confirmLinkLifetime)),
+	})
+	if err != nil {
+		requestLogger(w).Error("failed to confirm subscriber", "error", err)
+		http.Error(w, "Failed to confirm subscription", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		cfg.renderSubscribePage(w, r, http.StatusNotFound, page)
+		return
+	}
+
+	page.State = "confirmed"
+	cfg.renderSubscribePage(w, r, http.StatusOK, page)
+}
+
+// handleUnsubscribePage asks the reader to confirm leaving. Unsubscribing
+// takes a POST so link scanners in mail clients can't do it by accident.
+func (cfg *apiConfig) handleUnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	subscriber, err := cfg.DB.GetSubscriberByUnsubscribeToken(r.Context(), token)
+	if token == "" || errors.Is(err, sql.ErrNoRows) {
+		cfg.renderSubscribePage(w, r, http.StatusNotFound, subscribePage{State: "invalid", Token: token})
+		return
+	}
+	if err != nil {
+		requestLogger(w).Error("failed to look up subscriber", "error", err)
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	state := "unsubscribe"
+	if subscriber.Status == subscriberUnsubscribed {
+		state = "unsubscribed"
+	}
+	cfg.renderSubscribePage(w, r, http.StatusOK, subscribePage{
+		Email: subscriber.Email,
+		State: state,
+		Token: token,
+	})
+}
+
+// handleUnsubscribe removes a subscriber, from the button on the
+// unsubscribe page or a mail client's one-click unsubscribe (RFC 8058).
+// Unsubscribing twice is harmless.
+func (cfg *apiConfig) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	subscriber, err := cfg.DB.GetSubscriberByUnsubscribeToken(r.Context(), token)
+	if token == "" || errors.Is(err, sql.ErrNoRows) {
+		cfg.renderSubscribePage(w, r, http.StatusNotFound, subscribePage{State: "invalid", Token: token})
+		return
+	}
+	if err != nil {
+		requestLogger(w).Error("failed to look up subscriber", "error", err)
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cfg.DB.UnsubscribeSubscriber(r.Context(), subscriber.ID); err != nil {
+		requestLogger(w).Error("failed to unsubscribe", "subscriber_id", subscriber.ID, "error", err)
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	cfg.renderSubscribePage(w, r, http.StatusOK, subscribePage{
+		Email: subscriber.Email,
+		State: "unsubscribed",
+		Token: token,
+	})
+}
+
+// runConfirmSubscriber emails a pending subscriber their confirmation
+// link. Anyone who has confirmed or left since doesn't need it.
+func (cfg *apiConfig) runConfirmSubscriber(ctx context.Context, job subscriberJob) error {
+	subscriber, err := cfg.DB.GetSubscriber(ctx, job.SubscriberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if subscriber.Status != subscriberPending {
+		return nil
+	}
+	if cfg.baseURL == "" {
+		return errNoBaseURL
+	}
+
+	title := cfg.siteSettings(ctx).SiteTitle
+	link := cfg.baseURL + "/newsletter/confirm?token=" + url.QueryEscape(subscriber.ConfirmToken)
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      subscriber.Email,
+		Subject: "Confirm your subscription to " + title,
+		Text: fmt.Sprintf("Someone, hopefully you, asked to get new entries from %s by email.\n\n"+
+			"To confirm, open this link within %d days:\n\n%s\n\n"+
+			"If it wasn't you, ignore this email and you won't hear from us again.\n",
+			title, int(confirmLinkLifetime/(24*time.Hour)), link),
+	})
+}
+
+// digestEntry is a journal entry as a digest email lists it.
+type digestEntry struct {
+	Title   string
+	Summary string
+	URL     string
+}
+
+var digestHTML = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #111827; max-width: 600px; margin: 0 auto; padding: 24px;">
+  <h1 style="font-weight: 300;">{{ .Title }}</h1>
+  {{ range .Entries }}
+  <div style="margin-bottom: 24px;">
+    <h2 style="font-weight: 400; margin-bottom: 4px;"><a href="{{ .URL }}" style="color: #111827;">{{ .Title }}</a></h2>
+    {{ with .Summary }}<p style="color: #4b5563; margin-top: 0;">{{ . }}</p>{{ end }}
+  </div>
+  {{ end }}
+  <p style="font-size: 12px; color: #6b7280; border-top: 1px solid #e5e7eb; padding-top: 12px;">
+    You're getting this because you subscribed to {{ .Title }}.
+    <a href="{{ .Unsubscribe }}" style="color: #6b7280;">Unsubscribe</a>
+  </p>
+</body>
+</html>
+`))
+
+// digestMessage builds the digest email for one subscriber.
+func digestMessage(siteTitle, subject string, entries []digestEntry, to, unsubscribeURL string) (mailer.Message, error) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "New on %s:\n\n", siteTitle)
+	for _, e := range entries {
+		fmt.Fprintf(&text, "%s\n%s\n", e.Title, e.URL)
+		if e.Summary != "" {
+			fmt.Fprintf(&text, "%s\n", e.Summary)
+		}
+		text.WriteString("\n")
+	}
+	fmt.Fprintf(&text, "--\nYou're getting this because you subscribed to %s.\nUnsubscribe: %s\n", siteTitle, unsubscribeURL)
+
+	var html strings.Builder
+	err := digestHTML.Execute(&html, map[string]any{
+		"Title":       siteTitle,
+		"Entries":     entries,
+		"Unsubscribe": unsubscribeURL,
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.Message{
+		To:      to,
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": unsubscribeOneClickArg,
+		},
+	}, nil
+}
+
+// runSendDigest emails a digest to every confirmed subscriber. Progress is
+// saved after each one, so if the job is interrupted the retry carries on
+// where it stopped instead of emailing anyone twice. An address the mail
+// server refuses is counted as failed and skipped rather than holding up
+// everyone after it.
+func (cfg *apiConfig) runSendDigest(ctx context.Context, job digestJob) error {
+	send, err := cfg.DB.GetNewsletterSend(ctx, job.SendID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if send.Status == newsletterSent {
+		return nil
+	}
+	if cfg.baseURL == "" {
+		return errNoBaseURL
+	}
+
+	rows, err := cfg.DB.ListJournalsForDigest(ctx, database.ListJournalsForDigestParams{
+		Since: sqliteTimestamp(send.Since),
+		Until: sqliteTimestamp(send.Until),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Entries deleted or hidden since the digest was queued are left out;
+	// if that leaves nothing, there is nothing to send
+	entries := make([]digestEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, digestEntry{
+			Title:   row.Title,
+			Summary: seo.FirstNonEmpty(row.SeoDescription.String, row.Excerpt),
+			URL:     fmt.Sprintf("%s/journals/%d", cfg.baseURL, row.ID),
+		})
+	}
+	siteTitle := cfg.siteSettings(ctx).SiteTitle
+
+	after := send.LastSubscriberID
+	for len(entries) > 0 {
+		subscribers, err := cfg.DB.ListConfirmedSubscribersAfter(ctx, database.ListConfirmedSubscribersAfterParams{
+			AfterID: after,
+			Limit:   digestBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		if len(subscribers) == 0 {
+			break
+		}
+
+		for _, subscriber := range subscribers {
+			unsubscribeURL := cfg.baseURL + "/newsletter/unsubscribe?token=" + url.QueryEscape(subscriber.UnsubscribeToken)
+			msg, err := digestMessage(siteTitle, send.Subject, entries, subscriber.Email, unsubscribeURL)
+			if err != nil {
+				return err
+			}
+
+			delivered, failed := int64(1), int64(0)
+			if err := cfg.mailer.Send(ctx, msg); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				slog.WarnContext(ctx, "failed to send digest", "send_id", send.ID, "subscriber_id", subscriber.ID, "error", err)
+				delivered, failed = 0, 1
+			}
+
+			err = cfg.DB.RecordNewsletterDelivery(ctx, database.RecordNewsletterDeliveryParams{
+				SubscriberID: subscriber.ID,
+				Delivered:    delivered,
+				Failed:       failed,
+				ID:           send.ID,
+			})
+			if err != nil {
+				return err
+			}
+			after = subscriber.ID
+		}
+	}
+
+	slog.InfoContext(ctx, "sent newsletter digest", "send_id", send.ID, "entries", len(entries))
+	return cfg.DB.FinishNewsletterSend(ctx, send.ID)
+}
+
+// NewsletterSend is a digest as the admin API shows it.
+type NewsletterSend struct {
+	ID             int        `json:"id"`
+	Subject        string     `json:"subject"`
+	Since          time.Time  `json:"since"`
+	Until          time.Time  `json:"until"`
+	EntryCount     int        `json:"entry_count"`
+	Status         string     `json:"status"`
+	DeliveredCount int        `json:"delivered_count"`
+	FailedCount    int        `json:"failed_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+type NewsletterResponse struct {
+	Subscribers map[string]int   `json:"subscribers"`
+	Sends       []NewsletterSend `json:"sends"`
+	Total       int              `json:"total"`
+	Page        int              `json:"page"`
+	Limit       int              `json:"limit"`
+	HasMore     bool             `json:"has_more"`
+}
+
+func newsletterSendFromDB(s database.NewsletterSend) NewsletterSend {
+	send := NewsletterSend{
+		ID:             int(s.ID),
+		Subject:        s.Subject,
+		Since:          s.Since,
+		Until:          s.Until,
+		EntryCount:     int(s.EntryCount),
+		Status:         s.Status,
+		DeliveredCount: int(s.DeliveredCount),
+		FailedCount:    int(s.FailedCount),
+		CreatedAt:      s.CreatedAt.Time,
+	}
+	if s.FinishedAt.Valid {
+		send.FinishedAt = &s.FinishedAt.Time
+	}
+	return send
+}
+
+// getNewsletter returns subscriber counts by state and the digests sent so
+// far, newest first.
+func (cfg *apiConfig) getNewsletter(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "invalid limit parameter", err)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset parameter", err)
+			return
+		}
+		offset = n
+	}
+
+	counts, err := cfg.DB.CountSubscribersByStatus(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count subscribers", err)
+		return
+	}
+
+	total, err := cfg.DB.CountNewsletterSends(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count digests", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListNewsletterSends(r.Context(), database.ListNewsletterSendsParams{
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list digests", err)
+		return
+	}
+
+	resp := NewsletterResponse{
+		Subscribers: map[string]int{},
+		Sends:       []NewsletterSend{},
+		Total:       int(total),
+		Page:        offset/limit + 1,
+		Limit:       limit,
+		HasMore:     offset+len(rows) < int(total),
+	}
+	for _, s := range subscriberStatuses {
+		resp.Subscribers[s] = 0
+	}
+	for _, c := range counts {
+		resp.Subscribers[c.Status] = int(c.Count)
+	}
+	for _, row := range rows {
+		resp.Sends = append(resp.Sends, newsletterSendFromDB(row))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// sendNewsletter queues a digest of the public entries written since the
+// last one, or in the past week if there hasn't been one.
+func (cfg *apiConfig) sendNewsletter(w http.ResponseWriter, r *http.Request) {
+	if cfg.baseURL == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "BASE_URL must be set to send newsletters", errNoBaseURL)
+		return
+	}
+
+	var params struct {
+		Subject string `json:"subject"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+			return
+		}
+	}
+	params.Subject = strings.TrimSpace(params.Subject)
+	if len(params.Subject) > maxNewsletterSubject || strings.ContainsAny(params.Subject, "\r\n") {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("subject must be a single line of at most %d characters", maxNewsletterSubject), nil)
+		return
+	}
+
+	now := time.Now()
+	since := now.Add(-firstDigestWindow)
+	last, err := cfg.DB.GetLastNewsletterSend(r.Context())
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "failed to look up the last digest", err)
+		return
+	case last.Status == newsletterSending:
+		respondWithError(w, http.StatusConflict, "a digest is already being sent", nil)
+		return
+	default:
+		since = last.Until
+	}
+
+	entries, err := cfg.DB.ListJournalsForDigest(r.Context(), database.ListJournalsForDigestParams{
+		Since: sqliteTimestamp(since),
+		Until: sqliteTimestamp(now),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list new journal entries", err)
+		return
+	}
+	if len(entries) == 0 {
+		respondWithError(w, http.StatusBadRequest, "no new journal entries since the last digest", nil)
+		return
+	}
+
+	if params.Subject == "" {
+		title := cfg.siteSettings(r.Context()).SiteTitle
+		params.Subject = fmt.Sprintf("New on %s: %s", title, entries[0].Title)
+		if len(entries) > 1 {
+			params.Subject = fmt.Sprintf("%d new entries on %s", len(entries), title)
+		}
+	}
+
+	send, err := cfg.DB.CreateNewsletterSend(r.Context(), database.CreateNewsletterSendParams{
+		Subject:    params.Subject,
+		Since:      sqliteTimestamp(since),
+		Until:      sqliteTimestamp(now),
+		EntryCount: int64(len(entries)),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to create digest", err)
+		return
+	}
+
+	_, err = cfg.jobKinds.sendDigest.Enqueue(r.Context(), digestJob{SendID: send.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to queue digest", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusAccepted, newsletterSendFromDB(send))
+}
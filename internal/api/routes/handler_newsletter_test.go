@@ -0,0 +1,346 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/mailer"
+)
+
+// fakeMailer records messages instead of sending them, and refuses the
+// addresses in reject.
+type fakeMailer struct {
+	mu     sync.Mutex
+	sent   []mailer.Message
+	reject map[string]bool
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reject[msg.To] {
+		return errors.New("550 no such user")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *fakeMailer) take() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := m.sent
+	m.sent = nil
+	return sent
+}
+
+func setupNewsletter(t *testing.T) (*apiConfig, *jobs.Queue, *fakeMailer) {
+	t.Helper()
+
+	apiCfg, db := setupTestAPIConfig(t)
+	t.Cleanup(func() { db.Close() })
+
+	apiCfg.baseURL = "https://journal.example"
+	apiCfg.templates = template.Must(template.New("subscribe.html").Parse(
+		`{{ .Newsletter.State }}|{{ .Newsletter.Error }}|{{ .Newsletter.Email }}`))
+
+	fake := &fakeMailer{}
+	apiCfg.mailer = fake
+	queue := jobs.New(apiCfg.DB, jobs.Options{})
+	apiCfg.registerJobs(queue)
+	return apiCfg, queue, fake
+}
+
+// linkToken returns the token query parameter of the link to path in text.
+func linkToken(t *testing.T, text, path string) string {
+	t.Helper()
+	for _, field := range strings.Fields(text) {
+		u, err := url.Parse(field)
+		if err == nil && u.Path == path {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("No link to %s in %q", path, text)
+	return ""
+}
+
+func TestNewsletterSubscription(t *testing.T) {
+	apiCfg, queue, fake := setupNewsletter(t)
+	ctx := context.Background()
+
+	subscribe := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/newsletter", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		apiCfg.handleSubscribe(rr, req)
+		return rr
+	}
+	visit := func(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := subscribe(url.Values{"email": {"reader@example.org"}})
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/newsletter?subscribed=1" {
+		t.Fatalf("Expected a redirect to the thank you page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	subscriber, err := apiCfg.DB.GetSubscriberByEmail(ctx, "reader@example.org")
+	if err != nil || subscriber.Status != subscriberPending {
+		t.Fatalf("Expected a pending subscriber, got %+v: %v", subscriber, err)
+	}
+
+	queue.RunDue(ctx)
+	sent := fake.take()
+	if len(sent) != 1 || sent[0].To != "reader@example.org" {
+		t.Fatalf("Expected one confirmation email, got %+v", sent)
+	}
+	token := linkToken(t, sent[0].Text, "/newsletter/confirm")
+
+	// Asking again straight away doesn't send another email
+	subscribe(url.Values{"email": {"Reader@example.org"}})
+	queue.RunDue(ctx)
+	if sent := fake.take(); len(sent) != 0 {
+		t.Errorf("Expected no second confirmation email so soon, got %d", len(sent))
+	}
+
+	if rr := visit(apiCfg.handleConfirmSubscription, "GET", "/newsletter/confirm?token=nope"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown token to return 404, got %d", rr.Code)
+	}
+	rr = visit(apiCfg.handleConfirmSubscription, "GET", "/newsletter/confirm?token="+token)
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Body.String(), "confirmed|") {
+		t.Fatalf("Expected the subscription to be confirmed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Referrer-Policy") != "no-referrer" {
+		t.Error("Expected token pages not to send a referrer")
+	}
+	if rr := visit(apiCfg.handleConfirmSubscription, "GET", "/newsletter/confirm?token="+token); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected a used link to stop working, got %d", rr.Code)
+	}
+
+	// Confirmed readers aren't asked again
+	subscribe(url.Values{"email": {"reader@example.org"}})
+	queue.RunDue(ctx)
+	if sent := fake.take(); len(sent) != 0 {
+		t.Errorf("Expected no email to a confirmed subscriber, got %d", len(sent))
+	}
+
+	// Looking at the unsubscribe page changes nothing; the button does
+	subscriber, _ = apiCfg.DB.GetSubscriberByEmail(ctx, "reader@example.org")
+	unsubscribeURL := "/newsletter/unsubscribe?token=" + subscriber.UnsubscribeToken
+	rr = visit(apiCfg.handleUnsubscribePage, "GET", unsubscribeURL)
+	if !strings.HasPrefix(rr.Body.String(), "unsubscribe|") {
+		t.Errorf("Expected to be asked to unsubscribe, got %s", rr.Body.String())
+	}
+	if s, _ := apiCfg.DB.GetSubscriber(ctx, subscriber.ID); s.Status != subscriberConfirmed {
+		t.Errorf("Expected viewing the page to leave the subscription alone, got %s", s.Status)
+	}
+
+	// A mail client's one-click unsubscribe
+	req := httptest.NewRequest("POST", unsubscribeURL, strings.NewReader(unsubscribeOneClickArg))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	apiCfg.handleUnsubscribe(rr, req)
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Body.String(), "unsubscribed|") {
+		t.Fatalf("Expected to be unsubscribed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if s, _ := apiCfg.DB.GetSubscriber(ctx, subscriber.ID); s.Status != subscriberUnsubscribed {
+		t.Errorf("Expected the subscriber to be unsubscribed, got %s", s.Status)
+	}
+	if rr := visit(apiCfg.handleUnsubscribe, "POST", unsubscribeURL); rr.Code != http.StatusOK {
+		t.Errorf("Expected unsubscribing twice to be harmless, got %d", rr.Code)
+	}
+	if rr := visit(apiCfg.handleUnsubscribe, "POST", "/newsletter/unsubscribe?token=nope"); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown token to return 404, got %d", rr.Code)
+	}
+
+	// Coming back starts over with a new confirmation
+	subscribe(url.Values{"email": {"reader@example.org"}})
+	queue.RunDue(ctx)
+	sent = fake.take()
+	if len(sent) != 1 || linkToken(t, sent[0].Text, "/newsletter/confirm") == token {
+		t.Fatalf("Expected a new confirmation link, got %+v", sent)
+	}
+
+	// Confirmation links expire
+	if _, err := apiCfg.dbConn.Exec("UPDATE subscribers SET updated_at = datetime('now', '-8 days')"); err != nil {
+		t.Fatal(err)
+	}
+	token = linkToken(t, sent[0].Text, "/newsletter/confirm")
+	if rr := visit(apiCfg.handleConfirmSubscription, "GET", "/newsletter/confirm?token="+token); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected an expired link to return 404, got %d", rr.Code)
+	}
+
+	rr = subscribe(url.Values{"email": {"not an address"}})
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "valid email") {
+		t.Errorf("Expected an invalid address to be refused, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = subscribe(url.Values{"email": {"bot@example.org"}, commentHoneypotField: {"Acme"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("Expected bots to be redirected like everyone else, got %d", rr.Code)
+	}
+	if _, err := apiCfg.DB.GetSubscriberByEmail(ctx, "bot@example.org"); err == nil {
+		t.Error("Expected the honeypot to drop the subscription")
+	}
+}
+
+func TestNewsletterRequiresBaseURL(t *testing.T) {
+	apiCfg, queue, fake := setupNewsletter(t)
+	ctx := context.Background()
+
+	subscribe := func() *httptest.ResponseRecorder {
+		form := url.Values{"email": {"reader@example.org"}}
+		req := httptest.NewRequest("POST", "/newsletter", strings.NewReader(form.Encode()))
+		req.Host = "evil.example"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		apiCfg.handleSubscribe(rr, req)
+		return rr
+	}
+
+	// Links come from BASE_URL, never from the request
+	subscribe()
+	queue.RunDue(ctx)
+	sent := fake.take()
+	if len(sent) != 1 || !strings.Contains(sent[0].Text, "https://journal.example/newsletter/confirm?") || strings.Contains(sent[0].Text, "evil.example") {
+		t.Fatalf("Expected a link to BASE_URL, got %+v", sent)
+	}
+
+	apiCfg.baseURL = ""
+	apiCfg.dbConn.Exec("DELETE FROM subscribers")
+	if rr := subscribe(); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected subscribing without BASE_URL to return 503, got %d", rr.Code)
+	}
+	if _, err := apiCfg.DB.GetSubscriberByEmail(ctx, "reader@example.org"); err == nil {
+		t.Error("Expected no subscriber to be saved without BASE_URL")
+	}
+
+	req := httptest.NewRequest("POST", "/api/newsletter/sends", nil)
+	rr := httptest.NewRecorder()
+	apiCfg.sendNewsletter(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected sending a digest without BASE_URL to return 503, got %d", rr.Code)
+	}
+}
+
+func TestNewsletterDigest(t *testing.T) {
+	apiCfg, queue, fake := setupNewsletter(t)
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "owner", "password")
+
+	var ids []int64
+	for _, email := range []string{"a@example.org", "bounce@example.org", "c@example.org", "pending@example.org"} {
+		s, err := apiCfg.DB.CreateSubscriber(ctx, database.CreateSubscriberParams{
+			Email: email, ConfirmToken: "confirm-" + email, UnsubscribeToken: "unsubscribe-" + email,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.ID)
+	}
+	apiCfg.dbConn.Exec("UPDATE subscribers SET status = 'confirmed' WHERE email != 'pending@example.org'")
+	fake.reject = map[string]bool{"bounce@example.org": true}
+
+	call := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	if rr := call(apiCfg.sendNewsletter, "POST", "/api/newsletter/sends", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected nothing to send without entries, got %d", rr.Code)
+	}
+
+	for _, visibility := range []string{"public", "private", "unlisted"} {
+		_, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+			Title: "A " + visibility + " entry", Content: "content", UserID: user.ID, Format: "html",
+			Visibility: visibility, Excerpt: "What happened today",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rr := call(apiCfg.sendNewsletter, "POST", "/api/newsletter/sends", "")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Expected the digest to be queued, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var send NewsletterSend
+	json.NewDecoder(rr.Body).Decode(&send)
+	if send.EntryCount != 1 || send.Subject != "New on My Journal: A public entry" || send.Status != newsletterSending {
+		t.Errorf("Unexpected digest %+v", send)
+	}
+	if rr := call(apiCfg.sendNewsletter, "POST", "/api/newsletter/sends", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Expected a second digest to wait for the first, got %d", rr.Code)
+	}
+
+	queue.RunDue(ctx)
+	sent := fake.take()
+	if len(sent) != 2 || sent[0].To != "a@example.org" || sent[1].To != "c@example.org" {
+		t.Fatalf("Expected the digest to reach the confirmed subscribers, got %+v", sent)
+	}
+	msg := sent[0]
+	if !strings.Contains(msg.Text, "https://journal.example/journals/") || !strings.Contains(msg.HTML, "What happened today") {
+		t.Errorf("Expected the digest to link the entry, got %q", msg.Text)
+	}
+	if strings.Contains(msg.Text, "private") || strings.Contains(msg.Text, "unlisted") {
+		t.Errorf("Expected only public entries, got %q", msg.Text)
+	}
+	if msg.Headers["List-Unsubscribe"] != "<https://journal.example/newsletter/unsubscribe?token=unsubscribe-a%40example.org>" ||
+		msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("Expected one-click unsubscribe headers, got %v", msg.Headers)
+	}
+
+	rr = call(apiCfg.getNewsletter, "GET", "/api/newsletter", "")
+	var resp NewsletterResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Subscribers[subscriberConfirmed] != 3 || resp.Subscribers[subscriberPending] != 1 || resp.Subscribers[subscriberUnsubscribed] != 0 {
+		t.Errorf("Unexpected subscriber counts %v", resp.Subscribers)
+	}
+	if resp.Total != 1 || resp.Sends[0].Status != newsletterSent || resp.Sends[0].DeliveredCount != 2 ||
+		resp.Sends[0].FailedCount != 1 || resp.Sends[0].FinishedAt == nil {
+		t.Errorf("Unexpected send history %+v", resp.Sends)
+	}
+
+	// The next digest starts where this one ended
+	if rr := call(apiCfg.sendNewsletter, "POST", "/api/newsletter/sends", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected no new entries since the last digest, got %d", rr.Code)
+	}
+	if rr := call(apiCfg.sendNewsletter, "POST", "/api/newsletter/sends", `{"subject": "Hi\r\nBcc: x@example.org"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a multi-line subject to be refused, got %d", rr.Code)
+	}
+
+	// An interrupted digest resumes after the last subscriber it reached
+	interrupted, err := apiCfg.DB.CreateNewsletterSend(ctx, database.CreateNewsletterSendParams{
+		Subject: "Resumed", Since: "2000-01-01 00:00:00", Until: "2999-01-01 00:00:00", EntryCount: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.DB.RecordNewsletterDelivery(ctx, database.RecordNewsletterDeliveryParams{SubscriberID: ids[1], Delivered: 1, ID: interrupted.ID})
+	if err := apiCfg.runSendDigest(ctx, digestJob{SendID: interrupted.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := fake.take(); len(sent) != 1 || sent[0].To != "c@example.org" || sent[0].Subject != "Resumed" {
+		t.Errorf("Expected only the remaining subscriber to get the digest, got %+v", sent)
+	}
+	if s, _ := apiCfg.DB.GetNewsletterSend(ctx, interrupted.ID); s.Status != newsletterSent || s.DeliveredCount != 2 {
+		t.Errorf("Expected the resumed digest to finish, got %+v", s)
+	}
+}
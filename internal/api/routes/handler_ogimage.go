@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/ogimage"
+)
+
+const (
+	// ogImageRetention is how long a share image is kept after it was last
+	// served. Every title or settings change leaves the old card behind.
+	ogImageRetention     = 30 * 24 * time.Hour
+	ogImagePruneInterval = 24 * time.Hour
+)
+
+func (cfg *apiConfig) journalOGImage(w http.ResponseWriter, r *http.Request) {
+	journalID, err := strconv.Atoi(r.PathValue("ID"))
+	if err != nil {
+		http.Error(w, "Invalid journal ID", http.StatusBadRequest)
+		return
+	}
+
+	// Cards give away the title, so only public entries get one
+	journal, err := cfg.DB.GetJournalEntry(r.Context(), int64(journalID))
+	if err != nil || journal.Visibility != visibilityPublic {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+
+	data := cfg.baseTemplateData(r.Context(), journal.Title, "journals")
+	cfg.serveCard(w, r, journalCard(data, journal))
+}
+
+func (cfg *apiConfig) projectOGImage(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.Atoi(r.PathValue("ID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	project, err := cfg.DB.GetProject(r.Context(), int64(projectID))
+	if err != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	data := cfg.baseTemplateData(r.Context(), project.Title, "projects")
+	cfg.serveCard(w, r, projectCard(data, project))
+}
+
+// serveCard writes the PNG for card, rendering it on first request. The
+// content hash doubles as the ETag.
+func (cfg *apiConfig) serveCard(w http.ResponseWriter, r *http.Request, card ogimage.Card) {
+	png, err := cfg.ogImages.Get(card)
+	if err != nil {
+		requestLogger(w).Error("failed to render share image", "error", err)
+		http.Error(w, "Failed to render image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+card.Hash()+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(png))
+}
+
+// runShareImagePruner deletes unused share images every interval until
+// ctx is done.
+func (cfg *apiConfig) runShareImagePruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := cfg.ogImages.Prune(ogImageRetention); err != nil {
+			slog.ErrorContext(ctx, "failed to prune share images", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
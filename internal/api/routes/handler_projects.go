@@ -12,17 +12,19 @@ import (
 )
 
 type Project struct {
-	ProjectID   int       `json:"project_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	ImageURL    string    `json:"image_url"`
-	Link        string    `json:"link"`
-	Github      string    `json:"github"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	UserID      int       `json:"user_id"`
-	Tags        string    `json:"tags"`
+	ProjectID      int       `json:"project_id"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	ImageURL       string    `json:"image_url"`
+	Link           string    `json:"link"`
+	Github         string    `json:"github"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	UserID         int       `json:"user_id"`
+	Tags           string    `json:"tags"`
+	SeoTitle       string    `json:"seo_title,omitempty"`
+	SeoDescription string    `json:"seo_description,omitempty"`
 }
 
 type Tags struct {
@@ -31,14 +33,16 @@ type Tags struct {
 }
 
 type Params struct {
-	ProjectID   int    `json:"project_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	ImageUrl    string `json:"image_url"`
-	Link        string `json:"link"`
-	Github      string `json:"github"`
-	Status      string `json:"status"`
-	Tags        []Tags `json:"tags"`
+	ProjectID      int    `json:"project_id"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	ImageUrl       string `json:"image_url"`
+	Link           string `json:"link"`
+	Github         string `json:"github"`
+	Status         string `json:"status"`
+	Tags           []Tags `json:"tags"`
+	SeoTitle       string `json:"seo_title"`
+	SeoDescription string `json:"seo_description"`
 }
 
 type ProjectsResponse struct {
@@ -74,13 +78,15 @@ func (cgf *apiConfig) createProject(w http.ResponseWriter, r *http.Request) {
 	qtx := cgf.DB.WithTx(tx)
 
 	project, err := qtx.CreateProject(r.Context(), database.CreateProjectParams{
-		Title:       params.Title,
-		Description: params.Description,
-		ImageUrl:    sql.NullString{String: params.ImageUrl, Valid: params.ImageUrl != ""},
-		Link:        sql.NullString{String: params.Link, Valid: params.Link != ""},
-		Github:      sql.NullString{String: params.Github, Valid: params.Github != ""},
-		Status:      sql.NullString{String: params.Status, Valid: params.Status != ""},
-		UserID:      int64(userID),
+		Title:          params.Title,
+		Description:    params.Description,
+		ImageUrl:       sql.NullString{String: params.ImageUrl, Valid: params.ImageUrl != ""},
+		Link:           sql.NullString{String: params.Link, Valid: params.Link != ""},
+		Github:         sql.NullString{String: params.Github, Valid: params.Github != ""},
+		Status:         sql.NullString{String: params.Status, Valid: params.Status != ""},
+		UserID:         int64(userID),
+		SeoTitle:       sql.NullString{String: params.SeoTitle, Valid: params.SeoTitle != ""},
+		SeoDescription: sql.NullString{String: params.SeoDescription, Valid: params.SeoDescription != ""},
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to create project", err)
@@ -134,6 +140,7 @@ func (cgf *apiConfig) createProject(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
 		return
 	}
+	cgf.emitProjectEvent(r, eventProjectCreated, project.ID, project.Title)
 
 	respondWithJson(w, http.StatusCreated, struct {
 		ID          int    `json:"id"`
@@ -181,13 +188,15 @@ func (cfg *apiConfig) updateProject(w http.ResponseWriter, r *http.Request) {
 	qtx := cfg.DB.WithTx(tx)
 
 	err = qtx.UpdateProject(r.Context(), database.UpdateProjectParams{
-		Title:       params.Title,
-		Description: params.Description,
-		ImageUrl:    sql.NullString{String: params.ImageUrl, Valid: params.ImageUrl != ""},
-		Link:        sql.NullString{String: params.Link, Valid: params.Link != ""},
-		Github:      sql.NullString{String: params.Github, Valid: params.Github != ""},
-		Status:      sql.NullString{String: params.Status, Valid: params.Status != ""},
-		ID:          int64(params.ProjectID),
+		Title:          params.Title,
+		Description:    params.Description,
+		ImageUrl:       sql.NullString{String: params.ImageUrl, Valid: params.ImageUrl != ""},
+		Link:           sql.NullString{String: params.Link, Valid: params.Link != ""},
+		Github:         sql.NullString{String: params.Github, Valid: params.Github != ""},
+		Status:         sql.NullString{String: params.Status, Valid: params.Status != ""},
+		SeoTitle:       sql.NullString{String: params.SeoTitle, Valid: params.SeoTitle != ""},
+		SeoDescription: sql.NullString{String: params.SeoDescription, Valid: params.SeoDescription != ""},
+		ID:             int64(params.ProjectID),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to update project", err)
@@ -245,6 +254,7 @@ func (cfg *apiConfig) updateProject(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
 		return
 	}
+	cfg.emitProjectEvent(r, eventProjectUpdated, int64(params.ProjectID), params.Title)
 
 	respondWithJson(w, http.StatusOK, map[string]string{
 		"message": "successfully updated",
@@ -343,16 +353,19 @@ func (cfg *apiConfig) getProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondWithJson(w, http.StatusOK, Project{
-		ProjectID:   int(project.ProjectID),
-		Title:       project.Title,
-		Description: project.Description,
-		ImageURL:    project.ImageUrl.String,
-		Link:        project.Link.String,
-		Github:      project.Github.String,
-		Status:      project.Status.String,
-		CreatedAt:   project.CreatedAt.Time,
-		UserID:      int(project.UserID),
-		Tags:        project.Tags,
+		ProjectID:      int(project.ProjectID),
+		Title:          project.Title,
+		Description:    project.Description,
+		ImageURL:       project.ImageUrl.String,
+		Link:           project.Link.String,
+		Github:         project.Github.String,
+		Status:         project.Status.String,
+		CreatedAt:      project.CreatedAt.Time,
+		UpdatedAt:      project.UpdatedAt.Time,
+		UserID:         int(project.UserID),
+		Tags:           project.Tags,
+		SeoTitle:       project.SeoTitle.String,
+		SeoDescription: project.SeoDescription.String,
 	})
 }
 
@@ -366,13 +379,15 @@ func (cfg *apiConfig) deleteProject(w http.ResponseWriter, r *http.Request) {
 	projectID, err := strconv.Atoi(projectIDStr)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "invalid projectID", err)
+		return
 	}
 
-	err = cfg.DB.DeleteProject(r.Context(), int64(projectID))
+	err = cfg.DB.TrashProject(r.Context(), int64(projectID))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "failed to delete project", err)
 		return
 	}
+	cfg.emitProjectEvent(r, eventProjectDeleted, int64(projectID), "")
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,391 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+type Series struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	UserID      int    `json:"user_id"`
+	EntryCount  int    `json:"entry_count"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// SeriesEntry is one part of a series, numbered from 1 in reading order.
+type SeriesEntry struct {
+	Part           int    `json:"part"`
+	JournalID      int    `json:"journal_id"`
+	Title          string `json:"title"`
+	Excerpt        string `json:"excerpt"`
+	ReadingMinutes int    `json:"reading_minutes"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// SeriesDetail is a series with its entries in order.
+type SeriesDetail struct {
+	Series
+	Entries []SeriesEntry `json:"entries"`
+}
+
+// errInvalidSeriesEntries marks a journal_ids list the client has to fix.
+var errInvalidSeriesEntries = errors.New("invalid journal_ids")
+
+func seriesDetail(series database.Series, entries []database.ListSeriesEntriesRow) SeriesDetail {
+	detail := SeriesDetail{
+		Series: Series{
+			ID:          int(series.ID),
+			Title:       series.Title,
+			Description: series.Description,
+			UserID:      int(series.UserID),
+			EntryCount:  len(entries),
+			CreatedAt:   series.CreatedAt.Time.String(),
+			UpdatedAt:   series.UpdatedAt.Time.String(),
+		},
+		Entries: []SeriesEntry{},
+	}
+
+	for i, entry := range entries {
+		detail.Entries = append(detail.Entries, SeriesEntry{
+			Part:           i + 1,
+			JournalID:      int(entry.ID),
+			Title:          entry.Title,
+			Excerpt:        entry.Excerpt,
+			ReadingMinutes: int(entry.ReadingMinutes),
+			CreatedAt:      entry.CreatedAt.Time.String(),
+		})
+	}
+
+	return detail
+}
+
+// loadSeries fetches a series and its entries in reading order.
+func (cfg *apiConfig) loadSeries(ctx context.Context, seriesID int64) (database.Series, []database.ListSeriesEntriesRow, error) {
+	series, err := cfg.DB.GetSeries(ctx, seriesID)
+	if err != nil {
+		return series, nil, err
+	}
+
+	entries, err := cfg.DB.ListSeriesEntries(ctx, seriesID)
+	if err != nil {
+		return series, nil, err
+	}
+
+	return series, entries, nil
+}
+
+// checkSeriesEntries rejects duplicate or unknown journal IDs before any
+// membership is touched.
+func (cfg *apiConfig) checkSeriesEntries(ctx context.Context, journalIDs []int) error {
+	seen := make(map[int]bool, len(journalIDs))
+	for _, id := range journalIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: journal %d is listed twice", errInvalidSeriesEntries, id)
+		}
+		seen[id] = true
+
+		if _, err := cfg.DB.GetJournalEntry(ctx, int64(id)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: journal %d not found", errInvalidSeriesEntries, id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// setSeriesEntries replaces the membership of a series with journalIDs, in
+// that order. An entry can only be in one series, so listing it here moves
+// it out of any other. Trashed entries can't be listed, so they keep their
+// place and the listed entries are numbered around them, ready for a
+// restore.
+func setSeriesEntries(ctx context.Context, qtx *database.Queries, seriesID int64, journalIDs []int) error {
+	trashed, err := qtx.ListTrashedSeriesPositions(ctx, seriesID)
+	if err != nil {
+		return err
+	}
+	if err := qtx.ClearUntrashedSeriesEntries(ctx, seriesID); err != nil {
+		return err
+	}
+
+	var position int64
+	for _, id := range journalIDs {
+		position++
+		for slices.Contains(trashed, position) {
+			position++
+		}
+		if err := qtx.RemoveJournalFromSeries(ctx, int64(id)); err != nil {
+			return err
+		}
+		err := qtx.AddSeriesEntry(ctx, database.AddSeriesEntryParams{
+			SeriesID:  seriesID,
+			JournalID: int64(id),
+			Position:  position,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getSeriesList(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.DB.ListSeries(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	series := []Series{}
+	for _, row := range rows {
+		series = append(series, Series{
+			ID:          int(row.ID),
+			Title:       row.Title,
+			Description: row.Description,
+			UserID:      int(row.UserID),
+			EntryCount:  int(row.EntryCount),
+			CreatedAt:   row.CreatedAt.Time.String(),
+			UpdatedAt:   row.UpdatedAt.Time.String(),
+		})
+	}
+
+	respondWithJson(w, http.StatusOK, series)
+}
+
+func (cfg *apiConfig) getSeries(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("seriesID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid series ID", err)
+		return
+	}
+
+	series, entries, err := cfg.loadSeries(r.Context(), int64(seriesID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "series not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, seriesDetail(series, entries))
+}
+
+func (cfg *apiConfig) createSeries(w http.ResponseWriter, r *http.Request) {
+	type Req struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		JournalIDs  []int  `json:"journal_ids"`
+	}
+
+	var req Req
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+
+	if req.Title == "" {
+		respondWithError(w, http.StatusBadRequest, "please fill in required fields", nil)
+		return
+	}
+
+	if err := cfg.checkSeriesEntries(r.Context(), req.JournalIDs); err != nil {
+		if errors.Is(err, errInvalidSeriesEntries) {
+			respondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to check journals", err)
+		return
+	}
+
+	userID := r.Context().Value(userIDKey).(int)
+
+	tx, err := cfg.dbConn.Begin()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	series, err := qtx.CreateSeries(r.Context(), database.CreateSeriesParams{
+		Title:       req.Title,
+		Description: req.Description,
+		UserID:      int64(userID),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to create series", err)
+		return
+	}
+
+	if err := setSeriesEntries(r.Context(), qtx, series.ID, req.JournalIDs); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to add series entries", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	series, entries, err := cfg.loadSeries(r.Context(), series.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusCreated, seriesDetail(series, entries))
+}
+
+func (cfg *apiConfig) updateSeries(w http.ResponseWriter, r *http.Request) {
+	type Params struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+
+	var params Params
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+
+	if params.ID == 0 || params.Title == "" {
+		respondWithError(w, http.StatusBadRequest, "please fill in required fields", nil)
+		return
+	}
+
+	if _, err := cfg.DB.GetSeries(r.Context(), int64(params.ID)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "series not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	err := cfg.DB.UpdateSeries(r.Context(), database.UpdateSeriesParams{
+		Title:       params.Title,
+		Description: params.Description,
+		ID:          int64(params.ID),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update series", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{
+		"message": "series updated successfully",
+	})
+}
+
+// reorderSeries sets which entries make up a series and in what order.
+// Entries left out of journal_ids are removed from the series.
+func (cfg *apiConfig) reorderSeries(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("seriesID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid series ID", err)
+		return
+	}
+
+	type Req struct {
+		JournalIDs []int `json:"journal_ids"`
+	}
+
+	var req Req
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+
+	if _, err := cfg.DB.GetSeries(r.Context(), int64(seriesID)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "series not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	if err := cfg.checkSeriesEntries(r.Context(), req.JournalIDs); err != nil {
+		if errors.Is(err, errInvalidSeriesEntries) {
+			respondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to check journals", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.Begin()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := setSeriesEntries(r.Context(), cfg.DB.WithTx(tx), int64(seriesID), req.JournalIDs); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to reorder series", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	series, entries, err := cfg.loadSeries(r.Context(), int64(seriesID))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get series", err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, seriesDetail(series, entries))
+}
+
+func (cfg *apiConfig) deleteSeries(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.Atoi(r.PathValue("seriesID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid series ID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.Begin()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DB.WithTx(tx)
+
+	// Foreign keys aren't enforced on every connection, so don't rely on
+	// the cascade to release the entries
+	if err := qtx.ClearSeriesEntries(r.Context(), int64(seriesID)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete series", err)
+		return
+	}
+	if err := qtx.DeleteSeries(r.Context(), int64(seriesID)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete series", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,200 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestSeriesOrdering(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "seriesuser", "password")
+
+	var ids []int
+	for _, title := range []string{"One", "Two", "Three", "Standalone"} {
+		journal, err := apiCfg.DB.CreateJournalEntry(context.Background(), database.CreateJournalEntryParams{
+			Title:      title,
+			Content:    title,
+			UserID:     user.ID,
+			Format:     "html",
+			Visibility: "public",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(journal.ID))
+	}
+
+	call := func(handler http.HandlerFunc, method, path string, seriesID int, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(payload))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		if seriesID != 0 {
+			req.SetPathValue("seriesID", strconv.Itoa(seriesID))
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := call(apiCfg.createSeries, "POST", "/api/series", 0, map[string]interface{}{
+		"title":       "Building a journal",
+		"journal_ids": []int{ids[2], ids[0], ids[1]},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var created SeriesDetail
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if got := entryIDs(created); !slices.Equal(got, []int{ids[2], ids[0], ids[1]}) {
+		t.Errorf("Expected entries in the given order, got %v", got)
+	}
+
+	nav, err := apiCfg.DB.GetJournalSeriesNav(context.Background(), int64(ids[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Part != 2 || nav.Total != 3 || nav.PreviousID != int64(ids[2]) || nav.NextID != int64(ids[1]) {
+		t.Errorf("Unexpected navigation for middle part: %+v", nav)
+	}
+
+	rr = call(apiCfg.reorderSeries, "PUT", "/api/series/entries", created.ID, map[string]interface{}{
+		"journal_ids": []int{ids[0], ids[1]},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var reordered SeriesDetail
+	if err := json.NewDecoder(rr.Body).Decode(&reordered); err != nil {
+		t.Fatal(err)
+	}
+	if got := entryIDs(reordered); !slices.Equal(got, []int{ids[0], ids[1]}) {
+		t.Errorf("Expected reordered entries, got %v", got)
+	}
+
+	nav, err = apiCfg.DB.GetJournalSeriesNav(context.Background(), int64(ids[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Part != 1 || nav.Total != 2 || nav.PreviousID != nil {
+		t.Errorf("Unexpected navigation for first part: %+v", nav)
+	}
+
+	if _, err := apiCfg.DB.GetJournalSeriesNav(context.Background(), int64(ids[2])); err == nil {
+		t.Error("Expected the dropped entry to leave the series")
+	}
+
+	tests := []struct {
+		name       string
+		journalIDs []int
+	}{
+		{"duplicate", []int{ids[0], ids[0]}},
+		{"unknown journal", []int{ids[0], 9999}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := call(apiCfg.reorderSeries, "PUT", "/api/series/entries", created.ID, map[string]interface{}{
+				"journal_ids": tt.journalIDs,
+			})
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	rr = call(apiCfg.reorderSeries, "PUT", "/api/series/entries", 9999, map[string]interface{}{
+		"journal_ids": []int{},
+	})
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a missing series, got %d", rr.Code)
+	}
+}
+
+func entryIDs(detail SeriesDetail) []int {
+	var ids []int
+	for _, entry := range detail.Entries {
+		ids = append(ids, entry.JournalID)
+	}
+	return ids
+}
+
+func TestSeriesKeepsTrashedEntries(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "seriesuser", "password")
+
+	var ids []int
+	for _, title := range []string{"One", "Two", "Three"} {
+		journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+			Title: title, Content: title, UserID: user.ID, Format: "html", Visibility: "public",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(journal.ID))
+	}
+
+	edit := func(handler http.HandlerFunc, seriesID int, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("PUT", "/api/series/entries", bytes.NewBuffer(payload))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		req.SetPathValue("seriesID", strconv.Itoa(seriesID))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := edit(apiCfg.createSeries, 0, map[string]interface{}{"title": "Parts", "journal_ids": ids})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created SeriesDetail
+	json.NewDecoder(rr.Body).Decode(&created)
+
+	// Part two goes to the trash, then the series is edited without it
+	if err := apiCfg.DB.TrashJournalEntry(ctx, database.TrashJournalEntryParams{ID: int64(ids[1]), UserID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	rr = edit(apiCfg.reorderSeries, created.ID, map[string]interface{}{"journal_ids": []int{ids[2], ids[0]}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if _, err := apiCfg.DB.RestoreJournalEntry(ctx, int64(ids[1])); err != nil {
+		t.Fatal(err)
+	}
+	_, entries, err := apiCfg.loadSeries(ctx, int64(created.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		got = append(got, int(entry.ID))
+	}
+	if !slices.Equal(got, []int{ids[2], ids[1], ids[0]}) {
+		t.Errorf("Expected the restored entry back in its place, got %v", got)
+	}
+
+	nav, err := apiCfg.DB.GetJournalSeriesNav(ctx, int64(ids[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav.Part != 2 || nav.Total != 3 {
+		t.Errorf("Expected the restored entry to be part 2 of 3, got %+v", nav)
+	}
+}
@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+// maxRobotsTxtLength caps the extra robots.txt rules an admin can save.
+const maxRobotsTxtLength = 10000
+
+func (cfg *apiConfig) getSettings(w http.ResponseWriter, r *http.Request) {
+	respondWithJson(w, http.StatusOK, cfg.siteSettings(r.Context()))
+}
+
+func (cfg *apiConfig) updateSettings(w http.ResponseWriter, r *http.Request) {
+	var params SiteSettings
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON", err)
+		return
+	}
+
+	if params.SiteTitle == "" {
+		respondWithError(w, http.StatusBadRequest, "site title is required", nil)
+		return
+	}
+
+	if params.PostsPerPage < 1 || params.PostsPerPage > 100 {
+		respondWithError(w, http.StatusBadRequest, "posts per page must be between 1 and 100", nil)
+		return
+	}
+
+	if params.Timezone == "" {
+		params.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(params.Timezone); err != nil {
+		respondWithError(w, http.StatusBadRequest, "unknown timezone", err)
+		return
+	}
+
+	if params.DefaultOGImage != "" && !isWebURL(params.DefaultOGImage) {
+		respondWithError(w, http.StatusBadRequest, "default OpenGraph image must be an http(s) URL", nil)
+		return
+	}
+
+	if len(params.RobotsTxt) > maxRobotsTxtLength {
+		respondWithError(w, http.StatusBadRequest, "robots.txt rules are too long", nil)
+		return
+	}
+
+	if params.SocialLinks == nil {
+		params.SocialLinks = []SocialLink{}
+	}
+	for _, link := range params.SocialLinks {
+		if link.Name == "" || !isWebURL(link.URL) {
+			respondWithError(w, http.StatusBadRequest, "social links need a name and an http(s) URL", nil)
+			return
+		}
+	}
+
+	socialLinks, err := json.Marshal(params.SocialLinks)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to encode social links", err)
+		return
+	}
+
+	err = cfg.DB.UpdateSiteSettings(r.Context(), database.UpdateSiteSettingsParams{
+		SiteTitle:      params.SiteTitle,
+		Tagline:        params.Tagline,
+		FooterText:     params.FooterText,
+		SocialLinks:    string(socialLinks),
+		DefaultOgImage: params.DefaultOGImage,
+		PostsPerPage:   int64(params.PostsPerPage),
+		Timezone:       params.Timezone,
+		RobotsTxt:      params.RobotsTxt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update settings", err)
+		return
+	}
+
+	if cfg.settings != nil {
+		cfg.settings.invalidate()
+	}
+
+	respondWithJson(w, http.StatusOK, cfg.siteSettings(r.Context()))
+}
+
+// isWebURL reports whether s is an absolute http or https URL.
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
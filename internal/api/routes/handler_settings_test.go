@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestUpdateSettings(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	apiCfg.settings = newSettingsCache(apiCfg.DB)
+
+	// Prime the cache so the update has something to invalidate
+	if got := apiCfg.siteSettings(t.Context()).SiteTitle; got != "Sianwa" {
+		t.Fatalf("Expected seeded site title Sianwa, got %s", got)
+	}
+
+	tests := []struct {
+		name       string
+		payload    map[string]interface{}
+		wantStatus int
+	}{
+		{
+			name:       "missing title",
+			payload:    map[string]interface{}{"posts_per_page": 10, "timezone": "UTC"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown timezone",
+			payload:    map[string]interface{}{"site_title": "Notes", "posts_per_page": 10, "timezone": "Mars/Olympus"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid social link",
+			payload: map[string]interface{}{
+				"site_title": "Notes", "posts_per_page": 10, "timezone": "UTC",
+				"social_links": []map[string]string{{"name": "Mastodon", "url": "javascript:alert(1)"}},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "valid settings",
+			payload: map[string]interface{}{
+				"site_title": "Notes", "tagline": "Things I learned", "posts_per_page": 5, "timezone": "Africa/Nairobi",
+				"social_links": []map[string]string{{"name": "Mastodon", "url": "https://hachyderm.io/@me"}},
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloadBytes, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("PUT", "/api/settings", bytes.NewBuffer(payloadBytes))
+			rr := httptest.NewRecorder()
+
+			apiCfg.updateSettings(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d. Response: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	settings := apiCfg.siteSettings(t.Context())
+	if settings.SiteTitle != "Notes" || settings.PostsPerPage != 5 {
+		t.Errorf("Expected cached settings to be refreshed, got %+v", settings)
+	}
+	if settings.Location().String() != "Africa/Nairobi" {
+		t.Errorf("Expected Africa/Nairobi location, got %s", settings.Location())
+	}
+	if len(settings.SocialLinks) != 1 || settings.SocialLinks[0].Name != "Mastodon" {
+		t.Errorf("Expected one Mastodon social link, got %+v", settings.SocialLinks)
+	}
+}
+
+func TestSettingsCacheDropsStaleLoad(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	cache := newSettingsCache(apiCfg.DB)
+
+	// A load reads the old row, then the settings are saved and
+	// invalidated before it stores what it read
+	stale := SiteSettings{SiteTitle: "Old"}
+	cache.mu.RLock()
+	generation := cache.generation
+	cache.mu.RUnlock()
+	if _, err := db.Exec("UPDATE site_settings SET site_title = 'New'"); err != nil {
+		t.Fatal(err)
+	}
+	cache.invalidate()
+	cache.store(generation, stale)
+
+	settings, err := cache.get(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.SiteTitle != "New" {
+		t.Errorf("Expected the stale load not to be cached, got %s", settings.SiteTitle)
+	}
+}
+
+func TestSettingsLocationIsResolvedOnLoad(t *testing.T) {
+	settings := siteSettingsFromDB(database.SiteSetting{Timezone: "Africa/Nairobi"})
+	if settings.location == nil || settings.Location() != settings.location {
+		t.Fatal("Expected the timezone to be resolved when the settings are loaded")
+	}
+	if got := settings.Location().String(); got != "Africa/Nairobi" {
+		t.Errorf("Expected Africa/Nairobi, got %s", got)
+	}
+
+	if got := siteSettingsFromDB(database.SiteSetting{Timezone: "Mars/Olympus"}).Location(); got != time.UTC {
+		t.Errorf("Expected an unknown timezone to fall back to UTC, got %s", got)
+	}
+	if got := defaultSiteSettings.Location(); got != time.UTC {
+		t.Errorf("Expected the defaults to use UTC, got %s", got)
+	}
+}
@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sianwa11/my-journal/internal/sitemap"
+)
+
+// sitemapURLs lists every public page worth indexing: the home page, the
+// list pages, each journal entry and project, a page per project tag and
+// each series with entries in it.
+func (cfg *apiConfig) sitemapURLs(r *http.Request) ([]sitemap.URL, error) {
+	ctx := r.Context()
+
+	journals, err := cfg.DB.ListSitemapJournals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing journals: %w", err)
+	}
+
+	projects, err := cfg.DB.ListSitemapProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing projects: %w", err)
+	}
+
+	tags, err := cfg.DB.ListSitemapTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+
+	series, err := cfg.DB.ListSeries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing series: %w", err)
+	}
+
+	journalURLs := make([]sitemap.URL, 0, len(journals))
+	for _, j := range journals {
+		lastMod := j.UpdatedAt.Time
+		if lastMod.IsZero() {
+			lastMod = j.CreatedAt.Time
+		}
+		journalURLs = append(journalURLs, sitemap.URL{
+			Loc:     cfg.absoluteURL(r, "/journals/"+strconv.FormatInt(j.ID, 10)),
+			LastMod: lastMod,
+		})
+	}
+
+	projectURLs := make([]sitemap.URL, 0, len(projects))
+	for _, p := range projects {
+		lastMod := p.UpdatedAt.Time
+		if lastMod.IsZero() {
+			lastMod = p.CreatedAt.Time
+		}
+		projectURLs = append(projectURLs, sitemap.URL{
+			Loc:     cfg.absoluteURL(r, "/projects/"+strconv.FormatInt(p.ID, 10)),
+			LastMod: lastMod,
+		})
+	}
+
+	// Rows come back one per tagged project, ordered by tag name; a tag
+	// page changes whenever any of its projects does.
+	var tagURLs []sitemap.URL
+	for _, t := range tags {
+		loc := cfg.absoluteURL(r, "/tags/"+url.PathEscape(t.Name))
+		if n := len(tagURLs); n > 0 && tagURLs[n-1].Loc == loc {
+			if t.UpdatedAt.Time.After(tagURLs[n-1].LastMod) {
+				tagURLs[n-1].LastMod = t.UpdatedAt.Time
+			}
+			continue
+		}
+		tagURLs = append(tagURLs, sitemap.URL{Loc: loc, LastMod: t.UpdatedAt.Time})
+	}
+
+	var seriesURLs []sitemap.URL
+	for _, s := range series {
+		if s.EntryCount == 0 {
+			continue
+		}
+		seriesURLs = append(seriesURLs, sitemap.URL{
+			Loc:     cfg.absoluteURL(r, "/series/"+strconv.FormatInt(s.ID, 10)),
+			LastMod: s.UpdatedAt.Time,
+		})
+	}
+
+	urls := []sitemap.URL{
+		{Loc: cfg.absoluteURL(r, "/"), LastMod: cfg.siteSettings(ctx).UpdatedAt},
+		{Loc: cfg.absoluteURL(r, "/journals"), LastMod: sitemap.Newest(journalURLs)},
+		{Loc: cfg.absoluteURL(r, "/archive"), LastMod: sitemap.Newest(journalURLs)},
+		{Loc: cfg.absoluteURL(r, "/projects"), LastMod: sitemap.Newest(projectURLs)},
+	}
+	urls = append(urls, journalURLs...)
+	urls = append(urls, projectURLs...)
+	urls = append(urls, tagURLs...)
+	urls = append(urls, seriesURLs...)
+
+	return urls, nil
+}
+
+// handleSitemap serves /sitemap.xml. Once the site outgrows a single file
+// it becomes a sitemap index pointing at /sitemap/{n}.xml.
+func (cfg *apiConfig) handleSitemap(w http.ResponseWriter, r *http.Request) {
+	urls, err := cfg.sitemapURLs(r)
+	if err != nil {
+		requestLogger(w).Error("failed to build sitemap", "error", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+
+	pages := sitemap.Pages(len(urls))
+	if pages == 1 {
+		err = sitemap.WriteURLSet(w, urls)
+	} else {
+		index := make([]sitemap.URL, 0, pages)
+		for page := 1; page <= pages; page++ {
+			index = append(index, sitemap.URL{
+				Loc:     cfg.absoluteURL(r, "/sitemap/"+strconv.Itoa(page)+".xml"),
+				LastMod: sitemap.Newest(sitemap.Page(urls, page)),
+			})
+		}
+		err = sitemap.WriteIndex(w, index)
+	}
+	if err != nil {
+		requestLogger(w).Error("failed to write sitemap", "error", err)
+	}
+}
+
+// handleSitemapPage serves one file of a sitemap index.
+func (cfg *apiConfig) handleSitemapPage(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("file"), ".xml")
+	page, err := strconv.Atoi(name)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	urls, err := cfg.sitemapURLs(r)
+	if err != nil {
+		requestLogger(w).Error("failed to build sitemap", "error", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	urls = sitemap.Page(urls, page)
+	if urls == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := sitemap.WriteURLSet(w, urls); err != nil {
+		requestLogger(w).Error("failed to write sitemap", "error", err)
+	}
+}
+
+// handleRobots serves /robots.txt. The admin UI and API are always
+// disallowed; any extra rules from the site settings follow, and the
+// sitemap location comes last.
+func (cfg *apiConfig) handleRobots(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	b.WriteString("Disallow: /admin\n")
+	b.WriteString("Disallow: /api\n")
+
+	if extra := strings.TrimSpace(cfg.siteSettings(r.Context()).RobotsTxt); extra != "" {
+		b.WriteString("\n")
+		b.WriteString(strings.ReplaceAll(extra, "\r\n", "\n"))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nSitemap: " + cfg.absoluteURL(r, "/sitemap.xml") + "\n")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
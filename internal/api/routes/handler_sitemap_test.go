@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestSitemap(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.baseURL = "https://example.com"
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "sitemapuser", "password")
+
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Hello", Content: "World", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create journal: %v", err)
+	}
+
+	project, err := apiCfg.DB.CreateProject(ctx, database.CreateProjectParams{
+		Title: "Site", Description: "This site", UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	tag, err := apiCfg.DB.CreateTag(ctx, "go lang")
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	_, err = apiCfg.DB.CreateProjectTag(ctx, database.CreateProjectTagParams{ProjectID: project.ID, TagID: tag.ID})
+	if err != nil {
+		t.Fatalf("Failed to tag project: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/sitemap.xml", nil)
+	rr := httptest.NewRecorder()
+	apiCfg.handleSitemap(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("Expected XML content type, got %s", ct)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"<loc>https://example.com/</loc>",
+		"<loc>https://example.com/journals</loc>",
+		"<loc>https://example.com/journals/" + strconv.FormatInt(journal.ID, 10) + "</loc>",
+		"<loc>https://example.com/projects/" + strconv.FormatInt(project.ID, 10) + "</loc>",
+		"<loc>https://example.com/tags/go%20lang</loc>",
+		"<lastmod>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Sitemap missing %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestSitemapPageOutOfRange(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	for _, file := range []string{"2.xml", "0.xml", "one.xml", "1.txt"} {
+		req := httptest.NewRequest("GET", "/sitemap/"+file, nil)
+		req.SetPathValue("file", file)
+		rr := httptest.NewRecorder()
+		apiCfg.handleSitemapPage(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", file, rr.Code)
+		}
+	}
+}
+
+func TestRobots(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	_, err := db.Exec(`UPDATE site_settings SET robots_txt = 'User-agent: GPTBot' || char(13, 10) || 'Disallow: /'`)
+	if err != nil {
+		t.Fatalf("Failed to set robots rules: %v", err)
+	}
+	apiCfg.settings = newSettingsCache(apiCfg.DB)
+
+	req := httptest.NewRequest("GET", "/robots.txt", nil)
+	req.Host = "journal.test"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+	apiCfg.handleRobots(rr, req)
+
+	want := "User-agent: *\nDisallow: /admin\nDisallow: /api\n\n" +
+		"User-agent: GPTBot\nDisallow: /\n\n" +
+		"Sitemap: https://journal.test/sitemap.xml\n"
+	if got := rr.Body.String(); got != want {
+		t.Errorf("robots.txt = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/analytics"
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+const (
+	// analyticsRollupInterval is how often recorded page views are added
+	// to the daily totals. /api/stats also rolls up before answering.
+	analyticsRollupInterval = 10 * time.Minute
+
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+	statsTopLimit    = 10
+)
+
+// recordPageView counts a view of path for the stats. It is called once a
+// public page has been found; the owner's own visits, when signed in, are
+// left out. Failing to record is logged and never stops the page.
+func (cfg *apiConfig) recordPageView(w http.ResponseWriter, r *http.Request, path string) {
+	if cfg.analytics == nil || cfg.viewerID(r) != 0 {
+		return
+	}
+
+	siteHost := r.Host
+	if u, err := url.Parse(cfg.siteURL(r)); err == nil {
+		siteHost = u.Host
+	}
+	if _, err := cfg.analytics.Record(r.Context(), r, path, siteHost); err != nil {
+		requestLogger(w).Warn("failed to record page view", "path", path, "error", err)
+	}
+}
+
+// runAnalyticsRollup rolls up page views every interval until ctx is done.
+func (cfg *apiConfig) runAnalyticsRollup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := cfg.analytics.Rollup(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to roll up page views", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DailyStats is one day of traffic. Visitors are unique for the day.
+type DailyStats struct {
+	Date     string `json:"date"`
+	Views    int    `json:"views"`
+	Visitors int    `json:"visitors"`
+}
+
+type PageStats struct {
+	Path     string `json:"path"`
+	Title    string `json:"title,omitempty"`
+	Views    int    `json:"views"`
+	Visitors int    `json:"visitors"`
+}
+
+type ReferrerStats struct {
+	Referrer string `json:"referrer"`
+	Views    int    `json:"views"`
+	Visitors int    `json:"visitors"`
+}
+
+// StatsResponse covers the last Days days, today included. Visitors are
+// counted once a day, so the totals count someone who came back on
+// another day twice.
+type StatsResponse struct {
+	Days         int             `json:"days"`
+	Views        int             `json:"views"`
+	Visitors     int             `json:"visitors"`
+	Daily        []DailyStats    `json:"daily"`
+	TopPages     []PageStats     `json:"top_pages"`
+	TopReferrers []ReferrerStats `json:"top_referrers"`
+}
+
+// getStats returns page views over time, the most viewed pages and the
+// sites that send the most visitors.
+func (cfg *apiConfig) getStats(w http.ResponseWriter, r *http.Request) {
+	days := defaultStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxStatsDays {
+			respondWithError(w, http.StatusBadRequest, "days must be between 1 and 365", err)
+			return
+		}
+		days = n
+	}
+
+	// Include views from the last few minutes
+	if _, err := cfg.analytics.Rollup(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to roll up page views", err)
+		return
+	}
+
+	today, err := time.Parse(analytics.DayFormat, cfg.analytics.Today())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get stats", err)
+		return
+	}
+	first := today.AddDate(0, 0, 1-days)
+	since := first.Format(analytics.DayFormat)
+
+	daily, err := cfg.DB.GetDailyViews(r.Context(), since)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get daily views", err)
+		return
+	}
+	pages, err := cfg.DB.GetTopPages(r.Context(), database.GetTopPagesParams{Day: since, Limit: statsTopLimit})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get top pages", err)
+		return
+	}
+	referrers, err := cfg.DB.GetTopReferrers(r.Context(), database.GetTopReferrersParams{Day: since, Limit: statsTopLimit})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get top referrers", err)
+		return
+	}
+
+	resp := StatsResponse{
+		Days:         days,
+		Daily:        make([]DailyStats, 0, days),
+		TopPages:     []PageStats{},
+		TopReferrers: []ReferrerStats{},
+	}
+
+	// Days without views are filled in so the chart has no gaps
+	byDay := make(map[string]database.GetDailyViewsRow, len(daily))
+	for _, d := range daily {
+		byDay[d.Day] = d
+	}
+	for i := range days {
+		date := first.AddDate(0, 0, i).Format(analytics.DayFormat)
+		d := byDay[date]
+		resp.Daily = append(resp.Daily, DailyStats{Date: date, Views: int(d.Views), Visitors: int(d.Visitors)})
+		resp.Views += int(d.Views)
+		resp.Visitors += int(d.Visitors)
+	}
+
+	for _, p := range pages {
+		resp.TopPages = append(resp.TopPages, PageStats{
+			Path:     p.Path,
+			Title:    cfg.pageTitle(r.Context(), p.Path),
+			Views:    int(p.Views),
+			Visitors: int(p.Visitors),
+		})
+	}
+	for _, ref := range referrers {
+		resp.TopReferrers = append(resp.TopReferrers, ReferrerStats{
+			Referrer: ref.Referrer,
+			Views:    int(ref.Views),
+			Visitors: int(ref.Visitors),
+		})
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// pageTitle names a recorded path for the dashboard, or returns "" if the
+// page no longer exists.
+func (cfg *apiConfig) pageTitle(ctx context.Context, path string) string {
+	if path == "/" {
+		return "Home"
+	}
+
+	kind, idStr, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if !ok || err != nil {
+		return ""
+	}
+
+	switch kind {
+	case "journals":
+		if journal, err := cfg.DB.GetJournalEntry(ctx, id); err == nil {
+			return journal.Title
+		}
+	case "projects":
+		if project, err := cfg.DB.GetProject(ctx, id); err == nil {
+			return project.Title
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/analytics"
+	"github.com/sianwa11/my-journal/internal/auth"
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestStats(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	apiCfg.baseURL = "https://journal.example"
+	apiCfg.analytics = analytics.NewRecorder(apiCfg.DB)
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "owner", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Most read", Content: "content", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journalPath := fmt.Sprintf("/journals/%d", journal.ID)
+
+	view := func(path, ip, userAgent, referer, token string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":4000"
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Referer", referer)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		apiCfg.recordPageView(httptest.NewRecorder(), req, path)
+	}
+
+	view(journalPath, "203.0.113.7", "Mozilla/5.0 Firefox/131.0", "https://news.ycombinator.com/item?id=1", "")
+	view(journalPath, "203.0.113.7", "Mozilla/5.0 Firefox/131.0", "https://journal.example/", "")
+	view(journalPath, "198.51.100.2", "Mozilla/5.0 Safari/604.1", "", "")
+	view("/", "198.51.100.2", "Mozilla/5.0 Safari/604.1", "", "")
+	view("/", "198.51.100.9", "Googlebot/2.1", "", "")
+
+	// The owner reading their own site isn't counted
+	token, err := auth.MakeJWT(int(user.ID), apiCfg.jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view(journalPath, "192.0.2.1", "Mozilla/5.0 Firefox/131.0", "", token)
+
+	// Views from earlier days have already been rolled up
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(analytics.DayFormat)
+	if _, err := db.Exec("INSERT INTO page_view_rollups (day, dimension, value, views, visitors) VALUES (?, 'site', '', 5, 3), (?, 'page', '/projects/9', 5, 3)", yesterday, yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		rr := httptest.NewRecorder()
+		apiCfg.getStats(rr, req)
+		return rr
+	}
+
+	rr := call("/api/stats?days=7")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var stats StatsResponse
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats.Daily) != 7 || stats.Daily[6].Views != 4 || stats.Daily[6].Visitors != 2 || stats.Daily[5].Views != 5 || stats.Daily[0].Views != 0 {
+		t.Errorf("Unexpected daily views %+v", stats.Daily)
+	}
+	if stats.Daily[5].Date != yesterday {
+		t.Errorf("Expected the chart to end today, got %s before today", stats.Daily[5].Date)
+	}
+	if stats.Views != 9 || stats.Visitors != 5 {
+		t.Errorf("Expected 9 views by 5 daily visitors, got %d by %d", stats.Views, stats.Visitors)
+	}
+
+	wantPages := []PageStats{
+		{Path: "/projects/9", Views: 5, Visitors: 3},
+		{Path: journalPath, Title: "Most read", Views: 3, Visitors: 2},
+		{Path: "/", Title: "Home", Views: 1, Visitors: 1},
+	}
+	if len(stats.TopPages) != len(wantPages) {
+		t.Fatalf("Unexpected top pages %+v", stats.TopPages)
+	}
+	for i, want := range wantPages {
+		if stats.TopPages[i] != want {
+			t.Errorf("Top page %d = %+v, want %+v", i, stats.TopPages[i], want)
+		}
+	}
+	if len(stats.TopReferrers) != 1 || stats.TopReferrers[0].Referrer != "news.ycombinator.com" {
+		t.Errorf("Expected only the external referrer, got %+v", stats.TopReferrers)
+	}
+
+	// A shorter range leaves out older days
+	rr = call("/api/stats?days=1")
+	json.NewDecoder(rr.Body).Decode(&stats)
+	if stats.Views != 4 || len(stats.TopPages) != 2 {
+		t.Errorf("Expected only today's views, got %d views on %+v", stats.Views, stats.TopPages)
+	}
+
+	for _, days := range []string{"0", "366", "week"} {
+		if rr := call("/api/stats?days=" + days); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected days=%s to return 400, got %d", days, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+const (
+	// defaultTrashRetention applies when TRASH_RETENTION_DAYS is unset.
+	defaultTrashRetention = 30 * 24 * time.Hour
+
+	// trashPurgeInterval is how often the purger looks for expired items.
+	trashPurgeInterval = time.Hour
+)
+
+const (
+	trashJournals = "journals"
+	trashProjects = "projects"
+)
+
+// TrashItem is a deleted journal entry or project awaiting purge.
+type TrashItem struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+type TrashResponse struct {
+	Journals      []TrashItem `json:"journals"`
+	Projects      []TrashItem `json:"projects"`
+	RetentionDays int         `json:"retention_days"`
+}
+
+// trashRetentionFromEnv reads TRASH_RETENTION_DAYS. Zero keeps trashed
+// items until they are purged by hand.
+func trashRetentionFromEnv(value string) time.Duration {
+	if value == "" {
+		return defaultTrashRetention
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		slog.Warn("invalid TRASH_RETENTION_DAYS, using default", "value", value)
+		return defaultTrashRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (cfg *apiConfig) trashItem(id int64, title string, deletedAt sql.NullTime) TrashItem {
+	item := TrashItem{
+		ID:        int(id),
+		Title:     title,
+		DeletedAt: deletedAt.Time,
+	}
+	if cfg.trashRetention > 0 {
+		purgeAt := deletedAt.Time.Add(cfg.trashRetention)
+		item.PurgeAt = &purgeAt
+	}
+	return item
+}
+
+func (cfg *apiConfig) getTrash(w http.ResponseWriter, r *http.Request) {
+	journals, err := cfg.DB.ListTrashedJournals(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list trashed journals", err)
+		return
+	}
+
+	projects, err := cfg.DB.ListTrashedProjects(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list trashed projects", err)
+		return
+	}
+
+	resp := TrashResponse{
+		Journals:      []TrashItem{},
+		Projects:      []TrashItem{},
+		RetentionDays: int(cfg.trashRetention / (24 * time.Hour)),
+	}
+	for _, j := range journals {
+		resp.Journals = append(resp.Journals, cfg.trashItem(j.ID, j.Title, j.DeletedAt))
+	}
+	for _, p := range projects {
+		resp.Projects = append(resp.Projects, cfg.trashItem(p.ID, p.Title, p.DeletedAt))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// trashTarget reads the {kind} and {id} path segments shared by the restore
+// and purge endpoints.
+func trashTarget(w http.ResponseWriter, r *http.Request) (string, int64, bool) {
+	kind := r.PathValue("kind")
+	if kind != trashJournals && kind != trashProjects {
+		respondWithError(w, http.StatusNotFound, "unknown trash type", nil)
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid ID", err)
+		return "", 0, false
+	}
+
+	return kind, int64(id), true
+}
+
+func (cfg *apiConfig) restoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := trashTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var restored int64
+	var err error
+	if kind == trashJournals {
+		restored, err = cfg.DB.RestoreJournalEntry(r.Context(), id)
+	} else {
+		restored, err = cfg.DB.RestoreProject(r.Context(), id)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to restore item", err)
+		return
+	}
+	if restored == 0 {
+		respondWithError(w, http.StatusNotFound, "item not found in trash", nil)
+		return
+	}
+	if kind == trashJournals {
+		if journal, err := cfg.DB.GetJournalEntry(r.Context(), id); err == nil {
+			cfg.journalChanged(r, database.JournalEntry{}, journal)
+		}
+	} else if project, err := cfg.DB.GetProject(r.Context(), id); err == nil {
+		cfg.emitProjectEvent(r, eventProjectCreated, project.ProjectID, project.Title)
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{
+		"message": "item restored successfully",
+	})
+}
+
+func (cfg *apiConfig) purgeFromTrash(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := trashTarget(w, r)
+	if !ok {
+		return
+	}
+
+	purge := cfg.purgeJournal
+	if kind == trashProjects {
+		purge = cfg.purgeProject
+	}
+
+	purged, err := purge(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to purge item", err)
+		return
+	}
+	if !purged {
+		respondWithError(w, http.StatusNotFound, "item not found in trash", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// purgeJournal permanently deletes a trashed entry along with its series
+// membership, tags, comments and webmentions. It reports false if the
+// entry wasn't in the trash.
+func (cfg *apiConfig) purgeJournal(ctx context.Context, id int64) (bool, error) {
+	return cfg.purgeInTx(ctx, func(qtx *database.Queries) (int64, error) {
+		n, err := qtx.PurgeJournalEntry(ctx, id)
+		if err != nil || n == 0 {
+			return n, err
+		}
+		if err := qtx.DeleteJournalComments(ctx, id); err != nil {
+			return n, err
+		}
+		if err := qtx.DeleteJournalWebmentions(ctx, id); err != nil {
+			return n, err
+		}
+		if err := qtx.DeleteJournalTags(ctx, id); err != nil {
+			return n, err
+		}
+		return n, qtx.RemoveJournalFromSeries(ctx, id)
+	})
+}
+
+// purgeProject permanently deletes a trashed project along with its tag
+// links. It reports false if the project wasn't in the trash.
+func (cfg *apiConfig) purgeProject(ctx context.Context, id int64) (bool, error) {
+	return cfg.purgeInTx(ctx, func(qtx *database.Queries) (int64, error) {
+		n, err := qtx.PurgeProject(ctx, id)
+		if err != nil || n == 0 {
+			return n, err
+		}
+		return n, qtx.DeleteProjectTag(ctx, id)
+	})
+}
+
+// purgeInTx runs purge in a transaction. Foreign keys aren't enforced on
+// every connection, so dependent rows are removed explicitly rather than
+// left to the cascade.
+func (cfg *apiConfig) purgeInTx(ctx context.Context, purge func(*database.Queries) (int64, error)) (bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	n, err := purge(cfg.DB.WithTx(tx))
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// purgeExpiredTrash permanently deletes everything trashed before now minus
+// the retention period, returning how many items went.
+func (cfg *apiConfig) purgeExpiredTrash(ctx context.Context, now time.Time) (int, error) {
+	cutoff := sqliteTimestamp(now.Add(-cfg.trashRetention))
+
+	journals, err := cfg.DB.ListJournalsTrashedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	projects, err := cfg.DB.ListProjectsTrashedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range journals {
+		ok, err := cfg.purgeJournal(ctx, id)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+	for _, id := range projects {
+		ok, err := cfg.purgeProject(ctx, id)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// runTrashPurger purges expired trash every interval until ctx is done.
+// It does nothing when retention is disabled.
+func (cfg *apiConfig) runTrashPurger(ctx context.Context, interval time.Duration) {
+	if cfg.trashRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := cfg.purgeExpiredTrash(ctx, time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to purge expired trash", "error", err)
+		} else if purged > 0 {
+			slog.InfoContext(ctx, "purged expired trash", "items", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestTrashLifecycle(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.trashRetention = 30 * 24 * time.Hour
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "trashuser", "password")
+
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Doomed", Content: "content", UserID: user.ID, Format: "html", Visibility: "public",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := apiCfg.DB.CreateProject(ctx, database.CreateProjectParams{
+		Title: "Old project", Description: "description", UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(handler http.HandlerFunc, method string, values map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	journalID := strconv.FormatInt(journal.ID, 10)
+	projectID := strconv.FormatInt(project.ID, 10)
+
+	if rr := call(apiCfg.deleteJournalEntry, "DELETE", map[string]string{"journalID": journalID}); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected journal delete to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := call(apiCfg.deleteProject, "DELETE", map[string]string{"projectID": projectID}); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected project delete to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if _, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID); err == nil {
+		t.Error("Expected a trashed journal to be hidden")
+	}
+	if count, _ := apiCfg.DB.GetProjectsCount(ctx); count != 0 {
+		t.Errorf("Expected trashed projects to be excluded from counts, got %d", count)
+	}
+
+	rr := call(apiCfg.getTrash, "GET", nil)
+	var trash TrashResponse
+	if err := json.NewDecoder(rr.Body).Decode(&trash); err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Journals) != 1 || len(trash.Projects) != 1 || trash.RetentionDays != 30 {
+		t.Fatalf("Unexpected trash listing: %+v", trash)
+	}
+	if trash.Journals[0].PurgeAt == nil || trash.Journals[0].PurgeAt.Sub(trash.Journals[0].DeletedAt) != apiCfg.trashRetention {
+		t.Errorf("Expected purge_at to be deleted_at plus retention, got %+v", trash.Journals[0])
+	}
+
+	rr = call(apiCfg.restoreFromTrash, "POST", map[string]string{"kind": "journals", "id": journalID})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected restore to return 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID); err != nil {
+		t.Errorf("Expected the restored journal to be visible: %v", err)
+	}
+
+	rr = call(apiCfg.restoreFromTrash, "POST", map[string]string{"kind": "journals", "id": journalID})
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected restoring a live journal to return 404, got %d", rr.Code)
+	}
+
+	rr = call(apiCfg.purgeFromTrash, "DELETE", map[string]string{"kind": "journals", "id": journalID})
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected purging a live journal to return 404, got %d", rr.Code)
+	}
+
+	rr = call(apiCfg.purgeFromTrash, "DELETE", map[string]string{"kind": "widgets", "id": "1"})
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown kind to return 404, got %d", rr.Code)
+	}
+
+	rr = call(apiCfg.purgeFromTrash, "DELETE", map[string]string{"kind": "projects", "id": projectID})
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected purge to return 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var remaining int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM projects`).Scan(&remaining); err != nil || remaining != 0 {
+		t.Errorf("Expected the purged project to be gone, %d left (%v)", remaining, err)
+	}
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.trashRetention = 30 * 24 * time.Hour
+
+	user := createTestUser(t, apiCfg.DB, "purgeuser", "password")
+
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	for title, deletedAt := range map[string]interface{}{
+		"live":    nil,
+		"recent":  sqliteTimestamp(now.AddDate(0, 0, -29)),
+		"expired": sqliteTimestamp(now.AddDate(0, 0, -31)),
+	} {
+		_, err := db.Exec(`INSERT INTO journal_entries (title, content, user_id, deleted_at) VALUES (?, 'content', ?, ?)`,
+			title, user.ID, deletedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := apiCfg.purgeExpiredTrash(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged entry, got %d", purged)
+	}
+
+	rows, err := db.Query(`SELECT title FROM journal_entries ORDER BY title`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, title)
+	}
+	if len(titles) != 2 || titles[0] != "live" || titles[1] != "recent" {
+		t.Errorf("Expected live and recent entries to remain, got %v", titles)
+	}
+}
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/auth"
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+func TestJournalVisibility(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	owner := createTestUser(t, apiCfg.DB, "owner", "password")
+
+	create := func(title, visibility string) database.JournalEntry {
+		token := shareTokenFor(visibility, "")
+		journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+			Title: title, Content: "content", UserID: owner.ID, Format: "html",
+			Visibility: visibility,
+			ShareToken: sql.NullString{String: token, Valid: token != ""},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return journal
+	}
+	public := create("Public", visibilityPublic)
+	unlisted := create("Unlisted", visibilityUnlisted)
+	private := create("Private", visibilityPrivate)
+
+	ownerToken, err := auth.MakeJWT(int(owner.ID), apiCfg.jwtSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type listResponse struct {
+		Journals []Journal `json:"journals"`
+		Total    int       `json:"total"`
+	}
+	list := func(bearer string) listResponse {
+		req := httptest.NewRequest("GET", "/api/journals", nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rr := httptest.NewRecorder()
+		apiCfg.getJournalEntries(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp listResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := list(""); len(resp.Journals) != 1 || resp.Journals[0].ID != int(public.ID) || resp.Total != 1 {
+		t.Errorf("Expected anonymous visitors to see only the public entry, got %+v", resp)
+	}
+	if resp := list(ownerToken); len(resp.Journals) != 3 || resp.Total != 3 {
+		t.Errorf("Expected the owner to see all three entries, got %+v", resp)
+	}
+
+	get := func(id int64, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/journals/"+strconv.FormatInt(id, 10), nil)
+		req.SetPathValue("journalID", strconv.FormatInt(id, 10))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rr := httptest.NewRecorder()
+		apiCfg.getJournalEntry(rr, req)
+		return rr
+	}
+
+	for _, journal := range []database.JournalEntry{unlisted, private} {
+		if rr := get(journal.ID, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected %s entry to be hidden from anonymous visitors, got %d", journal.Visibility, rr.Code)
+		}
+		rr := get(journal.ID, ownerToken)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected owner to open %s entry, got %d", journal.Visibility, rr.Code)
+		}
+		if journal.Visibility == visibilityUnlisted && !strings.Contains(rr.Body.String(), sharePath(journal.ShareToken.String)) {
+			t.Errorf("Expected owner to see the share link, got %s", rr.Body.String())
+		}
+	}
+
+	if _, err := apiCfg.DB.GetJournalByShareToken(ctx, unlisted.ShareToken); err != nil {
+		t.Errorf("Expected the share token to open the unlisted entry: %v", err)
+	}
+	if private.ShareToken.Valid {
+		t.Error("Expected private entries to have no share token")
+	}
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(owner.ID)))
+	req.SetPathValue("journalID", strconv.FormatInt(unlisted.ID, 10))
+	rr := httptest.NewRecorder()
+	apiCfg.rotateShareToken(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected rotation to return 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, err := apiCfg.DB.GetJournalByShareToken(ctx, unlisted.ShareToken); err == nil {
+		t.Error("Expected the old share token to stop working after rotation")
+	}
+}
+
+func TestEditKeepsVisibility(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	owner := createTestUser(t, apiCfg.DB, "owner", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Private", Content: "content", UserID: owner.ID, Format: "html", Visibility: visibilityPrivate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit := func(body string) {
+		t.Helper()
+		req := httptest.NewRequest("PUT", "/api/journals", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(owner.ID)))
+		rr := httptest.NewRecorder()
+		apiCfg.editJournalEntry(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected the edit to succeed, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	// A client that doesn't know about visibility leaves it alone
+	edit(`{"id": ` + strconv.FormatInt(journal.ID, 10) + `, "title": "Still private", "content": "new content"}`)
+	edited, err := apiCfg.DB.GetJournalEntry(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Title != "Still private" || edited.Visibility != visibilityPrivate {
+		t.Errorf("Expected the entry to stay private, got %q %s", edited.Title, edited.Visibility)
+	}
+
+	edit(`{"id": ` + strconv.FormatInt(journal.ID, 10) + `, "title": "Published", "content": "new content", "visibility": "public"}`)
+	if edited, _ := apiCfg.DB.GetJournalEntry(ctx, journal.ID); edited.Visibility != visibilityPublic {
+		t.Errorf("Expected an explicit visibility to be applied, got %s", edited.Visibility)
+	}
+}
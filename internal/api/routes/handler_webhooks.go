@@ -0,0 +1,605 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/webhook"
+)
+
+// Events a webhook can subscribe to. Trashing counts as deleted and
+// restoring from the trash as created.
+const (
+	eventJournalCreated = "journal.created"
+	eventJournalUpdated = "journal.updated"
+	eventJournalDeleted = "journal.deleted"
+	eventProjectCreated = "project.created"
+	eventProjectUpdated = "project.updated"
+	eventProjectDeleted = "project.deleted"
+	eventCommentCreated = "comment.created"
+	eventCommentUpdated = "comment.updated"
+	eventCommentDeleted = "comment.deleted"
+)
+
+var webhookEvents = []string{
+	eventJournalCreated, eventJournalUpdated, eventJournalDeleted,
+	eventProjectCreated, eventProjectUpdated, eventProjectDeleted,
+	eventCommentCreated, eventCommentUpdated, eventCommentDeleted,
+}
+
+// Webhook delivery states.
+const (
+	webhookPending   = "pending"
+	webhookDelivered = "delivered"
+	webhookFailed    = "failed"
+)
+
+const (
+	// maxWebhookAttempts is how many times a payload is sent before the
+	// delivery is marked failed. With the queue's backoff that spans
+	// about four hours.
+	maxWebhookAttempts = 11
+
+	// webhookLogRetention is how long finished deliveries stay in the
+	// log, and webhookPruneInterval how often older ones are removed.
+	webhookLogRetention  = 30 * 24 * time.Hour
+	webhookPruneInterval = time.Hour
+
+	maxWebhookURLLength         = 2048
+	maxWebhookDescriptionLength = 200
+
+	webhookUserAgent = "my-journal-webhook/1.0"
+)
+
+// Webhook is a registered hook as the admin API shows it. The secret is
+// only included when the hook is created.
+type Webhook struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one entry in a hook's delivery log.
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// webhookPayload is the JSON body every hook receives.
+type webhookPayload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type webhookJournal struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	Visibility string `json:"visibility"`
+	URL        string `json:"url"`
+}
+
+type webhookProject struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+}
+
+type webhookComment struct {
+	ID        int64  `json:"id"`
+	JournalID int64  `json:"journal_id,omitempty"`
+	ParentID  int64  `json:"parent_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	IsOwner   bool   `json:"is_owner,omitempty"`
+}
+
+func webhookFromDB(h database.Webhook) Webhook {
+	return Webhook{
+		ID:          int(h.ID),
+		URL:         h.Url,
+		Events:      webhookFilters(h.Events),
+		Description: h.Description,
+		Active:      h.Active,
+		CreatedAt:   h.CreatedAt.Time,
+		UpdatedAt:   h.UpdatedAt.Time,
+	}
+}
+
+// webhookFilters decodes the stored events column.
+func webhookFilters(events string) []string {
+	var filters []string
+	if err := json.Unmarshal([]byte(events), &filters); err != nil || filters == nil {
+		return []string{}
+	}
+	return filters
+}
+
+// validWebhookFilter accepts a known event, a known group's wildcard such
+// as "journal.*", or "*".
+func validWebhookFilter(filter string) bool {
+	if filter == "*" {
+		return true
+	}
+	for _, event := range webhookEvents {
+		group, _, _ := strings.Cut(event, ".")
+		if filter == event || filter == group+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+type webhookParams struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// validate checks the params and returns the events column to store, or
+// a message for the client.
+func (p *webhookParams) validate() (string, string) {
+	p.URL = strings.TrimSpace(p.URL)
+	p.Description = strings.TrimSpace(p.Description)
+
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(p.URL) > maxWebhookURLLength {
+		return "", "url must be an http or https URL"
+	}
+	if len(p.Events) == 0 {
+		return "", "events must list at least one event"
+	}
+	for _, event := range p.Events {
+		if !validWebhookFilter(event) {
+			return "", fmt.Sprintf("unknown event %q", event)
+		}
+	}
+	if len(p.Description) > maxWebhookDescriptionLength {
+		return "", fmt.Sprintf("description must be at most %d characters", maxWebhookDescriptionLength)
+	}
+
+	events, err := json.Marshal(p.Events)
+	if err != nil {
+		return "", "invalid events"
+	}
+	return string(events), ""
+}
+
+func webhookIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.Atoi(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid webhook ID", err)
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// getWebhooks lists every registered hook.
+func (cfg *apiConfig) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := cfg.DB.ListWebhooks(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list webhooks", err)
+		return
+	}
+
+	resp := []Webhook{}
+	for _, h := range hooks {
+		resp = append(resp, webhookFromDB(h))
+	}
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// createWebhook registers a hook and returns it with its signing secret,
+// which is not shown again.
+func (cfg *apiConfig) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var params webhookParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+	events, msg := params.validate()
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+
+	hook, err := cfg.DB.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		Url:         params.URL,
+		Secret:      rand.Text(),
+		Events:      events,
+		Description: params.Description,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to create webhook", err)
+		return
+	}
+
+	resp := webhookFromDB(hook)
+	resp.Secret = hook.Secret
+	respondWithJson(w, http.StatusCreated, resp)
+}
+
+// updateWebhook changes a hook's URL, events, description or active flag.
+// The secret stays the same.
+func (cfg *apiConfig) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var params webhookParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON format", err)
+		return
+	}
+	events, msg := params.validate()
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+	active := params.Active == nil || *params.Active
+
+	n, err := cfg.DB.UpdateWebhook(r.Context(), database.UpdateWebhookParams{
+		Url:         params.URL,
+		Events:      events,
+		Description: params.Description,
+		Active:      active,
+		ID:          id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to update webhook", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+		return
+	}
+
+	hook, err := cfg.DB.GetWebhook(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to get webhook", err)
+		return
+	}
+	respondWithJson(w, http.StatusOK, webhookFromDB(hook))
+}
+
+// deleteWebhook removes a hook along with its delivery log.
+func (cfg *apiConfig) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	n, err := qtx.DeleteWebhook(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete webhook", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+		return
+	}
+	if err := qtx.DeleteWebhookDeliveries(r.Context(), id); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete webhook deliveries", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to commit transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhookDeliveries pages through a hook's delivery log, newest first.
+func (cfg *apiConfig) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "invalid limit parameter", err)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset parameter", err)
+			return
+		}
+		offset = n
+	}
+
+	if _, err := cfg.DB.GetWebhook(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "webhook not found", nil)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "failed to get webhook", err)
+		return
+	}
+
+	total, err := cfg.DB.CountWebhookDeliveries(r.Context(), id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count deliveries", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		WebhookID: id,
+		Limit:     int64(limit),
+		Offset:    int64(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list deliveries", err)
+		return
+	}
+
+	resp := WebhookDeliveriesResponse{
+		Deliveries: []WebhookDelivery{},
+		Total:      int(total),
+		Page:       offset/limit + 1,
+		Limit:      limit,
+		HasMore:    offset+len(rows) < int(total),
+	}
+	for _, row := range rows {
+		delivery := WebhookDelivery{
+			ID:             int(row.ID),
+			Event:          row.Event,
+			Status:         row.Status,
+			Attempts:       int(row.Attempts),
+			ResponseStatus: int(row.ResponseStatus),
+			ResponseBody:   row.ResponseBody,
+			LastError:      row.LastError,
+			CreatedAt:      row.CreatedAt.Time,
+			Payload:        json.RawMessage(row.Payload),
+		}
+		if row.Status == webhookPending && row.NextAttemptAt.Valid {
+			delivery.NextAttemptAt = &row.NextAttemptAt.Time
+		}
+		if row.DeliveredAt.Valid {
+			delivery.DeliveredAt = &row.DeliveredAt.Time
+		}
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// emitEvent queues event for every active hook subscribed to it. The
+// change it describes has already happened, so failures are only logged.
+func (cfg *apiConfig) emitEvent(ctx context.Context, event string, data any) {
+	if err := cfg.queueWebhooks(ctx, event, data); err != nil {
+		slog.ErrorContext(ctx, "failed to queue webhooks", "event", event, "error", err)
+	}
+}
+
+func (cfg *apiConfig) queueWebhooks(ctx context.Context, event string, data any) error {
+	hooks, err := cfg.DB.ListActiveWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var subscribed []database.Webhook
+	for _, h := range hooks {
+		if webhook.Matches(webhookFilters(h.Events), event) {
+			subscribed = append(subscribed, h)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		Event:      event,
+		OccurredAt: time.Now().UTC().Truncate(time.Second),
+		Data:       data,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	for _, h := range subscribed {
+		id, err := qtx.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			WebhookID: h.ID,
+			Event:     event,
+			Payload:   string(payload),
+		})
+		if err != nil {
+			return err
+		}
+		jobID, err := cfg.jobKinds.deliverWebhook.EnqueueTx(ctx, qtx, webhookJob{DeliveryID: id}, jobs.MaxAttempts(maxWebhookAttempts))
+		if err != nil {
+			return err
+		}
+		err = qtx.SetWebhookDeliveryJob(ctx, database.SetWebhookDeliveryJobParams{
+			JobID: sql.NullInt64{Int64: jobID, Valid: true},
+			ID:    id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.jobs.Wake()
+	return nil
+}
+
+// emitJournalEvent reports a journal change in the same terms as
+// journalChanged: the zero JournalEntry stands for one that didn't exist
+// or is in the trash.
+func (cfg *apiConfig) emitJournalEvent(r *http.Request, before, after database.JournalEntry) {
+	event, journal := eventJournalUpdated, after
+	switch {
+	case before.ID == 0 && after.ID == 0:
+		return
+	case before.ID == 0:
+		event = eventJournalCreated
+	case after.ID == 0:
+		event, journal = eventJournalDeleted, before
+	}
+
+	cfg.emitEvent(r.Context(), event, webhookJournal{
+		ID:         journal.ID,
+		Title:      journal.Title,
+		Visibility: journal.Visibility,
+		URL:        cfg.absoluteURL(r, fmt.Sprintf("/journals/%d", journal.ID)),
+	})
+}
+
+func (cfg *apiConfig) emitProjectEvent(r *http.Request, event string, id int64, title string) {
+	cfg.emitEvent(r.Context(), event, webhookProject{
+		ID:    id,
+		Title: title,
+		URL:   cfg.absoluteURL(r, fmt.Sprintf("/projects/%d", id)),
+	})
+}
+
+func (cfg *apiConfig) emitCommentEvent(r *http.Request, event string, c database.Comment) {
+	cfg.emitEvent(r.Context(), event, webhookComment{
+		ID:        c.ID,
+		JournalID: c.JournalID,
+		ParentID:  c.ParentID.Int64,
+		Status:    c.Status,
+		IsOwner:   c.IsOwner,
+	})
+}
+
+// runWebhookLogPruner drops finished deliveries older than the log keeps
+// every interval until ctx is done.
+func (cfg *apiConfig) runWebhookLogPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := sqliteTimestamp(time.Now().Add(-webhookLogRetention))
+		if _, err := cfg.DB.DeleteOldWebhookDeliveries(ctx, cutoff); err != nil {
+			slog.ErrorContext(ctx, "failed to prune webhook deliveries", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webhookJob asks for a delivery in the log to be sent.
+type webhookJob struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// runDeliverWebhook sends one queued payload and records the attempt in
+// the delivery log, marking it failed on the job's last attempt. A
+// delivery that is gone, because its hook was deleted, or that already
+// went through has nothing left to do.
+func (cfg *apiConfig) runDeliverWebhook(ctx context.Context, job webhookJob) error {
+	delivery, err := cfg.DB.GetWebhookDeliveryToSend(ctx, job.DeliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status == webhookDelivered {
+		return nil
+	}
+
+	resp, sendErr := cfg.webhooks.Send(ctx, webhook.Delivery{
+		ID:      delivery.ID,
+		URL:     delivery.Url,
+		Secret:  delivery.Secret,
+		Event:   delivery.Event,
+		Payload: []byte(delivery.Payload),
+	})
+	if sendErr == nil {
+		return cfg.DB.MarkWebhookDelivered(ctx, database.MarkWebhookDeliveredParams{
+			ResponseStatus: int64(resp.Status),
+			ResponseBody:   resp.Body,
+			ID:             delivery.ID,
+		})
+	}
+
+	lastError := truncate(sendErr.Error(), 500, "…")
+	if attempt, limit, _ := jobs.Attempt(ctx); attempt >= limit {
+		slog.WarnContext(ctx, "gave up delivering webhook", "url", delivery.Url, "event", delivery.Event, "error", sendErr)
+		err = cfg.DB.FailWebhookDelivery(ctx, database.FailWebhookDeliveryParams{
+			ResponseStatus: int64(resp.Status),
+			ResponseBody:   resp.Body,
+			LastError:      lastError,
+			ID:             delivery.ID,
+		})
+	} else {
+		err = cfg.DB.RetryWebhookDelivery(ctx, database.RetryWebhookDeliveryParams{
+			ResponseStatus: int64(resp.Status),
+			ResponseBody:   resp.Body,
+			LastError:      lastError,
+			ID:             delivery.ID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,258 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/webhook"
+)
+
+// hookReceiver is a stub webhook endpoint that checks signatures and
+// records the events it receives.
+type hookReceiver struct {
+	*httptest.Server
+	secret string
+
+	mu     sync.Mutex
+	events []string
+	status int
+}
+
+func newHookReceiver(t *testing.T) *hookReceiver {
+	t.Helper()
+
+	receiver := &hookReceiver{status: http.StatusOK}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		if err := webhook.Verify(receiver.secret, r.Header, body, time.Now(), time.Minute); err != nil {
+			t.Errorf("Delivery did not verify: %v", err)
+		}
+
+		var payload webhookPayload
+		json.Unmarshal(body, &payload)
+		if payload.Event != r.Header.Get(webhook.HeaderEvent) {
+			t.Errorf("Payload event %q does not match header %q", payload.Event, r.Header.Get(webhook.HeaderEvent))
+		}
+		receiver.events = append(receiver.events, payload.Event)
+		w.WriteHeader(receiver.status)
+		io.WriteString(w, "thanks")
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (h *hookReceiver) take() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	events := h.events
+	h.events = nil
+	return events
+}
+
+func (h *hookReceiver) setStatus(status int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status = status
+}
+
+func TestWebhookAPI(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	user := createTestUser(t, apiCfg.DB, "owner", "password")
+	call := func(handler http.HandlerFunc, method, target string, body any, values map[string]string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, target, &buf)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, int(user.ID)))
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	invalid := []map[string]any{
+		{"url": "ftp://example.com/hook", "events": []string{"*"}},
+		{"url": "https://", "events": []string{"*"}},
+		{"url": "https://example.com/hook"},
+		{"url": "https://example.com/hook", "events": []string{"journal.published"}},
+		{"url": "https://example.com/hook", "events": []string{"tags.*"}},
+	}
+	for _, body := range invalid {
+		if rr := call(apiCfg.createWebhook, "POST", "/api/webhooks", body, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected %v to return 400, got %d", body, rr.Code)
+		}
+	}
+
+	rr := call(apiCfg.createWebhook, "POST", "/api/webhooks", map[string]any{
+		"url":         "https://example.com/hook",
+		"events":      []string{"journal.*", "comment.created"},
+		"description": "Rebuild the site",
+	}, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected create to return 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created Webhook
+	json.NewDecoder(rr.Body).Decode(&created)
+	if created.Secret == "" || !created.Active || len(created.Events) != 2 {
+		t.Fatalf("Unexpected webhook: %+v", created)
+	}
+	id := map[string]string{"webhookID": strconv.Itoa(created.ID)}
+
+	// The secret is only shown once
+	rr = call(apiCfg.getWebhooks, "GET", "/api/webhooks", nil, nil)
+	var hooks []Webhook
+	json.NewDecoder(rr.Body).Decode(&hooks)
+	if len(hooks) != 1 || hooks[0].Secret != "" || hooks[0].Description != "Rebuild the site" {
+		t.Fatalf("Unexpected webhook list: %+v", hooks)
+	}
+
+	rr = call(apiCfg.updateWebhook, "PUT", "/", map[string]any{
+		"url":    "https://example.com/other",
+		"events": []string{"*"},
+		"active": false,
+	}, id)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected update to return 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var updated Webhook
+	json.NewDecoder(rr.Body).Decode(&updated)
+	if updated.URL != "https://example.com/other" || updated.Active || updated.Events[0] != "*" {
+		t.Errorf("Unexpected updated webhook: %+v", updated)
+	}
+
+	missing := map[string]string{"webhookID": "999"}
+	if rr := call(apiCfg.updateWebhook, "PUT", "/", map[string]any{"url": "https://example.com", "events": []string{"*"}}, missing); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected updating a missing webhook to return 404, got %d", rr.Code)
+	}
+	if rr := call(apiCfg.getWebhookDeliveries, "GET", "/", nil, missing); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected deliveries of a missing webhook to return 404, got %d", rr.Code)
+	}
+	if rr := call(apiCfg.getWebhookDeliveries, "GET", "/?limit=0", nil, id); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected limit=0 to return 400, got %d", rr.Code)
+	}
+
+	if rr := call(apiCfg.deleteWebhook, "DELETE", "/", nil, id); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected delete to return 204, got %d", rr.Code)
+	}
+	if rr := call(apiCfg.deleteWebhook, "DELETE", "/", nil, id); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected deleting twice to return 404, got %d", rr.Code)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	apiCfg.baseURL = "https://me.example"
+	receiver := newHookReceiver(t)
+	apiCfg.webhooks = webhook.NewClient(receiver.Client(), "")
+	apiCfg.registerJobs(jobs.New(apiCfg.DB, jobs.Options{}))
+
+	hook, err := apiCfg.DB.CreateWebhook(ctx, database.CreateWebhookParams{
+		Url:    receiver.URL,
+		Secret: "s3cret",
+		Events: `["journal.*","comment.created"]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver.secret = hook.Secret
+
+	req := httptest.NewRequest("POST", "/api/journals", nil)
+	journal := database.JournalEntry{ID: 1, Title: "Hello", Visibility: visibilityPrivate}
+	apiCfg.emitJournalEvent(req, database.JournalEntry{}, journal)
+	apiCfg.emitJournalEvent(req, journal, journal)
+	apiCfg.emitProjectEvent(req, eventProjectCreated, 1, "Not subscribed")
+	apiCfg.emitCommentEvent(req, eventCommentCreated, database.Comment{ID: 1, JournalID: 1, Status: commentPending})
+	apiCfg.emitCommentEvent(req, eventCommentDeleted, database.Comment{ID: 1})
+	apiCfg.emitJournalEvent(req, journal, database.JournalEntry{})
+
+	if attempted, err := apiCfg.jobs.RunDue(ctx); err != nil || attempted != 4 {
+		t.Fatalf("Expected 4 deliveries, got %d: %v", attempted, err)
+	}
+	got := receiver.take()
+	want := []string{eventJournalCreated, eventJournalUpdated, eventCommentCreated, eventJournalDeleted}
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected events %v, got %v", want, got)
+			break
+		}
+	}
+
+	// Failures stay pending with the response logged until retries run out
+	receiver.setStatus(http.StatusInternalServerError)
+	apiCfg.emitJournalEvent(req, journal, journal)
+	retryNow := func() {
+		t.Helper()
+		if _, err := db.Exec("UPDATE jobs SET run_at = '2000-01-01 00:00:00' WHERE status = 'pending'"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for attempt := 1; attempt < maxWebhookAttempts; attempt++ {
+		if attempted, _ := apiCfg.jobs.RunDue(ctx); attempted != 1 {
+			t.Fatalf("Expected attempt %d to be made, got %d", attempt, attempted)
+		}
+		if attempted, _ := apiCfg.jobs.RunDue(ctx); attempted != 0 {
+			t.Fatalf("Expected no attempts before the retry is due, got %d", attempted)
+		}
+		retryNow()
+	}
+	rows, err := apiCfg.DB.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{WebhookID: hook.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Status != webhookPending || rows[0].ResponseStatus != http.StatusInternalServerError || rows[0].ResponseBody != "thanks" || !rows[0].NextAttemptAt.Valid {
+		t.Fatalf("Expected a pending retry with the response logged, got %+v", rows[0])
+	}
+
+	apiCfg.jobs.RunDue(ctx)
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/?limit=2", nil)
+	req.SetPathValue("webhookID", strconv.FormatInt(hook.ID, 10))
+	apiCfg.getWebhookDeliveries(rr, req)
+
+	var log WebhookDeliveriesResponse
+	if err := json.NewDecoder(rr.Body).Decode(&log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Total != 5 || !log.HasMore || len(log.Deliveries) != 2 {
+		t.Fatalf("Unexpected delivery log: %+v", log)
+	}
+	failed, delivered := log.Deliveries[0], log.Deliveries[1]
+	if failed.Status != webhookFailed || failed.Attempts != maxWebhookAttempts || failed.LastError == "" || failed.NextAttemptAt != nil {
+		t.Errorf("Expected the newest delivery to have failed, got %+v", failed)
+	}
+	if delivered.Status != webhookDelivered || delivered.DeliveredAt == nil || delivered.Event != eventJournalDeleted {
+		t.Errorf("Expected a delivered journal.deleted, got %+v", delivered)
+	}
+	if counts, _ := apiCfg.DB.CountJobsByStatus(ctx); len(counts) != 1 || counts[0].Status != jobs.StatusDead {
+		t.Errorf("Expected only the failed delivery's dead job to be left, got %+v", counts)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(delivered.Payload, &payload); err != nil || payload.Data.(map[string]any)["url"] != "https://me.example/journals/1" {
+		t.Errorf("Unexpected payload %s", delivered.Payload)
+	}
+}
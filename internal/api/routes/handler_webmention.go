@@ -0,0 +1,353 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/webmention"
+)
+
+// Webmention verification states.
+const (
+	webmentionPending  = "pending"
+	webmentionVerified = "verified"
+	webmentionInvalid  = "invalid"
+)
+
+const (
+	// webmentionCheckInterval is how often the verifier looks for pending
+	// mentions it was not woken up for, e.g. after a restart.
+	webmentionCheckInterval = 5 * time.Minute
+
+	// webmentionBatchSize is how many pending mentions are read at a time.
+	webmentionBatchSize = 20
+
+	maxWebmentionFormBytes = 16 << 10
+	maxWebmentionURLLength = 2048
+	maxWebmentionTitle     = 200
+	maxWebmentionAuthor    = 100
+
+	webmentionUserAgent = "my-journal-webmention/1.0"
+)
+
+// Webmention is a received mention as the admin API shows it.
+type Webmention struct {
+	ID           int        `json:"id"`
+	JournalID    int        `json:"journal_id"`
+	JournalTitle string     `json:"journal_title"`
+	Source       string     `json:"source"`
+	Target       string     `json:"target"`
+	Status       string     `json:"status"`
+	Title        string     `json:"title,omitempty"`
+	AuthorName   string     `json:"author_name,omitempty"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type WebmentionsResponse struct {
+	Webmentions []Webmention `json:"webmentions"`
+	Total       int          `json:"total"`
+	Page        int          `json:"page"`
+	Limit       int          `json:"limit"`
+	HasMore     bool         `json:"has_more"`
+}
+
+// mentionView is a verified mention as view-journal.html shows it.
+type mentionView struct {
+	Source   string
+	Title    string
+	Author   string
+	Verified time.Time
+}
+
+func mentionViews(mentions []database.Webmention, loc *time.Location) []mentionView {
+	views := make([]mentionView, 0, len(mentions))
+	for _, m := range mentions {
+		view := mentionView{
+			Source:   m.Source,
+			Title:    m.Title,
+			Author:   m.AuthorName,
+			Verified: m.VerifiedAt.Time.In(loc),
+		}
+		if view.Title == "" {
+			if u, err := url.Parse(m.Source); err == nil {
+				view.Title = u.Host
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// webmentionURL parses a source or target sent to the endpoint.
+func webmentionURL(value string) (*url.URL, bool) {
+	if value == "" || len(value) > maxWebmentionURLLength {
+		return nil, false
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// handleWebmention is the Webmention receiving endpoint. It checks the
+// request and queues the mention; whether the source really links here is
+// verified in the background, as the spec recommends.
+func (cfg *apiConfig) handleWebmention(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebmentionFormBytes)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sourceValue := r.PostFormValue("source")
+	targetValue := r.PostFormValue("target")
+
+	_, ok := webmentionURL(sourceValue)
+	if !ok {
+		http.Error(w, "source must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+	target, ok := webmentionURL(targetValue)
+	if !ok {
+		http.Error(w, "target must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+	if sourceValue == targetValue {
+		http.Error(w, "source and target must differ", http.StatusBadRequest)
+		return
+	}
+
+	journalID, ok := cfg.journalIDFromURL(r, target)
+	if !ok {
+		http.Error(w, "target does not accept webmentions", http.StatusBadRequest)
+		return
+	}
+	journal, err := cfg.DB.GetJournalEntry(r.Context(), journalID)
+	if err != nil || journal.Visibility != visibilityPublic {
+		http.Error(w, "target does not accept webmentions", http.StatusBadRequest)
+		return
+	}
+
+	_, err = cfg.DB.UpsertWebmention(r.Context(), database.UpsertWebmentionParams{
+		JournalID: journal.ID,
+		Source:    sourceValue,
+		Target:    targetValue,
+	})
+	if err != nil {
+		requestLogger(w).Error("failed to save webmention", "journal_id", journal.ID, "error", err)
+		http.Error(w, "Failed to save webmention", http.StatusInternalServerError)
+		return
+	}
+	cfg.wakeWebmentionVerifier()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "Webmention accepted and queued for verification.")
+}
+
+// wakeWebmentionVerifier nudges the verifier without waiting for it. A
+// nudge already queued covers this one too.
+func (cfg *apiConfig) wakeWebmentionVerifier() {
+	if cfg.webmentionWake == nil {
+		return
+	}
+	select {
+	case cfg.webmentionWake <- struct{}{}:
+	default:
+	}
+}
+
+// runWebmentionVerifier verifies pending mentions whenever one arrives and
+// every interval, until ctx is done.
+func (cfg *apiConfig) runWebmentionVerifier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checked, err := cfg.verifyPendingWebmentions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to verify webmentions", "error", err)
+		} else if checked > 0 {
+			slog.InfoContext(ctx, "checked webmentions", "count", checked)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cfg.webmentionWake:
+		}
+	}
+}
+
+// verifyPendingWebmentions fetches the source of every pending mention.
+// Sources that link to their target are verified, ones that have gone are
+// deleted and the rest are marked invalid. It returns how many it checked.
+func (cfg *apiConfig) verifyPendingWebmentions(ctx context.Context) (int, error) {
+	checked := 0
+	for {
+		pending, err := cfg.DB.ListPendingWebmentions(ctx, webmentionBatchSize)
+		if err != nil {
+			return checked, err
+		}
+
+		for _, mention := range pending {
+			if err := cfg.verifyWebmention(ctx, mention); err != nil {
+				return checked, err
+			}
+			checked++
+		}
+
+		if len(pending) < webmentionBatchSize {
+			return checked, nil
+		}
+	}
+}
+
+func (cfg *apiConfig) verifyWebmention(ctx context.Context, mention database.Webmention) error {
+	source, err := cfg.webmentions.Verify(ctx, mention.Source, mention.Target)
+	switch {
+	case err == nil:
+		return cfg.DB.MarkWebmentionVerified(ctx, database.MarkWebmentionVerifiedParams{
+			Title:      truncate(source.Title, maxWebmentionTitle, "…"),
+			AuthorName: truncate(source.Author, maxWebmentionAuthor, "…"),
+			ID:         mention.ID,
+		})
+	case errors.Is(err, webmention.ErrGone):
+		_, err := cfg.DB.DeleteWebmention(ctx, mention.ID)
+		return err
+	default:
+		slog.InfoContext(ctx, "rejected webmention", "source", mention.Source, "target", mention.Target, "error", err)
+		return cfg.DB.MarkWebmentionInvalid(ctx, mention.ID)
+	}
+}
+
+// sendWebmentions notifies every site linked from a journal entry. Pass
+// the entry's public HTML from before and after a change so sites that
+// are no longer linked hear about it too and can drop the mention.
+// Each target is notified by a background job, which retries failures.
+func (cfg *apiConfig) sendWebmentions(r *http.Request, journalID int64, contents ...string) {
+	if cfg.webmentions == nil {
+		return
+	}
+
+	source := cfg.absoluteURL(r, fmt.Sprintf("/journals/%d", journalID))
+
+	var targets []string
+	seen := map[string]bool{}
+	for _, content := range contents {
+		for _, link := range webmention.OutboundLinks(content, source) {
+			if !seen[link] {
+				seen[link] = true
+				targets = append(targets, link)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	for _, target := range targets {
+		job := webmentionJob{Source: source, Target: target}
+		if _, err := cfg.jobKinds.sendWebmention.Enqueue(r.Context(), job, jobs.MaxAttempts(webmentionAttempts)); err != nil {
+			slog.ErrorContext(r.Context(), "failed to queue webmention", "source", source, "target", target, "error", err)
+		}
+	}
+}
+
+// getWebmentions lists received mentions in every state, newest first.
+func (cfg *apiConfig) getWebmentions(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, "invalid limit parameter", err)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondWithError(w, http.StatusBadRequest, "invalid offset parameter", err)
+			return
+		}
+		offset = n
+	}
+
+	total, err := cfg.DB.CountWebmentions(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to count webmentions", err)
+		return
+	}
+
+	rows, err := cfg.DB.ListWebmentions(r.Context(), database.ListWebmentionsParams{
+		Limit:  int64(limit),
+		Offset: int64(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to list webmentions", err)
+		return
+	}
+
+	resp := WebmentionsResponse{
+		Webmentions: []Webmention{},
+		Total:       int(total),
+		Page:        offset/limit + 1,
+		Limit:       limit,
+		HasMore:     offset+len(rows) < int(total),
+	}
+	for _, row := range rows {
+		mention := Webmention{
+			ID:           int(row.ID),
+			JournalID:    int(row.JournalID),
+			JournalTitle: row.JournalTitle,
+			Source:       row.Source,
+			Target:       row.Target,
+			Status:       row.Status,
+			Title:        row.Title,
+			AuthorName:   row.AuthorName,
+			CreatedAt:    row.CreatedAt.Time,
+		}
+		if row.VerifiedAt.Valid {
+			mention.VerifiedAt = &row.VerifiedAt.Time
+		}
+		resp.Webmentions = append(resp.Webmentions, mention)
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// deleteWebmention removes a mention the owner doesn't want shown. If the
+// source sends it again it is verified afresh.
+func (cfg *apiConfig) deleteWebmention(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("webmentionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid webmention ID", err)
+		return
+	}
+
+	n, err := cfg.DB.DeleteWebmention(r.Context(), int64(id))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "failed to delete webmention", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "webmention not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/webmention"
+)
+
+func TestReceiveWebmention(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.baseURL = "https://me.example"
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "mentioned", "password")
+	create := func(visibility string) string {
+		journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+			Title: "Entry", Content: "content", UserID: user.ID, Format: "html", Visibility: visibility,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("https://me.example/journals/%d", journal.ID)
+	}
+	public := create(visibilityPublic)
+	private := create(visibilityPrivate)
+
+	tests := []struct {
+		name       string
+		source     string
+		target     string
+		wantStatus int
+	}{
+		{"missing source", "", public, http.StatusBadRequest},
+		{"non-http source", "ftp://them.example/post", public, http.StatusBadRequest},
+		{"same URL", public, public, http.StatusBadRequest},
+		{"other site", "https://them.example/post", "https://else.example/journals/1", http.StatusBadRequest},
+		{"not a journal", "https://them.example/post", "https://me.example/projects", http.StatusBadRequest},
+		{"missing journal", "https://them.example/post", "https://me.example/journals/999", http.StatusBadRequest},
+		{"private journal", "https://them.example/post", private, http.StatusBadRequest},
+		{"valid", "https://them.example/post", public, http.StatusAccepted},
+		{"resent", "https://them.example/post", public, http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"source": {tt.source}, "target": {tt.target}}
+			req := httptest.NewRequest("POST", "/webmention", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			apiCfg.handleWebmention(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Sending the same mention twice updates it rather than duplicating it
+	pending, err := apiCfg.DB.ListPendingWebmentions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Target != public {
+		t.Errorf("Expected one pending mention of %s, got %+v", public, pending)
+	}
+}
+
+func TestVerifyPendingWebmentions(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := createTestUser(t, apiCfg.DB, "verifier", "password")
+	journal, err := apiCfg.DB.CreateJournalEntry(ctx, database.CreateJournalEntryParams{
+		Title: "Entry", Content: "content", UserID: user.ID, Format: "html", Visibility: visibilityPublic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fmt.Sprintf("https://me.example/journals/%d", journal.ID)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reply":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<title>Re: Entry</title><meta name="author" content="Ada"><a href="%s">this</a>`, target)
+		case "/unrelated":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="https://me.example/">home</a>`)
+		case "/deleted":
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+	apiCfg.webmentions = webmention.NewClient(srv.Client(), "")
+
+	for _, path := range []string{"/reply", "/unrelated", "/deleted"} {
+		_, err := apiCfg.DB.UpsertWebmention(ctx, database.UpsertWebmentionParams{
+			JournalID: journal.ID, Source: srv.URL + path, Target: target,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checked, err := apiCfg.verifyPendingWebmentions(ctx)
+	if err != nil || checked != 3 {
+		t.Fatalf("Expected three mentions checked, got %d, %v", checked, err)
+	}
+
+	rows, err := apiCfg.DB.ListWebmentions(ctx, database.ListWebmentionsParams{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, row := range rows {
+		statuses[strings.TrimPrefix(row.Source, srv.URL)] = row.Status
+	}
+	want := map[string]string{"/reply": webmentionVerified, "/unrelated": webmentionInvalid}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("Expected statuses %v, got %v", want, statuses)
+	}
+
+	verified, err := apiCfg.DB.ListVerifiedWebmentions(ctx, journal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views := mentionViews(verified, time.UTC)
+	if len(views) != 1 || views[0].Title != "Re: Entry" || views[0].Author != "Ada" {
+		t.Errorf("Unexpected mentions for display: %+v", views)
+	}
+}
+
+func TestSendWebmentions(t *testing.T) {
+	apiCfg, db := setupTestAPIConfig(t)
+	defer db.Close()
+	apiCfg.baseURL = "https://me.example"
+	apiCfg.registerJobs(jobs.New(apiCfg.DB, jobs.Options{}))
+
+	received := make(chan url.Values, 2)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<link rel="webmention" href="/endpoint">`)
+	})
+	mux.HandleFunc("GET /quiet", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<p>No endpoint here</p>`)
+	})
+	mux.HandleFunc("POST /endpoint", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		received <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	apiCfg.webmentions = webmention.NewClient(srv.Client(), "")
+
+	before := fmt.Sprintf(`<a href="%s/post">old link</a> <a href="/journals/2">internal</a>`, srv.URL)
+	after := fmt.Sprintf(`<a href="%s/quiet">new link</a>`, srv.URL)
+
+	req := httptest.NewRequest("PUT", "/api/journals", nil)
+	apiCfg.sendWebmentions(req, 7, before, after)
+
+	// One job per linked page; the page without an endpoint is done too
+	if ran, err := apiCfg.jobs.RunDue(context.Background()); err != nil || ran != 2 {
+		t.Fatalf("Expected two webmention jobs, got %d: %v", ran, err)
+	}
+	var left int
+	db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&left)
+	if left != 0 {
+		t.Errorf("Expected every job to finish, %d left", left)
+	}
+
+	select {
+	case form := <-received:
+		if form.Get("source") != "https://me.example/journals/7" || form.Get("target") != srv.URL+"/post" {
+			t.Errorf("Unexpected webmention %v", form)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the previously linked page to be notified")
+	}
+}
@@ -2,7 +2,6 @@ package routes
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 )
 
@@ -10,25 +9,26 @@ func respondWithJson(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Fatalf("Error marshalling json: %s", err)
-		w.WriteHeader(500)
+		requestLogger(w).Error("failed to marshal json response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"internal server error"}`))
 		return
 	}
 
 	w.WriteHeader(code)
 	_, err = w.Write(data)
 	if err != nil {
-		log.Fatal("Failed to write data to connection")
+		requestLogger(w).Warn("failed to write response", "error", err)
 	}
 }
 
 func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
-	if err != nil {
-		log.Println(err)
-	}
+	logger := requestLogger(w)
 
 	if code > 499 {
-		log.Printf("Responding with 5xx error: %v", msg)
+		logger.Error("responding with 5xx error", "status", code, "message", msg, "error", err)
+	} else if err != nil {
+		logger.Warn("responding with error", "status", code, "message", msg, "error", err)
 	}
 
 	type errorResponse struct {
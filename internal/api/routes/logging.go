@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	requestIDKey    contextKey = "request_id"
+	requestIDHeader            = "X-Request-ID"
+)
+
+// loggingResponseWriter records the status code and number of bytes written
+// so they can be reported in the access log once the handler returns.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	logger *slog.Logger
+}
+
+func (lw *loggingResponseWriter) WriteHeader(code int) {
+	if lw.status == 0 {
+		lw.status = code
+	}
+	lw.ResponseWriter.WriteHeader(code)
+}
+
+func (lw *loggingResponseWriter) Write(b []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(b)
+	lw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (lw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDFromContext returns the request ID assigned by middlewareLogging,
+// or an empty string if there is none.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// requestLogger returns the logger bound to the current request, falling back
+// to the default logger when w was not wrapped by middlewareLogging.
+func requestLogger(w http.ResponseWriter) *slog.Logger {
+	if lw, ok := w.(*loggingResponseWriter); ok && lw.logger != nil {
+		return lw.logger
+	}
+	return slog.Default()
+}
+
+// middlewareLogging assigns every request an ID (reusing an incoming
+// X-Request-ID when present), echoes it back in the response and writes one
+// access log line per request.
+func middlewareLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		lw := &loggingResponseWriter{
+			ResponseWriter: w,
+			logger:         slog.Default().With("request_id", requestID),
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		next.ServeHTTP(lw, r.WithContext(ctx))
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		lw.logger.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", lw.bytes),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
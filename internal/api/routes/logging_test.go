@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareLoggingRequestID(t *testing.T) {
+	var seen string
+	handler := middlewareLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	t.Run("propagates incoming id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(requestIDHeader, "abc123")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if seen != "abc123" {
+			t.Errorf("Expected request ID abc123 in context, got %q", seen)
+		}
+		if got := rr.Header().Get(requestIDHeader); got != "abc123" {
+			t.Errorf("Expected response header abc123, got %q", got)
+		}
+	})
+
+	t.Run("assigns new id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if seen == "" {
+			t.Error("Expected a generated request ID")
+		}
+		if got := rr.Header().Get(requestIDHeader); got != seen {
+			t.Errorf("Expected response header %q, got %q", seen, got)
+		}
+	})
+}
+
+func TestRespondWithJsonMarshalError(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	// NaN cannot be encoded as JSON; this used to call log.Fatalf
+	respondWithJson(rr, http.StatusOK, math.NaN())
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+}
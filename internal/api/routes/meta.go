@@ -0,0 +1,201 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/ogimage"
+	"github.com/sianwa11/my-journal/internal/seo"
+)
+
+// siteURL returns the public origin of the site without a trailing slash.
+// BASE_URL wins when set; otherwise it is derived from the request, which
+// is only trustworthy behind a proxy that sets X-Forwarded-Proto.
+func (cfg *apiConfig) siteURL(r *http.Request) string {
+	if cfg.baseURL != "" {
+		return cfg.baseURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host
+}
+
+// absoluteURL resolves a site-relative path against siteURL. Values that are
+// already absolute are returned untouched.
+func (cfg *apiConfig) absoluteURL(r *http.Request, path string) string {
+	if path == "" || isWebURL(path) {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return cfg.siteURL(r) + path
+}
+
+// journalIDFromURL returns the journal entry u points at, if it is one of
+// this site's /journals/{id} pages.
+func (cfg *apiConfig) journalIDFromURL(r *http.Request, u *url.URL) (int64, bool) {
+	site, err := url.Parse(cfg.siteURL(r))
+	if err != nil || !strings.EqualFold(u.Host, site.Host) || !strings.HasPrefix(u.Path, "/journals/") {
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(u.Path, "/journals/"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// pageMeta returns the metadata every public page starts from: the site
+// name, a canonical URL for the current path and the default share image.
+func (cfg *apiConfig) pageMeta(r *http.Request, settings SiteSettings, title, description string) seo.Meta {
+	canonical := r.URL.Path
+	if page := pageFromRequest(r); page > 1 {
+		canonical += "?page=" + strconv.Itoa(page)
+	}
+
+	return seo.Meta{
+		Title:       title,
+		Description: description,
+		Canonical:   cfg.absoluteURL(r, canonical),
+		Type:        "website",
+		Image:       cfg.absoluteURL(r, settings.DefaultOGImage),
+		SiteName:    settings.SiteTitle,
+	}
+}
+
+// homeMeta describes the about page, which doubles as the owner's profile.
+func (cfg *apiConfig) homeMeta(r *http.Request, data map[string]interface{}) seo.Meta {
+	settings := data["Settings"].(SiteSettings)
+	name, _ := data["Name"].(string)
+	bio, _ := data["Bio"].(string)
+
+	meta := cfg.pageMeta(r, settings, settings.SiteTitle, seo.Excerpt(seo.FirstNonEmpty(settings.Tagline, bio), seo.DescriptionLength))
+	meta.Type = "profile"
+	meta.Author = name
+
+	var sameAs []string
+	for _, key := range []string{"Github", "Linkedin"} {
+		if link, _ := data[key].(string); isWebURL(link) {
+			sameAs = append(sameAs, link)
+		}
+	}
+	for _, link := range settings.SocialLinks {
+		sameAs = append(sameAs, link.URL)
+	}
+
+	meta.JSONLD = seo.NewPerson(name, cfg.siteURL(r)+"/", seo.Excerpt(bio, seo.DescriptionLength), sameAs)
+	return meta
+}
+
+// journalMeta describes a single journal entry. The entry's SEO overrides
+// take precedence over its title and an excerpt of its content. Encrypted
+// entries have no excerpt; their content is the envelope.
+func (cfg *apiConfig) journalMeta(r *http.Request, data map[string]interface{}, journal database.JournalEntry) seo.Meta {
+	settings := data["Settings"].(SiteSettings)
+	name, _ := data["Name"].(string)
+
+	excerpt := "Encrypted entry"
+	if !journal.Encrypted {
+		excerpt = seo.Excerpt(seo.FirstNonEmpty(journal.ContentHtml, journal.Content), seo.DescriptionLength)
+	}
+	meta := cfg.pageMeta(r, settings,
+		seo.FirstNonEmpty(journal.SeoTitle.String, journal.Title),
+		seo.FirstNonEmpty(journal.SeoDescription.String, excerpt),
+	)
+	meta.Type = "article"
+	meta.Author = name
+	meta.Published = journal.CreatedAt.Time
+	meta.Modified = journal.UpdatedAt.Time
+	cfg.setCardImage(r, &meta, "/journals/"+strconv.FormatInt(journal.ID, 10)+"/og.png", journalCard(data, journal))
+
+	meta.JSONLD = seo.NewBlogPosting(meta, seo.Author(name, cfg.siteURL(r)+"/"))
+	return meta
+}
+
+// projectMeta describes a single project, preferring the project's own
+// image over a generated card for social previews.
+func (cfg *apiConfig) projectMeta(r *http.Request, data map[string]interface{}, project database.GetProjectRow) seo.Meta {
+	settings := data["Settings"].(SiteSettings)
+	name, _ := data["Name"].(string)
+
+	meta := cfg.pageMeta(r, settings,
+		seo.FirstNonEmpty(project.SeoTitle.String, project.Title),
+		seo.FirstNonEmpty(project.SeoDescription.String, seo.Excerpt(project.Description, seo.DescriptionLength)),
+	)
+	meta.Type = "article"
+	meta.Author = name
+	meta.Published = project.CreatedAt.Time
+	meta.Modified = project.UpdatedAt.Time
+	meta.Tags = projectTags(project)
+	if project.ImageUrl.String != "" {
+		meta.Image = cfg.absoluteURL(r, project.ImageUrl.String)
+	} else {
+		cfg.setCardImage(r, &meta, "/projects/"+strconv.FormatInt(project.ProjectID, 10)+"/og.png", projectCard(data, project))
+	}
+
+	meta.JSONLD = seo.NewCreativeWork(meta, seo.Author(name, cfg.siteURL(r)+"/"), project.Github.String, project.Link.String)
+	return meta
+}
+
+// setCardImage points meta at a generated share card. The card's hash is
+// added to the URL so networks that cache previews pick up edits.
+func (cfg *apiConfig) setCardImage(r *http.Request, meta *seo.Meta, path string, card ogimage.Card) {
+	meta.Image = cfg.absoluteURL(r, path+"?v="+card.Hash())
+	meta.ImageWidth = ogimage.Width
+	meta.ImageHeight = ogimage.Height
+}
+
+// journalCard describes the share image for a journal entry.
+func journalCard(data map[string]interface{}, journal database.JournalEntry) ogimage.Card {
+	return ogimage.Card{
+		Kind:     "Journal",
+		Title:    journal.Title,
+		Date:     cardDate(data, journal.CreatedAt.Time),
+		Author:   data["Name"].(string),
+		SiteName: data["Settings"].(SiteSettings).SiteTitle,
+	}
+}
+
+// projectCard describes the share image for a project.
+func projectCard(data map[string]interface{}, project database.GetProjectRow) ogimage.Card {
+	return ogimage.Card{
+		Kind:     "Project",
+		Title:    project.Title,
+		Date:     cardDate(data, project.CreatedAt.Time),
+		Author:   data["Name"].(string),
+		Tags:     projectTags(project),
+		SiteName: data["Settings"].(SiteSettings).SiteTitle,
+	}
+}
+
+func cardDate(data map[string]interface{}, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	loc, _ := data["Location"].(*time.Location)
+	return inLocation(t, loc).Format("Jan 2, 2006")
+}
+
+// projectTags splits GetProject's comma-joined tag names.
+func projectTags(project database.GetProjectRow) []string {
+	var tags []string
+	for _, tag := range strings.Split(project.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sianwa11/my-journal/internal/auth"
+)
+
+// metrics holds the Prometheus collectors exported on /metrics. A nil
+// *metrics is valid and records nothing, which keeps handlers usable in tests.
+type metrics struct {
+	registry        *prometheus.Registry
+	requestsTotal   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	loginAttempts   *prometheus.CounterVec
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served.",
+		}),
+		loginAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "login_attempts_total",
+			Help: "Login attempts by result.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requestsTotal,
+		m.requestDuration,
+		m.inFlight,
+		m.loginAttempts,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "journal"),
+	)
+
+	return m
+}
+
+func (m *metrics) loginAttempt(success bool) {
+	if m == nil {
+		return
+	}
+
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	m.loginAttempts.WithLabelValues(result).Inc()
+}
+
+// middleware records request counts, latency and in-flight requests. The
+// route label is the ServeMux pattern that matched, so /journals/1 and
+// /journals/2 share a single series.
+func (m *metrics) middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		lw, ok := w.(*loggingResponseWriter)
+		if !ok {
+			lw = &loggingResponseWriter{ResponseWriter: w}
+		}
+
+		next.ServeHTTP(lw, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// handler serves the registry in the Prometheus text format. When token is
+// non-empty, scrapers must present it as a bearer token. Without a
+// registry there is nothing to serve, so it answers 404.
+func (m *metrics) handler(token string) http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := auth.GetBearerToken(r.Header)
+		if err != nil || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			respondWithError(w, http.StatusUnauthorized, "invalid metrics token", nil)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, m *metrics) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	m.handler("").ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from /metrics, got %d", rr.Code)
+	}
+	return rr.Body.String()
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	_, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	m := newMetrics(db)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /journals/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := middlewareLogging(m.middleware(mux))
+
+	for _, path := range []string{"/journals/1", "/journals/1", "/journals/2", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		// Requests are labelled by pattern, with the status the handler wrote
+		`http_requests_total{method="GET",route="GET /journals/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="GET /journals/{id}",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="GET /journals/{id}"} 3`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+	if strings.Contains(body, `route="/journals/1"`) {
+		t.Error("Expected raw paths not to be used as route labels")
+	}
+}
+
+func TestMetricsMiddlewareWithoutLogging(t *testing.T) {
+	_, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	// The status is still captured when nothing has wrapped the writer yet
+	m := newMetrics(db)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	m.middleware(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/login", nil))
+
+	want := `http_requests_total{method="POST",route="POST /login",status="401"} 1`
+	if body := scrape(t, m); !strings.Contains(body, want) {
+		t.Errorf("Expected metrics to contain %s", want)
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	_, db := setupTestAPIConfig(t)
+	defer db.Close()
+
+	handler := newMetrics(db).handler("scrape-secret")
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer token", "Basic scrape-secret", http.StatusUnauthorized},
+		{"valid token", "Bearer scrape-secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK && strings.Contains(rr.Body.String(), "http_requests_total") {
+				t.Error("Expected no metrics without the token")
+			}
+		})
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *metrics
+
+	rr := httptest.NewRecorder()
+	m.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected requests to pass through, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	m.handler("").ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 from /metrics, got %d", rr.Code)
+	}
+
+	m.loginAttempt(true)
+}
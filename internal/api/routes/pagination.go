@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// pagination describes one page of a server-rendered list.
+type pagination struct {
+	Page       int
+	PerPage    int
+	Total      int
+	TotalPages int
+}
+
+func newPagination(page, perPage, total int) pagination {
+	totalPages := (total + perPage - 1) / perPage
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	return pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+}
+
+func (p pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+func (p pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+func (p pagination) HasNext() bool {
+	return p.Page < p.TotalPages
+}
+
+func (p pagination) PrevPage() int {
+	return p.Page - 1
+}
+
+func (p pagination) NextPage() int {
+	return p.Page + 1
+}
+
+// OutOfRange reports whether the requested page lies past the last page.
+func (p pagination) OutOfRange() bool {
+	return p.Page > p.TotalPages
+}
+
+// pageFromRequest reads ?page=N, treating missing or invalid values as 1.
+func pageFromRequest(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// pageURL links to a page of the list at path, leaving page 1 canonical.
+func pageURL(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	return path + "?page=" + strconv.Itoa(page)
+}
+
+// pageNumbers returns a window of up to five page numbers around the
+// current page for rendering numbered links.
+func pageNumbers(p pagination) []int {
+	const window = 5
+
+	start := p.Page - window/2
+	if start+window-1 > p.TotalPages {
+		start = p.TotalPages - window + 1
+	}
+	if start < 1 {
+		start = 1
+	}
+
+	pages := []int{}
+	for i := start; i <= p.TotalPages && len(pages) < window; i++ {
+		pages = append(pages, i)
+	}
+	return pages
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPageNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  int
+		total int
+		want  []int
+	}{
+		{name: "single page", page: 1, total: 5, want: []int{1}},
+		{name: "first page", page: 1, total: 200, want: []int{1, 2, 3, 4, 5}},
+		{name: "middle page", page: 6, total: 200, want: []int{4, 5, 6, 7, 8}},
+		{name: "last page", page: 10, total: 200, want: []int{6, 7, 8, 9, 10}},
+		{name: "few pages", page: 2, total: 50, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pageNumbers(newPagination(tt.page, 20, tt.total))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPageURL(t *testing.T) {
+	if got := pageURL("/journals", 1); got != "/journals" {
+		t.Errorf("Expected /journals for the first page, got %s", got)
+	}
+	if got := pageURL("/journals", 3); got != "/journals?page=3" {
+		t.Errorf("Expected /journals?page=3, got %s", got)
+	}
+}
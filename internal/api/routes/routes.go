@@ -3,36 +3,90 @@ package routes
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/microcosm-cc/bluemonday"
+	"github.com/sianwa11/my-journal/internal/activitypub"
+	"github.com/sianwa11/my-journal/internal/analytics"
 	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/jobs"
+	"github.com/sianwa11/my-journal/internal/mailer"
+	"github.com/sianwa11/my-journal/internal/ogimage"
+	"github.com/sianwa11/my-journal/internal/safehttp"
+	"github.com/sianwa11/my-journal/internal/seo"
+	"github.com/sianwa11/my-journal/internal/webhook"
+	"github.com/sianwa11/my-journal/internal/webmention"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 )
 
+const projectsPerPage = 9
+
 type apiConfig struct {
 	dbConn    *sql.DB
 	DB        *database.Queries
 	jwtSecret string
+	metrics   *metrics
+	templates *template.Template
+	mediaDir  string
+	baseURL   string
+	settings  *settingsCache
+	ogImages  *ogimage.Cache
+
+	// webmentions fetches other sites for sending and verifying mentions;
+	// webmentionWake tells the verifier a new mention has arrived.
+	webmentions    *webmention.Client
+	webmentionWake chan struct{}
+
+	// activityPub fetches remote actors and delivers activities for the
+	// activitypub.deliver jobs.
+	activityPub *activitypub.Client
+	actorKeys   actorKeyCache
+
+	// webhooks sends the payloads logged in webhook_deliveries for the
+	// webhook.deliver jobs.
+	webhooks *webhook.Client
+
+	// jobs runs background work stored in the jobs table; jobKinds are
+	// the kinds registered on it.
+	jobs     *jobs.Queue
+	jobKinds backgroundJobs
+
+	// mailer sends newsletter confirmations and digests.
+	mailer mailer.Mailer
+
+	// analytics records views of public pages for /api/stats.
+	analytics *analytics.Recorder
+
+	trashRetention time.Duration
 }
 
-func SetupRoutes() *http.ServeMux {
+// SetupRoutes builds the server's handler and starts its background
+// workers. The returned function stops them, waiting for running jobs to
+// finish until its context is done.
+func SetupRoutes() (http.Handler, func(context.Context) error) {
 
 	err := godotenv.Load()
 	if err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
+		slog.Warn("error loading .env file", "error", err)
 	}
 
 	secret := os.Getenv("SECRET")
 	dbUrl := os.Getenv("DB_URL")
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "./data/media"
+	}
+	baseURL := strings.TrimRight(os.Getenv("BASE_URL"), "/")
 	// const rootPath = "."
 
 	db, err := sql.Open("libsql", dbUrl)
@@ -42,9 +96,38 @@ func SetupRoutes() *http.ServeMux {
 
 	apiCfg := &apiConfig{
 		jwtSecret: secret,
+		metrics:   newMetrics(db),
+		mediaDir:  mediaDir,
+		baseURL:   baseURL,
+
+		trashRetention: trashRetentionFromEnv(os.Getenv("TRASH_RETENTION_DAYS")),
 	}
 	apiCfg.DB = database.New(db)
 	apiCfg.dbConn = db
+	apiCfg.settings = newSettingsCache(apiCfg.DB)
+	apiCfg.ogImages = ogimage.NewCache(filepath.Join(mediaDir, "og"))
+
+	apiCfg.webmentions = webmention.NewClient(safehttp.NewClient(), webmentionUserAgent)
+	apiCfg.webmentionWake = make(chan struct{}, 1)
+	apiCfg.activityPub = activitypub.NewClient(safehttp.NewClient(), activityPubUserAgent)
+	apiCfg.webhooks = webhook.NewClient(safehttp.NewClient(), webhookUserAgent)
+	apiCfg.mailer = mailerFromEnv()
+	apiCfg.analytics = analytics.NewRecorder(apiCfg.DB)
+
+	apiCfg.registerJobs(jobs.New(apiCfg.DB, jobs.Options{
+		Concurrency: jobConcurrencyFromEnv(os.Getenv("JOB_CONCURRENCY")),
+	}))
+
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	apiCfg.jobs.Start(ctx)
+	go apiCfg.runTrashPurger(ctx, trashPurgeInterval)
+	go apiCfg.runWebmentionVerifier(ctx, webmentionCheckInterval)
+	if !apiCfg.federates() {
+		slog.Warn("ActivityPub is disabled because BASE_URL is not set")
+	}
+	go apiCfg.runWebhookLogPruner(ctx, webhookPruneInterval)
+	go apiCfg.runShareImagePruner(ctx, ogImagePruneInterval)
+	go apiCfg.runAnalyticsRollup(ctx, analyticsRollupInterval)
 
 	mux := http.NewServeMux()
 
@@ -53,39 +136,21 @@ func SetupRoutes() *http.ServeMux {
 	mux.Handle("/static/", http.StripPrefix("/static/", fileServer))
 
 	funcMap := template.FuncMap{
-		"safeHTML": func(s string) template.HTML {
-			p := bluemonday.UGCPolicy()
-
-			sanitized := p.Sanitize(s)
-
-			// Safe to convert to template.HTML after sanitization
-			// #nosec G203 - XSS prevention: content sanitized with bluemonday UGC policy before HTML conversion
-			return template.HTML(sanitized)
-		},
-		"split": strings.Split,
+		"safeHTML":    safeHTML,
+		"split":       strings.Split,
+		"upper":       strings.ToUpper,
+		"truncate":    truncate,
+		"pageURL":     pageURL,
+		"pageNumbers": pageNumbers,
+		"in":          inLocation,
+		"pathEscape":  url.PathEscape,
+		"add":         add,
 	}
 
 	// Parse templates
 	tmpl := template.Must(template.New("").Funcs(funcMap).ParseGlob("template/*.html"))
 	tmpl = template.Must(tmpl.ParseGlob("template/partials/*.html"))
-
-	getUserTemplateData := func() (map[string]interface{}, error) {
-		user, err := apiCfg.DB.ListUser(context.Background())
-		if err != nil || len(user) == 0 {
-			return map[string]interface{}{
-				"Name": "Your Name",
-				"Year": time.Now().Year(),
-			}, nil
-		}
-
-		return map[string]interface{}{
-			"Name":     user[0].Name,
-			"Email":    user[0].Email.String,
-			"Github":   user[0].Github.String,
-			"Linkedin": user[0].Linkedin.String,
-			"Year":     time.Now().Year(),
-		}, nil
-	}
+	apiCfg.templates = tmpl
 
 	// Dashboard route using template
 	mux.HandleFunc("/admin/dashboard", func(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +189,33 @@ func SetupRoutes() *http.ServeMux {
 		}
 	})
 
+	mux.HandleFunc("/admin/comments", func(w http.ResponseWriter, r *http.Request) {
+		err := tmpl.ExecuteTemplate(w, "comments.html", map[string]interface{}{
+			"Title": "Moderate Comments",
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("/admin/newsletter", func(w http.ResponseWriter, r *http.Request) {
+		err := tmpl.ExecuteTemplate(w, "newsletter.html", map[string]interface{}{
+			"Title": "Newsletter",
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("/admin/settings", func(w http.ResponseWriter, r *http.Request) {
+		err := tmpl.ExecuteTemplate(w, "settings.html", map[string]interface{}{
+			"Title": "Site Settings",
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
 	mux.HandleFunc("/admin/profile", func(w http.ResponseWriter, r *http.Request) {
 		user, err := apiCfg.DB.ListUser(r.Context())
 		if err != nil {
@@ -145,45 +237,96 @@ func SetupRoutes() *http.ServeMux {
 	})
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		user, err := apiCfg.DB.ListUser(r.Context())
+		data := apiCfg.baseTemplateData(r.Context(), "About", "about")
+		data["SEO"] = apiCfg.homeMeta(r, data)
+
+		// Every unmatched path lands here too; only the home page counts
+		if r.URL.Path == "/" {
+			apiCfg.recordPageView(w, r, "/")
+		}
+
+		err := tmpl.ExecuteTemplate(w, "me.html", data)
+
 		if err != nil {
-			http.Error(w, "Failed to fetch user data", http.StatusInternalServerError)
-			return
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
+	})
+
+	mux.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
+		data := apiCfg.baseTemplateData(r.Context(), "Projects", "projects")
 
-		if len(user) == 0 {
-			http.Error(w, "No user data found", http.StatusInternalServerError)
+		total, err := apiCfg.DB.GetProjectsCount(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
 			return
 		}
 
-		bio := ""
-		if user[0].Bio.Valid {
-			bio = strings.TrimSpace(user[0].Bio.String)
+		page := newPagination(pageFromRequest(r), projectsPerPage, int(total))
+		if page.OutOfRange() {
+			http.NotFound(w, r)
+			return
 		}
 
-		err = tmpl.ExecuteTemplate(w, "me.html", map[string]interface{}{
-			"Title":       "Sianwa",
-			"Name":        user[0].Name,
-			"Bio":         bio,
-			"Github":      user[0].Github.String,
-			"Linkedin":    user[0].Linkedin.String,
-			"Email":       user[0].Email.String,
-			"CurrentPage": "about",
-			"FooterText":  "Built with passion ❤️.",
+		projects, err := apiCfg.DB.GetProjects(r.Context(), database.GetProjectsParams{
+			Limit:  int64(page.PerPage),
+			Offset: int64(page.Offset()),
 		})
+		if err != nil {
+			http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
+			return
+		}
+
+		data["Projects"] = projects
+		data["Pagination"] = page
+		data["BasePath"] = "/projects"
+		data["SEO"] = apiCfg.pageMeta(r, data["Settings"].(SiteSettings), "Projects",
+			"Here are some of the projects I've worked on. Each one represents a journey of learning and growth.")
+
+		err = tmpl.ExecuteTemplate(w, "list-projects.html", data)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	})
 
-	mux.HandleFunc("/projects", func(w http.ResponseWriter, r *http.Request) {
-		data, _ := getUserTemplateData()
-		data["Title"] = "Projects"
-		data["CurrentPage"] = "projects"
-		data["FooterText"] = "Built with passion ❤️."
+	mux.HandleFunc("/tags/{name}", func(w http.ResponseWriter, r *http.Request) {
+		tag := r.PathValue("name")
+		data := apiCfg.baseTemplateData(r.Context(), "Tagged "+tag, "projects")
 
-		err := tmpl.ExecuteTemplate(w, "list-projects.html", data)
+		total, err := apiCfg.DB.GetProjectsByTagCount(r.Context(), tag)
+		if err != nil {
+			http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
+			return
+		}
+		if total == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		page := newPagination(pageFromRequest(r), projectsPerPage, int(total))
+		if page.OutOfRange() {
+			http.NotFound(w, r)
+			return
+		}
+
+		projects, err := apiCfg.DB.GetProjectsByTag(r.Context(), database.GetProjectsByTagParams{
+			Name:   tag,
+			Limit:  int64(page.PerPage),
+			Offset: int64(page.Offset()),
+		})
+		if err != nil {
+			http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
+			return
+		}
+
+		data["Tag"] = tag
+		data["Projects"] = projects
+		data["Pagination"] = page
+		data["BasePath"] = "/tags/" + url.PathEscape(tag)
+		data["SEO"] = apiCfg.pageMeta(r, data["Settings"].(SiteSettings), "Projects tagged "+tag,
+			"Projects I've worked on that involve "+tag+".")
+
+		err = tmpl.ExecuteTemplate(w, "list-projects.html", data)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -191,12 +334,43 @@ func SetupRoutes() *http.ServeMux {
 	})
 
 	mux.HandleFunc("/journals", func(w http.ResponseWriter, r *http.Request) {
-		data, _ := getUserTemplateData()
-		data["Title"] = "Journals"
-		data["CurrentPage"] = "journals"
-		data["FooterText"] = "Built with passion ❤️."
+		data := apiCfg.baseTemplateData(r.Context(), "Journals", "journals")
+
+		// Browsers don't send the admin's token, so this page is always
+		// the public view
+		total, err := apiCfg.DB.GetAllJournalsCount(r.Context(), 0)
+		if err != nil {
+			http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+			return
+		}
 
-		err := tmpl.ExecuteTemplate(w, "list-journals.html", data)
+		perPage := apiCfg.siteSettings(r.Context()).PostsPerPage
+		page := newPagination(pageFromRequest(r), perPage, int(total))
+		if page.OutOfRange() {
+			http.NotFound(w, r)
+			return
+		}
+
+		journals, err := apiCfg.DB.GetJournals(r.Context(), database.GetJournalsParams{
+			Limit:  int64(page.PerPage),
+			Offset: int64(page.Offset()),
+		})
+		if err != nil {
+			http.Error(w, "Failed to fetch journals", http.StatusInternalServerError)
+			return
+		}
+
+		for i := range journals {
+			apiCfg.ensureRendered(r.Context(), &journals[i])
+		}
+
+		data["Journals"] = journals
+		data["Pagination"] = page
+		data["BasePath"] = "/journals"
+		data["SEO"] = apiCfg.pageMeta(r, data["Settings"].(SiteSettings), "Journals",
+			"A collection of thoughts, experiences, and reflections on my journey.")
+
+		err = tmpl.ExecuteTemplate(w, "list-journals.html", data)
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -204,11 +378,6 @@ func SetupRoutes() *http.ServeMux {
 	})
 
 	mux.HandleFunc("/journals/{ID}", func(w http.ResponseWriter, r *http.Request) {
-		data, _ := getUserTemplateData()
-		data["Title"] = "Journal Entry"
-		data["CurrentPage"] = "journals"
-		data["FooterText"] = "Built with passion ❤️."
-
 		IDStr := r.PathValue("ID")
 		journalID, err := strconv.Atoi(IDStr)
 
@@ -218,33 +387,57 @@ func SetupRoutes() *http.ServeMux {
 		}
 
 		journal, err := apiCfg.DB.GetJournalEntry(r.Context(), int64(journalID))
-		if err != nil {
+		if err != nil || !canViewJournal(journal, apiCfg.viewerID(r)) {
 			http.Error(w, "Journal entry not found", http.StatusNotFound)
 			return
 		}
 
-		nextAndPrev, err := apiCfg.DB.GetPrevAndNextJournalIDs(r.Context(), int64(journalID))
-		if err != nil {
-			http.Error(w, "Failed to fetch navigation data", http.StatusInternalServerError)
+		// Only the owner gets this far with an entry that isn't public
+		if journal.Visibility != visibilityPublic {
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+
+		// The page and its ActivityPub object share a URL
+		w.Header().Add("Vary", "Accept")
+		if apiCfg.federates() && activitypub.IsActivityJSON(r.Header.Get("Accept")) {
+			if journal.Visibility != visibilityPublic {
+				http.Error(w, "Journal entry not found", http.StatusNotFound)
+				return
+			}
+			apiCfg.serveJournalObject(w, r, journal)
 			return
 		}
 
-		data["Journal"] = journal
-		data["NextJournalID"] = nextAndPrev.NextID
-		data["PrevJournalID"] = nextAndPrev.PreviousID
+		apiCfg.recordPageView(w, r, fmt.Sprintf("/journals/%d", journal.ID))
+		apiCfg.renderJournalPage(w, r, journal, commentForm{})
+	})
 
-		err = tmpl.ExecuteTemplate(w, "view-journal.html", data)
+	mux.HandleFunc("POST /journals/{ID}/comments", apiCfg.handlePostComment)
+	mux.HandleFunc("POST /webmention", apiCfg.handleWebmention)
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	})
+	mux.HandleFunc("GET /micropub", apiCfg.handleMicropubQuery)
+	mux.HandleFunc("POST /micropub", apiCfg.handleMicropub)
+	mux.HandleFunc("POST /micropub/media", apiCfg.handleMicropubMedia)
+	mux.HandleFunc("GET /media/uploads/{file}", apiCfg.serveMediaUpload)
+
+	if apiCfg.federates() {
+		mux.HandleFunc("GET /.well-known/webfinger", apiCfg.handleWebFinger)
+		mux.HandleFunc("GET /ap/actor", apiCfg.handleActor)
+		mux.HandleFunc("GET /ap/outbox", apiCfg.handleOutbox)
+		mux.HandleFunc("GET /ap/followers", apiCfg.handleFollowers)
+		mux.HandleFunc("POST /ap/inbox", apiCfg.handleInbox)
+	}
+
+	mux.HandleFunc("GET /shared/{token}", apiCfg.handleSharedJournal)
+
+	mux.HandleFunc("GET /newsletter", apiCfg.handleSubscribePage)
+	mux.HandleFunc("POST /newsletter", apiCfg.handleSubscribe)
+	mux.HandleFunc("GET /newsletter/confirm", apiCfg.handleConfirmSubscription)
+	mux.HandleFunc("GET /newsletter/unsubscribe", apiCfg.handleUnsubscribePage)
+	mux.HandleFunc("POST /newsletter/unsubscribe", apiCfg.handleUnsubscribe)
 
 	mux.HandleFunc("/projects/{ID}", func(w http.ResponseWriter, r *http.Request) {
-		data, _ := getUserTemplateData()
-		data["Title"] = "Project Details"
-		data["CurrentPage"] = "projects"
-		data["FooterText"] = "Built with passion ❤️."
+		data := apiCfg.baseTemplateData(r.Context(), "Project Details", "projects")
 
 		IDStr := r.PathValue("ID")
 		projectID, err := strconv.Atoi(IDStr)
@@ -257,6 +450,7 @@ func SetupRoutes() *http.ServeMux {
 		project, err := apiCfg.DB.GetProject(r.Context(), int64(projectID))
 		if err != nil {
 			http.Error(w, "Project not found", http.StatusNotFound)
+			return
 		}
 
 		ordered, err := apiCfg.DB.GetProjectsNextAndPrevious(r.Context(), int64(projectID))
@@ -268,6 +462,8 @@ func SetupRoutes() *http.ServeMux {
 		data["Project"] = project
 		data["NextProjectID"] = ordered.NextID
 		data["PrevProjectID"] = ordered.PreviousID
+		data["SEO"] = apiCfg.projectMeta(r, data, project)
+		apiCfg.recordPageView(w, r, fmt.Sprintf("/projects/%d", project.ProjectID))
 
 		err = tmpl.ExecuteTemplate(w, "view-project.html", data)
 
@@ -276,13 +472,61 @@ func SetupRoutes() *http.ServeMux {
 		}
 	})
 
+	mux.HandleFunc("GET /archive", apiCfg.handleArchive)
+	mux.HandleFunc("GET /archive/{year}", apiCfg.handleArchiveYear)
+	mux.HandleFunc("GET /archive/{year}/{month}", apiCfg.handleArchiveMonth)
+
+	mux.HandleFunc("/series/{ID}", func(w http.ResponseWriter, r *http.Request) {
+		seriesID, err := strconv.Atoi(r.PathValue("ID"))
+		if err != nil {
+			http.Error(w, "Invalid series ID", http.StatusBadRequest)
+			return
+		}
+
+		series, entries, err := apiCfg.loadSeries(r.Context(), int64(seriesID))
+		if err != nil || len(entries) == 0 {
+			http.Error(w, "Series not found", http.StatusNotFound)
+			return
+		}
+
+		data := apiCfg.baseTemplateData(r.Context(), series.Title, "journals")
+		data["Series"] = series
+		data["Entries"] = entries
+		data["SEO"] = apiCfg.pageMeta(r, data["Settings"].(SiteSettings), series.Title,
+			seo.Excerpt(seo.FirstNonEmpty(series.Description, fmt.Sprintf("A series of %d journal entries.", len(entries))), seo.DescriptionLength))
+
+		err = tmpl.ExecuteTemplate(w, "view-series.html", data)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("GET /journals/{ID}/og.png", apiCfg.journalOGImage)
+	mux.HandleFunc("GET /projects/{ID}/og.png", apiCfg.projectOGImage)
+
+	mux.HandleFunc("GET /robots.txt", apiCfg.handleRobots)
+	mux.HandleFunc("GET /sitemap.xml", apiCfg.handleSitemap)
+	mux.HandleFunc("GET /sitemap/{file}", apiCfg.handleSitemapPage)
+
 	mux.HandleFunc("/api/healthz", healthCheck)
+	mux.HandleFunc("GET /api/readyz", apiCfg.readinessCheck)
+	mux.Handle("GET /metrics", apiCfg.metrics.handler(os.Getenv("METRICS_TOKEN")))
 
 	mux.HandleFunc("GET /api/journals", apiCfg.getJournalEntries)
+	mux.HandleFunc("GET /api/journals/archive", apiCfg.getJournalArchive)
 	mux.HandleFunc("GET /api/journals/{journalID}", apiCfg.getJournalEntry)
 	mux.HandleFunc("POST /api/journals", apiCfg.middlewareMustBeLoggedIn(apiCfg.postJournalEntry))
 	mux.HandleFunc("PUT /api/journals", apiCfg.middlewareMustBeLoggedIn(apiCfg.editJournalEntry))
 	mux.HandleFunc("DELETE /api/journals/{journalID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteJournalEntry))
+	mux.HandleFunc("POST /api/journals/{journalID}/share-token", apiCfg.middlewareMustBeLoggedIn(apiCfg.rotateShareToken))
+
+	mux.HandleFunc("GET /api/series", apiCfg.getSeriesList)
+	mux.HandleFunc("GET /api/series/{seriesID}", apiCfg.getSeries)
+	mux.HandleFunc("POST /api/series", apiCfg.middlewareMustBeLoggedIn(apiCfg.createSeries))
+	mux.HandleFunc("PUT /api/series", apiCfg.middlewareMustBeLoggedIn(apiCfg.updateSeries))
+	mux.HandleFunc("PUT /api/series/{seriesID}/entries", apiCfg.middlewareMustBeLoggedIn(apiCfg.reorderSeries))
+	mux.HandleFunc("DELETE /api/series/{seriesID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteSeries))
 
 	mux.HandleFunc("POST /api/projects", apiCfg.middlewareMustBeLoggedIn(apiCfg.createProject))
 	mux.HandleFunc("GET /api/projects", apiCfg.getProjects)
@@ -290,8 +534,40 @@ func SetupRoutes() *http.ServeMux {
 	mux.HandleFunc("DELETE /api/projects/{projectID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteProject))
 	mux.HandleFunc("PUT /api/projects", apiCfg.middlewareMustBeLoggedIn(apiCfg.updateProject))
 
+	mux.HandleFunc("GET /api/trash", apiCfg.middlewareMustBeLoggedIn(apiCfg.getTrash))
+	mux.HandleFunc("POST /api/trash/{kind}/{id}/restore", apiCfg.middlewareMustBeLoggedIn(apiCfg.restoreFromTrash))
+	mux.HandleFunc("DELETE /api/trash/{kind}/{id}", apiCfg.middlewareMustBeLoggedIn(apiCfg.purgeFromTrash))
+
+	mux.HandleFunc("GET /api/comments", apiCfg.middlewareMustBeLoggedIn(apiCfg.getComments))
+	mux.HandleFunc("PUT /api/comments/{commentID}/status", apiCfg.middlewareMustBeLoggedIn(apiCfg.setCommentStatus))
+	mux.HandleFunc("POST /api/comments/{commentID}/replies", apiCfg.middlewareMustBeLoggedIn(apiCfg.replyToComment))
+	mux.HandleFunc("DELETE /api/comments/{commentID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteComment))
+
+	mux.HandleFunc("GET /api/webmentions", apiCfg.middlewareMustBeLoggedIn(apiCfg.getWebmentions))
+	mux.HandleFunc("DELETE /api/webmentions/{webmentionID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteWebmention))
+
+	mux.HandleFunc("GET /api/webhooks", apiCfg.middlewareMustBeLoggedIn(apiCfg.getWebhooks))
+	mux.HandleFunc("POST /api/webhooks", apiCfg.middlewareMustBeLoggedIn(apiCfg.createWebhook))
+	mux.HandleFunc("PUT /api/webhooks/{webhookID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.updateWebhook))
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteWebhook))
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.middlewareMustBeLoggedIn(apiCfg.getWebhookDeliveries))
+
+	mux.HandleFunc("GET /api/jobs", apiCfg.middlewareMustBeLoggedIn(apiCfg.getJobs))
+	mux.HandleFunc("POST /api/jobs/{jobID}/retry", apiCfg.middlewareMustBeLoggedIn(apiCfg.retryJob))
+	mux.HandleFunc("DELETE /api/jobs/{jobID}", apiCfg.middlewareMustBeLoggedIn(apiCfg.deleteJob))
+
+	mux.HandleFunc("GET /api/stats", apiCfg.middlewareMustBeLoggedIn(apiCfg.getStats))
+
+	mux.HandleFunc("GET /api/newsletter", apiCfg.middlewareMustBeLoggedIn(apiCfg.getNewsletter))
+	mux.HandleFunc("POST /api/newsletter/sends", apiCfg.middlewareMustBeLoggedIn(apiCfg.sendNewsletter))
+
+	mux.HandleFunc("POST /api/micropub/token", apiCfg.middlewareMustBeLoggedIn(apiCfg.createMicropubToken))
+
 	mux.HandleFunc("GET /api/tags", apiCfg.searchTags)
 
+	mux.HandleFunc("GET /api/settings", apiCfg.getSettings)
+	mux.HandleFunc("PUT /api/settings", apiCfg.middlewareMustBeLoggedIn(apiCfg.updateSettings))
+
 	mux.HandleFunc("POST /api/users", apiCfg.handleCreateUser)
 
 	mux.HandleFunc("PUT /api/me", apiCfg.middlewareMustBeLoggedIn(apiCfg.editUserInfo))
@@ -300,5 +576,9 @@ func SetupRoutes() *http.ServeMux {
 	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handleRevokeToken)
 
-	return mux
+	shutdown := func(ctx context.Context) error {
+		stopWorkers()
+		return apiCfg.jobs.Shutdown(ctx)
+	}
+	return middlewareLogging(apiCfg.metrics.middleware(mux)), shutdown
 }
@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+type SocialLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type SiteSettings struct {
+	SiteTitle      string       `json:"site_title"`
+	Tagline        string       `json:"tagline"`
+	FooterText     string       `json:"footer_text"`
+	SocialLinks    []SocialLink `json:"social_links"`
+	DefaultOGImage string       `json:"default_og_image"`
+	PostsPerPage   int          `json:"posts_per_page"`
+	Timezone       string       `json:"timezone"`
+	RobotsTxt      string       `json:"robots_txt"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+
+	// location is Timezone, resolved once when the settings are loaded
+	location *time.Location
+}
+
+// defaultSiteSettings is used until the site_settings row can be read.
+var defaultSiteSettings = SiteSettings{
+	SiteTitle:    "My Journal",
+	FooterText:   "Built with passion ❤️.",
+	SocialLinks:  []SocialLink{},
+	PostsPerPage: 20,
+	Timezone:     "UTC",
+	location:     time.UTC,
+}
+
+// Location returns the configured timezone, falling back to UTC if the
+// stored name is unknown.
+func (s SiteSettings) Location() *time.Location {
+	if s.location != nil {
+		return s.location
+	}
+	return loadLocation(s.Timezone)
+}
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func siteSettingsFromDB(row database.SiteSetting) SiteSettings {
+	links := []SocialLink{}
+	// A malformed column shouldn't take the site down; render without links
+	_ = json.Unmarshal([]byte(row.SocialLinks), &links)
+
+	return SiteSettings{
+		SiteTitle:      row.SiteTitle,
+		Tagline:        row.Tagline,
+		FooterText:     row.FooterText,
+		SocialLinks:    links,
+		DefaultOGImage: row.DefaultOgImage,
+		PostsPerPage:   int(row.PostsPerPage),
+		Timezone:       row.Timezone,
+		RobotsTxt:      row.RobotsTxt,
+		UpdatedAt:      row.UpdatedAt.Time,
+		location:       loadLocation(row.Timezone),
+	}
+}
+
+// settingsCache keeps the site settings in memory so every page render
+// doesn't hit the database. Call invalidate after writing new settings.
+type settingsCache struct {
+	db *database.Queries
+
+	mu     sync.RWMutex
+	cached *SiteSettings
+	// generation counts invalidations, so a load that started before one
+	// doesn't cache the row it read.
+	generation uint64
+}
+
+func newSettingsCache(db *database.Queries) *settingsCache {
+	return &settingsCache{db: db}
+}
+
+func (c *settingsCache) get(ctx context.Context) (SiteSettings, error) {
+	c.mu.RLock()
+	cached, generation := c.cached, c.generation
+	c.mu.RUnlock()
+
+	if cached != nil {
+		return *cached, nil
+	}
+
+	row, err := c.db.GetSiteSettings(ctx)
+	if err != nil {
+		return defaultSiteSettings, err
+	}
+
+	settings := siteSettingsFromDB(row)
+	c.store(generation, settings)
+	return settings, nil
+}
+
+// store caches settings read during generation, unless the cache has
+// been invalidated since.
+func (c *settingsCache) store(generation uint64, settings SiteSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.cached = &settings
+	}
+}
+
+func (c *settingsCache) invalidate() {
+	c.mu.Lock()
+	c.cached = nil
+	c.generation++
+	c.mu.Unlock()
+}
+
+// siteSettings returns the cached settings, or the defaults when they
+// cannot be loaded.
+func (cfg *apiConfig) siteSettings(ctx context.Context) SiteSettings {
+	if cfg.settings == nil {
+		return defaultSiteSettings
+	}
+
+	settings, err := cfg.settings.get(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load site settings, using defaults", "error", err)
+	}
+	return settings
+}
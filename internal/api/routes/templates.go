@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// truncate shortens s to at most n runes, appending suffix when it cuts.
+func truncate(s string, n int, suffix string) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:n]) + suffix
+}
+
+// inLocation converts t to loc for display in templates.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// add sums its arguments, e.g. to number a range from 1.
+func add(a, b int) int {
+	return a + b
+}
+
+// baseTemplateData builds the data shared by every public page: the site
+// settings and the owner's profile. Handlers add their own keys on top.
+func (cfg *apiConfig) baseTemplateData(ctx context.Context, title, currentPage string) map[string]interface{} {
+	settings := cfg.siteSettings(ctx)
+	loc := settings.Location()
+
+	data := map[string]interface{}{
+		"Title":       title,
+		"CurrentPage": currentPage,
+		"Settings":    settings,
+		"SiteTitle":   settings.SiteTitle,
+		"Tagline":     settings.Tagline,
+		"FooterText":  settings.FooterText,
+		"SocialLinks": settings.SocialLinks,
+		"Location":    loc,
+		"Name":        "Your Name",
+		"Year":        time.Now().In(loc).Year(),
+	}
+
+	user, err := cfg.DB.ListUser(ctx)
+	if err != nil || len(user) == 0 {
+		return data
+	}
+
+	data["Name"] = user[0].Name
+	data["Email"] = user[0].Email.String
+	data["Github"] = user[0].Github.String
+	data["Linkedin"] = user[0].Linkedin.String
+	data["Bio"] = strings.TrimSpace(user[0].Bio.String)
+
+	return data
+}
@@ -0,0 +1,13 @@
+package routes
+
+import "time"
+
+// sqliteTimestampFormat is how CURRENT_TIMESTAMP writes a DATETIME column.
+const sqliteTimestampFormat = "2006-01-02 15:04:05"
+
+// sqliteTimestamp formats t to compare against columns filled by
+// CURRENT_TIMESTAMP. Text comparison only orders correctly when both sides
+// use the same layout and zone, so t is converted to UTC.
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format(sqliteTimestampFormat)
+}
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"crypto/rand"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+// Journal visibility. Public entries are listed everywhere; unlisted ones
+// only open through their share link; private ones only for their owner.
+const (
+	visibilityPublic   = "public"
+	visibilityUnlisted = "unlisted"
+	visibilityPrivate  = "private"
+)
+
+func validVisibility(v string) bool {
+	switch v {
+	case visibilityPublic, visibilityUnlisted, visibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// canViewJournal reports whether viewerID (0 when anonymous) may open
+// journal by its ID. Unlisted entries are hidden here too: outside their
+// owner, they are reachable only through the share link.
+func canViewJournal(journal database.JournalEntry, viewerID int) bool {
+	return journal.Visibility == visibilityPublic || (viewerID != 0 && journal.UserID == int64(viewerID))
+}
+
+// sharePath is the link that opens an unlisted entry.
+func sharePath(token string) string {
+	return "/shared/" + token
+}
+
+// shareTokenFor keeps an entry's existing share token, or issues one the
+// first time it becomes unlisted.
+func shareTokenFor(visibility, current string) string {
+	if current != "" || visibility != visibilityUnlisted {
+		return current
+	}
+	return rand.Text()
+}
@@ -7,22 +7,47 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createJournalEntry = `-- name: CreateJournalEntry :one
-INSERT INTO journal_entries (title, content, user_id)
-VALUES (?, ?, ?)
-RETURNING id, title, content, created_at, updated_at, user_id
+INSERT INTO journal_entries (title, content, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, visibility, share_token, encrypted)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, title, content, created_at, updated_at, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, deleted_at, visibility, share_token, encrypted
 `
 
 type CreateJournalEntryParams struct {
-	Title   string
-	Content string
-	UserID  int64
+	Title          string
+	Content        string
+	UserID         int64
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	Format         string
+	ContentHtml    string
+	WordCount      int64
+	ReadingMinutes int64
+	Excerpt        string
+	Visibility     string
+	ShareToken     sql.NullString
+	Encrypted      bool
 }
 
 func (q *Queries) CreateJournalEntry(ctx context.Context, arg CreateJournalEntryParams) (JournalEntry, error) {
-	row := q.db.QueryRowContext(ctx, createJournalEntry, arg.Title, arg.Content, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createJournalEntry,
+		arg.Title,
+		arg.Content,
+		arg.UserID,
+		arg.SeoTitle,
+		arg.SeoDescription,
+		arg.Format,
+		arg.ContentHtml,
+		arg.WordCount,
+		arg.ReadingMinutes,
+		arg.Excerpt,
+		arg.Visibility,
+		arg.ShareToken,
+		arg.Encrypted,
+	)
 	var i JournalEntry
 	err := row.Scan(
 		&i.ID,
@@ -31,39 +56,66 @@ func (q *Queries) CreateJournalEntry(ctx context.Context, arg CreateJournalEntry
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
+		&i.Format,
+		&i.ContentHtml,
+		&i.WordCount,
+		&i.ReadingMinutes,
+		&i.Excerpt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.Encrypted,
 	)
 	return i, err
 }
 
-const deleteJournalEntry = `-- name: DeleteJournalEntry :exec
-DELETE FROM journal_entries
-WHERE id = ? AND user_id = ?
-`
-
-type DeleteJournalEntryParams struct {
-	ID     int64
-	UserID int64
-}
-
-func (q *Queries) DeleteJournalEntry(ctx context.Context, arg DeleteJournalEntryParams) error {
-	_, err := q.db.ExecContext(ctx, deleteJournalEntry, arg.ID, arg.UserID)
-	return err
-}
-
 const getAllJournalsCount = `-- name: GetAllJournalsCount :one
 SELECT COUNT(*) as count FROM journal_entries
+WHERE deleted_at IS NULL AND (visibility = 'public' OR user_id = ?1)
 `
 
-func (q *Queries) GetAllJournalsCount(ctx context.Context) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getAllJournalsCount)
+func (q *Queries) GetAllJournalsCount(ctx context.Context, viewerID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAllJournalsCount, viewerID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const getJournalByShareToken = `-- name: GetJournalByShareToken :one
+SELECT id, title, content, created_at, updated_at, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, deleted_at, visibility, share_token, encrypted FROM journal_entries
+WHERE share_token = ? AND visibility = 'unlisted' AND deleted_at IS NULL
+`
+
+func (q *Queries) GetJournalByShareToken(ctx context.Context, shareToken sql.NullString) (JournalEntry, error) {
+	row := q.db.QueryRowContext(ctx, getJournalByShareToken, shareToken)
+	var i JournalEntry
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
+		&i.Format,
+		&i.ContentHtml,
+		&i.WordCount,
+		&i.ReadingMinutes,
+		&i.Excerpt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.Encrypted,
+	)
+	return i, err
+}
+
 const getJournalEntry = `-- name: GetJournalEntry :one
-SELECT id, title, content, created_at, updated_at, user_id FROM journal_entries
-WHERE id = ?
+SELECT id, title, content, created_at, updated_at, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, deleted_at, visibility, share_token, encrypted FROM journal_entries
+WHERE id = ? AND deleted_at IS NULL
 `
 
 func (q *Queries) GetJournalEntry(ctx context.Context, id int64) (JournalEntry, error) {
@@ -76,23 +128,38 @@ func (q *Queries) GetJournalEntry(ctx context.Context, id int64) (JournalEntry,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
+		&i.Format,
+		&i.ContentHtml,
+		&i.WordCount,
+		&i.ReadingMinutes,
+		&i.Excerpt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.Encrypted,
 	)
 	return i, err
 }
 
 const getJournals = `-- name: GetJournals :many
-SELECT id, title, content, created_at, updated_at, user_id FROM journal_entries
+SELECT id, title, content, created_at, updated_at, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, deleted_at, visibility, share_token, encrypted FROM journal_entries
+WHERE deleted_at IS NULL AND (visibility = 'public' OR user_id = ?1)
 ORDER BY id DESC
-LIMIT ? OFFSET ?
+LIMIT ?3 OFFSET ?2
 `
 
 type GetJournalsParams struct {
-	Limit  int64
-	Offset int64
+	ViewerID int64
+	Offset   int64
+	Limit    int64
 }
 
+// Lists public entries, plus every entry owned by viewer_id. Pass 0 for
+// anonymous visitors.
 func (q *Queries) GetJournals(ctx context.Context, arg GetJournalsParams) ([]JournalEntry, error) {
-	rows, err := q.db.QueryContext(ctx, getJournals, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, getJournals, arg.ViewerID, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +174,17 @@ func (q *Queries) GetJournals(ctx context.Context, arg GetJournalsParams) ([]Jou
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserID,
+			&i.SeoTitle,
+			&i.SeoDescription,
+			&i.Format,
+			&i.ContentHtml,
+			&i.WordCount,
+			&i.ReadingMinutes,
+			&i.Excerpt,
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.ShareToken,
+			&i.Encrypted,
 		); err != nil {
 			return nil, err
 		}
@@ -128,6 +206,7 @@ WITH ordered AS (
     LAG(id) OVER (ORDER BY id) AS previous_id,
     LEAD(id) OVER (ORDER BY id) AS next_id
   FROM journal_entries
+  WHERE deleted_at IS NULL AND visibility = 'public'
 )
 SELECT id, previous_id, next_id FROM ordered WHERE id = ?
 `
@@ -146,8 +225,8 @@ func (q *Queries) GetPrevAndNextJournalIDs(ctx context.Context, id int64) (GetPr
 }
 
 const getUsersJournal = `-- name: GetUsersJournal :one
-SELECT id, title, content, created_at, updated_at, user_id FROM journal_entries
-WHERE id = ? AND user_id = ?
+SELECT id, title, content, created_at, updated_at, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, deleted_at, visibility, share_token, encrypted FROM journal_entries
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL
 `
 
 type GetUsersJournalParams struct {
@@ -165,25 +244,130 @@ func (q *Queries) GetUsersJournal(ctx context.Context, arg GetUsersJournalParams
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
+		&i.Format,
+		&i.ContentHtml,
+		&i.WordCount,
+		&i.ReadingMinutes,
+		&i.Excerpt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.ShareToken,
+		&i.Encrypted,
 	)
 	return i, err
 }
 
+const setJournalRendered = `-- name: SetJournalRendered :exec
+UPDATE journal_entries
+set content_html = ?,
+word_count = ?,
+reading_minutes = ?,
+excerpt = ?
+WHERE id = ?
+`
+
+type SetJournalRenderedParams struct {
+	ContentHtml    string
+	WordCount      int64
+	ReadingMinutes int64
+	Excerpt        string
+	ID             int64
+}
+
+func (q *Queries) SetJournalRendered(ctx context.Context, arg SetJournalRenderedParams) error {
+	_, err := q.db.ExecContext(ctx, setJournalRendered,
+		arg.ContentHtml,
+		arg.WordCount,
+		arg.ReadingMinutes,
+		arg.Excerpt,
+		arg.ID,
+	)
+	return err
+}
+
+const setJournalShareToken = `-- name: SetJournalShareToken :exec
+UPDATE journal_entries
+set share_token = ?
+WHERE id = ? AND deleted_at IS NULL
+`
+
+type SetJournalShareTokenParams struct {
+	ShareToken sql.NullString
+	ID         int64
+}
+
+func (q *Queries) SetJournalShareToken(ctx context.Context, arg SetJournalShareTokenParams) error {
+	_, err := q.db.ExecContext(ctx, setJournalShareToken, arg.ShareToken, arg.ID)
+	return err
+}
+
+const trashJournalEntry = `-- name: TrashJournalEntry :exec
+UPDATE journal_entries
+set deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+`
+
+type TrashJournalEntryParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) TrashJournalEntry(ctx context.Context, arg TrashJournalEntryParams) error {
+	_, err := q.db.ExecContext(ctx, trashJournalEntry, arg.ID, arg.UserID)
+	return err
+}
+
 const updateJournalEntry = `-- name: UpdateJournalEntry :exec
 UPDATE journal_entries
 set title = ?,
 content = ?,
+seo_title = ?,
+seo_description = ?,
+format = ?,
+content_html = ?,
+word_count = ?,
+reading_minutes = ?,
+excerpt = ?,
+visibility = ?,
+share_token = ?,
+encrypted = ?,
 updated_at = CURRENT_TIMESTAMP
-WHERE id = ?
+WHERE id = ? AND deleted_at IS NULL
 `
 
 type UpdateJournalEntryParams struct {
-	Title   string
-	Content string
-	ID      int64
+	Title          string
+	Content        string
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	Format         string
+	ContentHtml    string
+	WordCount      int64
+	ReadingMinutes int64
+	Excerpt        string
+	Visibility     string
+	ShareToken     sql.NullString
+	Encrypted      bool
+	ID             int64
 }
 
 func (q *Queries) UpdateJournalEntry(ctx context.Context, arg UpdateJournalEntryParams) error {
-	_, err := q.db.ExecContext(ctx, updateJournalEntry, arg.Title, arg.Content, arg.ID)
+	_, err := q.db.ExecContext(ctx, updateJournalEntry,
+		arg.Title,
+		arg.Content,
+		arg.SeoTitle,
+		arg.SeoDescription,
+		arg.Format,
+		arg.ContentHtml,
+		arg.WordCount,
+		arg.ReadingMinutes,
+		arg.Excerpt,
+		arg.Visibility,
+		arg.ShareToken,
+		arg.Encrypted,
+		arg.ID,
+	)
 	return err
 }
@@ -9,26 +9,125 @@ import (
 	"time"
 )
 
+type ActivitypubFollower struct {
+	ID          int64
+	ActorID     string
+	Inbox       string
+	SharedInbox string
+	FollowID    string
+	CreatedAt   sql.NullTime
+}
+
+type ActivitypubKey struct {
+	ID            int64
+	PrivateKeyPem string
+	PublicKeyPem  string
+	CreatedAt     sql.NullTime
+}
+
+type AnalyticsSalt struct {
+	Day  string
+	Salt string
+}
+
+type Comment struct {
+	ID          int64
+	JournalID   int64
+	ParentID    sql.NullInt64
+	AuthorName  string
+	AuthorEmail string
+	AuthorUrl   string
+	Body        string
+	Status      string
+	IsOwner     bool
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     string
+	Status      string
+	Attempts    int64
+	MaxAttempts int64
+	RunAt       time.Time
+	LockedUntil sql.NullTime
+	LastError   string
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
 type JournalEntry struct {
+	ID             int64
+	Title          string
+	Content        string
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	UserID         int64
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	Format         string
+	ContentHtml    string
+	WordCount      int64
+	ReadingMinutes int64
+	Excerpt        string
+	DeletedAt      sql.NullTime
+	Visibility     string
+	ShareToken     sql.NullString
+	Encrypted      bool
+}
+
+type JournalTag struct {
+	JournalID int64
+	TagID     int64
+}
+
+type NewsletterSend struct {
+	ID               int64
+	Subject          string
+	Since            time.Time
+	Until            time.Time
+	EntryCount       int64
+	Status           string
+	LastSubscriberID int64
+	DeliveredCount   int64
+	FailedCount      int64
+	CreatedAt        sql.NullTime
+	FinishedAt       sql.NullTime
+}
+
+type PageView struct {
 	ID        int64
-	Title     string
-	Content   string
+	Day       string
+	Path      string
+	Referrer  string
+	Visitor   string
 	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
-	UserID    int64
+}
+
+type PageViewRollup struct {
+	Day       string
+	Dimension string
+	Value     string
+	Views     int64
+	Visitors  int64
 }
 
 type Project struct {
-	ID          int64
-	Title       string
-	Description string
-	ImageUrl    sql.NullString
-	Link        sql.NullString
-	Github      sql.NullString
-	Status      sql.NullString
-	CreatedAt   sql.NullTime
-	UpdatedAt   sql.NullTime
-	UserID      int64
+	ID             int64
+	Title          string
+	Description    string
+	ImageUrl       sql.NullString
+	Link           sql.NullString
+	Github         sql.NullString
+	Status         sql.NullString
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	UserID         int64
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	DeletedAt      sql.NullTime
 }
 
 type ProjectTag struct {
@@ -45,6 +144,46 @@ type RefreshToken struct {
 	RevokedAt time.Time
 }
 
+type Series struct {
+	ID          int64
+	Title       string
+	Description string
+	UserID      int64
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type SeriesEntry struct {
+	SeriesID  int64
+	JournalID int64
+	Position  int64
+}
+
+type SiteSetting struct {
+	ID             int64
+	SiteTitle      string
+	Tagline        string
+	FooterText     string
+	SocialLinks    string
+	DefaultOgImage string
+	PostsPerPage   int64
+	Timezone       string
+	UpdatedAt      sql.NullTime
+	RobotsTxt      string
+}
+
+type Subscriber struct {
+	ID               int64
+	Email            string
+	Status           string
+	ConfirmToken     string
+	UnsubscribeToken string
+	ConfirmedAt      sql.NullTime
+	UnsubscribedAt   sql.NullTime
+	CreatedAt        sql.NullTime
+	UpdatedAt        sql.NullTime
+}
+
 type Tag struct {
 	ID        int64
 	Name      string
@@ -62,3 +201,43 @@ type User struct {
 	Github    sql.NullString
 	Linkedin  sql.NullString
 }
+
+type Webhook struct {
+	ID          int64
+	Url         string
+	Secret      string
+	Events      string
+	Description string
+	Active      bool
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int64
+	ResponseStatus int64
+	ResponseBody   string
+	LastError      string
+	DeliveredAt    sql.NullTime
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	JobID          sql.NullInt64
+}
+
+type Webmention struct {
+	ID         int64
+	JournalID  int64
+	Source     string
+	Target     string
+	Status     string
+	Title      string
+	AuthorName string
+	VerifiedAt sql.NullTime
+	CreatedAt  sql.NullTime
+	UpdatedAt  sql.NullTime
+}
@@ -11,19 +11,21 @@ import (
 )
 
 const createProject = `-- name: CreateProject :one
-INSERT INTO projects (title, description, image_url, link, github, status, user_id)
-VALUES (?, ?, ?, ?, ?, ?, ?)
-RETURNING id, title, description, image_url, link, github, status, created_at, updated_at, user_id
+INSERT INTO projects (title, description, image_url, link, github, status, user_id, seo_title, seo_description)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id, title, description, image_url, link, github, status, created_at, updated_at, user_id, seo_title, seo_description, deleted_at
 `
 
 type CreateProjectParams struct {
-	Title       string
-	Description string
-	ImageUrl    sql.NullString
-	Link        sql.NullString
-	Github      sql.NullString
-	Status      sql.NullString
-	UserID      int64
+	Title          string
+	Description    string
+	ImageUrl       sql.NullString
+	Link           sql.NullString
+	Github         sql.NullString
+	Status         sql.NullString
+	UserID         int64
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
 }
 
 func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
@@ -35,6 +37,8 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		arg.Github,
 		arg.Status,
 		arg.UserID,
+		arg.SeoTitle,
+		arg.SeoDescription,
 	)
 	var i Project
 	err := row.Scan(
@@ -48,20 +52,13 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteProject = `-- name: DeleteProject :exec
-DELETE FROM projects
-WHERE id = ?
-`
-
-func (q *Queries) DeleteProject(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteProject, id)
-	return err
-}
-
 const getProject = `-- name: GetProject :one
 SELECT
   projects.id as project_id,
@@ -72,28 +69,34 @@ SELECT
   projects.github,
   projects.status,
   projects.created_at,
+  projects.updated_at,
   projects.user_id,
+  projects.seo_title,
+  projects.seo_description,
   tags.id as tag_id,
-  GROUP_CONCAT(tags.name, ', ') as tags
+  CAST(COALESCE(GROUP_CONCAT(tags.name, ', '), '') AS TEXT) as tags
 FROM projects
 LEFT JOIN project_tags ON projects.id = project_tags.project_id
 LEFT JOIN tags ON project_tags.tag_id = tags.id
-WHERE projects.id = ?
+WHERE projects.id = ? AND projects.deleted_at IS NULL
 ORDER BY projects.created_at DESC
 `
 
 type GetProjectRow struct {
-	ProjectID   int64
-	Title       string
-	Description string
-	ImageUrl    sql.NullString
-	Link        sql.NullString
-	Github      sql.NullString
-	Status      sql.NullString
-	CreatedAt   sql.NullTime
-	UserID      int64
-	TagID       sql.NullInt64
-	Tags        string
+	ProjectID      int64
+	Title          string
+	Description    string
+	ImageUrl       sql.NullString
+	Link           sql.NullString
+	Github         sql.NullString
+	Status         sql.NullString
+	CreatedAt      sql.NullTime
+	UpdatedAt      sql.NullTime
+	UserID         int64
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	TagID          sql.NullInt64
+	Tags           string
 }
 
 func (q *Queries) GetProject(ctx context.Context, id int64) (GetProjectRow, error) {
@@ -108,7 +111,10 @@ func (q *Queries) GetProject(ctx context.Context, id int64) (GetProjectRow, erro
 		&i.Github,
 		&i.Status,
 		&i.CreatedAt,
+		&i.UpdatedAt,
 		&i.UserID,
+		&i.SeoTitle,
+		&i.SeoDescription,
 		&i.TagID,
 		&i.Tags,
 	)
@@ -116,7 +122,8 @@ func (q *Queries) GetProject(ctx context.Context, id int64) (GetProjectRow, erro
 }
 
 const getProjects = `-- name: GetProjects :many
-SELECT id, title, description, image_url, link, github, status, created_at, updated_at, user_id FROM projects 
+SELECT id, title, description, image_url, link, github, status, created_at, updated_at, user_id, seo_title, seo_description, deleted_at FROM projects
+WHERE deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT ? OFFSET ?
 `
@@ -146,6 +153,61 @@ func (q *Queries) GetProjects(ctx context.Context, arg GetProjectsParams) ([]Pro
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserID,
+			&i.SeoTitle,
+			&i.SeoDescription,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectsByTag = `-- name: GetProjectsByTag :many
+SELECT projects.id, projects.title, projects.description, projects.image_url, projects.link, projects.github, projects.status, projects.created_at, projects.updated_at, projects.user_id, projects.seo_title, projects.seo_description, projects.deleted_at FROM projects
+JOIN project_tags ON project_tags.project_id = projects.id
+JOIN tags ON tags.id = project_tags.tag_id
+WHERE tags.name = ? AND projects.deleted_at IS NULL
+ORDER BY projects.created_at DESC
+LIMIT ? OFFSET ?
+`
+
+type GetProjectsByTagParams struct {
+	Name   string
+	Limit  int64
+	Offset int64
+}
+
+func (q *Queries) GetProjectsByTag(ctx context.Context, arg GetProjectsByTagParams) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, getProjectsByTag, arg.Name, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.ImageUrl,
+			&i.Link,
+			&i.Github,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.SeoTitle,
+			&i.SeoDescription,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -160,8 +222,23 @@ func (q *Queries) GetProjects(ctx context.Context, arg GetProjectsParams) ([]Pro
 	return items, nil
 }
 
+const getProjectsByTagCount = `-- name: GetProjectsByTagCount :one
+SELECT COUNT(*) as count FROM projects
+JOIN project_tags ON project_tags.project_id = projects.id
+JOIN tags ON tags.id = project_tags.tag_id
+WHERE tags.name = ? AND projects.deleted_at IS NULL
+`
+
+func (q *Queries) GetProjectsByTagCount(ctx context.Context, name string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getProjectsByTagCount, name)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const getProjectsCount = `-- name: GetProjectsCount :one
 SELECT COUNT(*) as count FROM projects
+WHERE deleted_at IS NULL
 `
 
 func (q *Queries) GetProjectsCount(ctx context.Context) (int64, error) {
@@ -178,6 +255,7 @@ WITH ordered AS (
     LAG(id) OVER (ORDER BY id) AS previous_id,
     LEAD(id) OVER (ORDER BY id) AS next_id
   FROM projects
+  WHERE deleted_at IS NULL
 )
 SELECT id, previous_id, next_id FROM ordered WHERE id = ?
 `
@@ -195,6 +273,17 @@ func (q *Queries) GetProjectsNextAndPrevious(ctx context.Context, id int64) (Get
 	return i, err
 }
 
+const trashProject = `-- name: TrashProject :exec
+UPDATE projects
+set deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) TrashProject(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, trashProject, id)
+	return err
+}
+
 const updateProject = `-- name: UpdateProject :exec
 UPDATE projects
 set title = ?,
@@ -202,18 +291,23 @@ description = ?,
 image_url = ?,
 link = ?,
 github = ?,
-status = ?
-WHERE id = ?
+status = ?,
+seo_title = ?,
+seo_description = ?,
+updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL
 `
 
 type UpdateProjectParams struct {
-	Title       string
-	Description string
-	ImageUrl    sql.NullString
-	Link        sql.NullString
-	Github      sql.NullString
-	Status      sql.NullString
-	ID          int64
+	Title          string
+	Description    string
+	ImageUrl       sql.NullString
+	Link           sql.NullString
+	Github         sql.NullString
+	Status         sql.NullString
+	SeoTitle       sql.NullString
+	SeoDescription sql.NullString
+	ID             int64
 }
 
 func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) error {
@@ -224,6 +318,8 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) er
 		arg.Link,
 		arg.Github,
 		arg.Status,
+		arg.SeoTitle,
+		arg.SeoDescription,
 		arg.ID,
 	)
 	return err
@@ -0,0 +1,115 @@
+// Package envelope checks the format of journal entries that were encrypted
+// in the browser. The server never sees the passphrase or the key, so it can
+// only confirm that content looks like a well-formed envelope; it cannot
+// decrypt it.
+//
+// An envelope is a JSON object:
+//
+//	{"v":1,"kdf":"argon2id","m":65536,"t":3,"p":1,"salt":"…",
+//	 "alg":"A256GCM","iv":"…","ct":"…"}
+//
+// The key is derived with Argon2id from the passphrase and salt using m KiB
+// of memory, t passes and p lanes, and the content is sealed with
+// AES-256-GCM. salt, iv and ct are standard base64; ct carries the GCM tag.
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	Version = 1
+	KDF     = "argon2id"
+	Alg     = "A256GCM"
+)
+
+// Argon2id bounds. The minimums follow the OWASP recommendation; the
+// maximums stop a crafted envelope from making the browser hang.
+const (
+	MinMemory      = 19 * 1024
+	MaxMemory      = 1024 * 1024
+	MinIterations  = 2
+	MaxIterations  = 10
+	MinParallelism = 1
+	MaxParallelism = 16
+)
+
+const (
+	minSaltLen = 16
+	maxSaltLen = 64
+	ivLen      = 12
+	tagLen     = 16
+)
+
+// Envelope is the decoded form of an encrypted entry's content.
+type Envelope struct {
+	Version     int    `json:"v"`
+	KDF         string `json:"kdf"`
+	Memory      uint32 `json:"m"`
+	Iterations  uint32 `json:"t"`
+	Parallelism uint8  `json:"p"`
+	Salt        []byte `json:"salt"`
+	Alg         string `json:"alg"`
+	IV          []byte `json:"iv"`
+	Ciphertext  []byte `json:"ct"`
+}
+
+// ErrInvalid wraps every validation failure.
+var ErrInvalid = errors.New("invalid encryption envelope")
+
+// Parse decodes and validates content as an envelope.
+func Parse(content string) (Envelope, error) {
+	var env Envelope
+
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&env); err != nil {
+		return Envelope{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if dec.More() {
+		return Envelope{}, fmt.Errorf("%w: trailing data", ErrInvalid)
+	}
+
+	if err := env.Validate(); err != nil {
+		return Envelope{}, err
+	}
+	return env, nil
+}
+
+// Validate reports whether env uses a supported version, algorithms and
+// parameters.
+func (env Envelope) Validate() error {
+	switch {
+	case env.Version != Version:
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalid, env.Version)
+	case env.KDF != KDF:
+		return fmt.Errorf("%w: kdf must be %s", ErrInvalid, KDF)
+	case env.Alg != Alg:
+		return fmt.Errorf("%w: alg must be %s", ErrInvalid, Alg)
+	case env.Memory < MinMemory || env.Memory > MaxMemory:
+		return fmt.Errorf("%w: m must be between %d and %d KiB", ErrInvalid, MinMemory, MaxMemory)
+	case env.Iterations < MinIterations || env.Iterations > MaxIterations:
+		return fmt.Errorf("%w: t must be between %d and %d", ErrInvalid, MinIterations, MaxIterations)
+	case env.Parallelism < MinParallelism || env.Parallelism > MaxParallelism:
+		return fmt.Errorf("%w: p must be between %d and %d", ErrInvalid, MinParallelism, MaxParallelism)
+	case len(env.Salt) < minSaltLen || len(env.Salt) > maxSaltLen:
+		return fmt.Errorf("%w: salt must be %d to %d bytes", ErrInvalid, minSaltLen, maxSaltLen)
+	case len(env.IV) != ivLen:
+		return fmt.Errorf("%w: iv must be %d bytes", ErrInvalid, ivLen)
+	case len(env.Ciphertext) < tagLen:
+		return fmt.Errorf("%w: ct is shorter than the authentication tag", ErrInvalid)
+	}
+	return nil
+}
+
+// String encodes env in its canonical JSON form.
+func (env Envelope) String() string {
+	b, err := json.Marshal(env)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
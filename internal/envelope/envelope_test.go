@@ -0,0 +1,99 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// seal builds an envelope the way the admin editor does, so the test
+// catches any drift between the documented format and the validator.
+func seal(t *testing.T, passphrase, plaintext string) Envelope {
+	t.Helper()
+
+	env := Envelope{
+		Version:     Version,
+		KDF:         KDF,
+		Memory:      MinMemory,
+		Iterations:  MinIterations,
+		Parallelism: 1,
+		Salt:        []byte("0123456789abcdef"),
+		Alg:         Alg,
+		IV:          []byte("nonce-12byte"),
+	}
+	key := argon2.IDKey([]byte(passphrase), env.Salt, env.Iterations, env.Memory, env.Parallelism, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Ciphertext = gcm.Seal(nil, env.IV, []byte(plaintext), nil)
+	return env
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	sealed := seal(t, "correct horse", "dear diary")
+
+	env, err := Parse(sealed.String())
+	if err != nil {
+		t.Fatalf("Expected a sealed envelope to parse, got %v", err)
+	}
+
+	key := argon2.IDKey([]byte("correct horse"), env.Salt, env.Iterations, env.Memory, env.Parallelism, 32)
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, env.IV, env.Ciphertext, nil)
+	if err != nil || string(plaintext) != "dear diary" {
+		t.Errorf("Expected the parsed envelope to decrypt, got %q, %v", plaintext, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	valid := seal(t, "pass", "text")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"plaintext", "dear diary"},
+		{"empty object", "{}"},
+		{"unknown field", strings.Replace(valid.String(), `"v":1`, `"v":1,"extra":true`, 1)},
+		{"trailing data", valid.String() + "{}"},
+		{"bad base64", strings.Replace(valid.String(), `"iv":"`, `"iv":"!`, 1)},
+		{"version", strings.Replace(valid.String(), `"v":1`, `"v":2`, 1)},
+		{"kdf", strings.Replace(valid.String(), `"argon2id"`, `"scrypt"`, 1)},
+		{"alg", strings.Replace(valid.String(), `"A256GCM"`, `"A128CBC"`, 1)},
+		{"weak memory", strings.Replace(valid.String(), `"m":19456`, `"m":1024`, 1)},
+		{"huge memory", strings.Replace(valid.String(), `"m":19456`, `"m":4194304`, 1)},
+		{"one pass", strings.Replace(valid.String(), `"t":2`, `"t":1`, 1)},
+		{"no lanes", strings.Replace(valid.String(), `"p":1`, `"p":0`, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.content); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+
+	short := valid
+	short.Salt = short.Salt[:8]
+	if err := short.Validate(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a short salt to be rejected, got %v", err)
+	}
+
+	truncated := valid
+	truncated.Ciphertext = truncated.Ciphertext[:tagLen-1]
+	if err := truncated.Validate(); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected a truncated ciphertext to be rejected, got %v", err)
+	}
+}
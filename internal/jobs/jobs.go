@@ -0,0 +1,453 @@
+// Package jobs runs background work stored in the jobs table, so slow or
+// flaky tasks happen outside the request that asked for them and survive
+// restarts.
+//
+// Each kind of job is registered with a typed handler and enqueued with a
+// payload of that type, stored as JSON. A pool of workers claims due jobs,
+// retries failures with exponential backoff and, once a job's attempts
+// run out or it fails permanently, leaves it in the dead state for
+// someone to look at:
+//
+//	queue := jobs.New(queries, jobs.Options{Concurrency: 4})
+//	resize := jobs.Register(queue, "image.resize", func(ctx context.Context, p ResizePayload) error {
+//		...
+//	})
+//	queue.Start(ctx)
+//	defer queue.Shutdown(shutdownCtx)
+//
+//	resize.Enqueue(ctx, ResizePayload{Path: path}, jobs.RunAt(later))
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sianwa11/my-journal/internal/database"
+)
+
+// Job states.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDead    = "dead"
+)
+
+// timestampFormat is how CURRENT_TIMESTAMP writes a DATETIME column; run_at
+// and locked_until are compared as text against values in this layout.
+const timestampFormat = "2006-01-02 15:04:05"
+
+// maxErrorLength caps how much of a failure is kept in last_error.
+const maxErrorLength = 500
+
+// Options configure a Queue. Zero fields take the defaults below.
+type Options struct {
+	// Concurrency is how many jobs run at once. Defaults to 2.
+	Concurrency int
+
+	// PollInterval is how often idle workers look for jobs that have come
+	// due. Enqueueing wakes a worker straight away. Defaults to 15s.
+	PollInterval time.Duration
+
+	// Lease is how long a claimed job may run before another worker
+	// assumes its process died and runs it again. Defaults to 5m.
+	Lease time.Duration
+
+	// MaxAttempts is how many times a job runs before it's marked dead,
+	// unless it was enqueued with its own limit. Defaults to 10.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubling with each
+	// failure up to MaxBackoff. Defaults to 30s and 1h.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Concurrency <= 0 {
+		o.Concurrency = 2
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 15 * time.Second
+	}
+	if o.Lease <= 0 {
+		o.Lease = 5 * time.Minute
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 10
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = 30 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Hour
+	}
+	return o
+}
+
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// Queue stores jobs and runs them on a pool of workers.
+type Queue struct {
+	db       *database.Queries
+	opts     Options
+	handlers map[string]handlerFunc
+	wake     chan struct{}
+	now      func() time.Time
+
+	mu         sync.Mutex
+	running    bool
+	stop       context.CancelFunc
+	cancelJobs context.CancelFunc
+	workers    sync.WaitGroup
+}
+
+// New returns a Queue that keeps its jobs in db. Register every kind of
+// job before calling Start.
+func New(db *database.Queries, opts Options) *Queue {
+	return &Queue{
+		db:       db,
+		opts:     opts.withDefaults(),
+		handlers: map[string]handlerFunc{},
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// Kind is a registered kind of job whose payloads are a T.
+type Kind[T any] struct {
+	queue *Queue
+	name  string
+}
+
+// Register adds the handler for jobs of the named kind and returns the
+// Kind to enqueue them with. It panics if the name is already taken.
+//
+// A handler that returns an error is retried later; wrap the error with
+// Permanent to mark the job dead straight away. Handlers should return
+// when ctx is cancelled, which happens if Shutdown runs out of time.
+func Register[T any](q *Queue, name string, handle func(ctx context.Context, payload T) error) Kind[T] {
+	if _, ok := q.handlers[name]; ok {
+		panic("jobs: kind " + name + " registered twice")
+	}
+	q.handlers[name] = func(ctx context.Context, raw []byte) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return Permanent(fmt.Errorf("decode payload: %w", err))
+		}
+		return handle(ctx, payload)
+	}
+	return Kind[T]{queue: q, name: name}
+}
+
+// Name returns the kind's name as stored in the jobs table.
+func (k Kind[T]) Name() string {
+	return k.name
+}
+
+type enqueueOptions struct {
+	runAt       time.Time
+	maxAttempts int
+}
+
+// EnqueueOption changes how a single job is scheduled.
+type EnqueueOption func(*enqueueOptions)
+
+// RunAt delays a job until t.
+func RunAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAt = t }
+}
+
+// MaxAttempts overrides the queue's attempt limit for a job.
+func MaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) { o.maxAttempts = n }
+}
+
+// Enqueue stores a job to run payload, now unless RunAt says otherwise,
+// and returns its ID.
+func (k Kind[T]) Enqueue(ctx context.Context, payload T, opts ...EnqueueOption) (int64, error) {
+	id, due, err := k.enqueue(ctx, k.queue.db, payload, opts)
+	if err == nil && due {
+		k.queue.Wake()
+	}
+	return id, err
+}
+
+// EnqueueTx is Enqueue within the caller's transaction, so the job is
+// only stored if the work that asked for it is too. tx is the queue's
+// Queries with WithTx applied. Call Wake on the queue after committing to
+// have the job picked up straight away.
+func (k Kind[T]) EnqueueTx(ctx context.Context, tx *database.Queries, payload T, opts ...EnqueueOption) (int64, error) {
+	id, _, err := k.enqueue(ctx, tx, payload, opts)
+	return id, err
+}
+
+// enqueue stores the job in db and reports whether it is due now.
+func (k Kind[T]) enqueue(ctx context.Context, db *database.Queries, payload T, opts []EnqueueOption) (int64, bool, error) {
+	o := enqueueOptions{runAt: k.queue.now(), maxAttempts: k.queue.opts.MaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, false, fmt.Errorf("jobs: encode %s payload: %w", k.name, err)
+	}
+
+	id, err := db.EnqueueJob(ctx, database.EnqueueJobParams{
+		Kind:        k.name,
+		Payload:     string(raw),
+		MaxAttempts: int64(max(o.maxAttempts, 1)),
+		RunAt:       timestamp(o.runAt),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("jobs: enqueue %s: %w", k.name, err)
+	}
+	return id, !o.runAt.After(k.queue.now()), nil
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job that returned it is marked dead instead
+// of retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type attemptKey struct{}
+
+type attempt struct {
+	n, limit int
+}
+
+// Attempt returns which attempt at its job a handler is making, counting
+// from 1, and how many the job gets. Handlers that keep their own record
+// of a job's progress use it to tell the last attempt from the others.
+// ok is false if ctx doesn't belong to a job.
+func Attempt(ctx context.Context) (n, limit int, ok bool) {
+	a, ok := ctx.Value(attemptKey{}).(attempt)
+	return a.n, a.limit, ok
+}
+
+// Wake tells an idle worker to look for due jobs now rather than at its
+// next poll. A wake already pending covers this one too.
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the workers. They stop claiming jobs when ctx is done or
+// Shutdown is called. Starting a running queue does nothing.
+func (q *Queue) Start(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running {
+		return
+	}
+	q.running = true
+
+	// Claiming stops with ctx, but jobs already running are only cancelled
+	// when Shutdown gives up waiting for them
+	claimCtx, stop := context.WithCancel(ctx)
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	q.stop, q.cancelJobs = stop, cancelJobs
+
+	for range q.opts.Concurrency {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			q.work(claimCtx, jobCtx)
+		}()
+	}
+}
+
+// Shutdown stops the workers from claiming new jobs and waits for the
+// ones running to finish. If ctx ends first, the running jobs' contexts
+// are cancelled and Shutdown returns ctx's error without waiting further;
+// jobs that don't finish are retried.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.running {
+		q.mu.Unlock()
+		return nil
+	}
+	q.running = false
+	stop, cancelJobs := q.stop, q.cancelJobs
+	q.mu.Unlock()
+
+	stop()
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		cancelJobs()
+		return nil
+	case <-ctx.Done():
+		cancelJobs()
+		return ctx.Err()
+	}
+}
+
+// work runs due jobs until claimCtx is done, sleeping between polls when
+// there are none.
+func (q *Queue) work(claimCtx, jobCtx context.Context) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for claimCtx.Err() == nil {
+			ran, err := q.runNext(claimCtx, jobCtx)
+			if err != nil {
+				slog.ErrorContext(claimCtx, "failed to run job", "error", err)
+				break
+			}
+			if !ran {
+				break
+			}
+		}
+
+		select {
+		case <-claimCtx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// RunDue runs every job due now, one at a time, in the calling goroutine,
+// and returns how many it ran. It's meant for tests and one-off commands;
+// servers use Start.
+func (q *Queue) RunDue(ctx context.Context) (int, error) {
+	ran := 0
+	for {
+		ok, err := q.runNext(ctx, ctx)
+		if err != nil || !ok {
+			return ran, err
+		}
+		ran++
+	}
+}
+
+// runNext claims one due job and runs it with jobCtx, reporting false if
+// nothing was due. Only database errors are returned; the job's own
+// failure is recorded on it.
+func (q *Queue) runNext(claimCtx, jobCtx context.Context) (bool, error) {
+	now := q.now()
+	job, err := q.db.ClaimJob(claimCtx, database.ClaimJobParams{
+		LockedUntil: timestamp(now.Add(q.opts.Lease)),
+		Now:         timestamp(now),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || claimCtx.Err() != nil {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// There may be more where this came from, so let another worker look
+	q.Wake()
+
+	runErr := q.run(jobCtx, job)
+
+	// The outcome is recorded even if shutdown cancelled the job
+	ctx := context.WithoutCancel(jobCtx)
+	var recorded int64
+	if runErr == nil {
+		recorded, err = q.db.CompleteJob(ctx, database.CompleteJobParams{ID: job.ID, Attempts: job.Attempts})
+	} else {
+		recorded, err = q.fail(ctx, job, runErr)
+	}
+	if err != nil {
+		return true, err
+	}
+
+	// The job ran past its lease and another worker has claimed it since,
+	// so that worker's run is the one that counts
+	if recorded == 0 {
+		slog.WarnContext(ctx, "lost the lease on a job, dropping its outcome", "job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", runErr)
+	}
+	return true, nil
+}
+
+// fail records a failed run of job, retrying it later or marking it dead,
+// and returns how many rows it changed: 0 if the job's lease was lost.
+func (q *Queue) fail(ctx context.Context, job database.Job, runErr error) (int64, error) {
+	lastError := runErr.Error()
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+	}
+
+	var permanent *permanentError
+	if errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts {
+		killed, err := q.db.KillJob(ctx, database.KillJobParams{LastError: lastError, ID: job.ID, Attempts: job.Attempts})
+		if killed > 0 {
+			slog.ErrorContext(ctx, "job is dead", "job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", runErr)
+		}
+		return killed, err
+	}
+
+	delay := backoff(int(job.Attempts), q.opts.Backoff, q.opts.MaxBackoff)
+	retried, err := q.db.RetryJob(ctx, database.RetryJobParams{
+		LastError: lastError,
+		RunAt:     timestamp(q.now().Add(delay)),
+		ID:        job.ID,
+		Attempts:  job.Attempts,
+	})
+	if retried > 0 {
+		slog.WarnContext(ctx, "job failed, will retry", "job_id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "retry_in", delay, "error", runErr)
+	}
+	return retried, err
+}
+
+// run calls the job's handler, turning a panic into an error.
+func (q *Queue) run(ctx context.Context, job database.Job) (err error) {
+	handle, ok := q.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for %q", job.Kind))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	ctx = context.WithValue(ctx, attemptKey{}, attempt{n: int(job.Attempts), limit: int(job.MaxAttempts)})
+	return handle(ctx, []byte(job.Payload))
+}
+
+// backoff is how long to wait after a job's nth failed attempt: base,
+// doubling each time, capped at limit.
+func backoff(attempts int, base, limit time.Duration) time.Duration {
+	delay := base
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= limit {
+			return limit
+		}
+	}
+	return min(delay, limit)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
@@ -0,0 +1,424 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sianwa11/my-journal/internal/database"
+	"github.com/sianwa11/my-journal/internal/sql/schema"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func setupQueue(t *testing.T, opts Options) (*Queue, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := schema.Up(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(database.New(db), opts), db
+}
+
+func jobState(t *testing.T, db *sql.DB, id int64) (status string, attempts int, lastError string) {
+	t.Helper()
+	err := db.QueryRow("SELECT status, attempts, last_error FROM jobs WHERE id = ?", id).Scan(&status, &attempts, &lastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status, attempts, lastError
+}
+
+func TestEnqueueAndRunDue(t *testing.T) {
+	queue, db := setupQueue(t, Options{})
+	ctx := context.Background()
+
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	var got []string
+	greet := Register(queue, "greet", func(ctx context.Context, g greeting) error {
+		got = append(got, g.Name)
+		return nil
+	})
+
+	first, err := greet.Enqueue(ctx, greeting{Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := greet.Enqueue(ctx, greeting{Name: "Grace"}, RunAt(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ran, err := queue.RunDue(ctx); err != nil || ran != 1 {
+		t.Fatalf("Expected one job to run, got %d: %v", ran, err)
+	}
+	if len(got) != 1 || got[0] != "Ada" {
+		t.Errorf("Expected Ada to be greeted, got %v", got)
+	}
+	if status, _, _ := jobState(t, db, first); status != "" {
+		t.Errorf("Expected a finished job to be deleted, got %s", status)
+	}
+	if status, _, _ := jobState(t, db, later); status != StatusPending {
+		t.Errorf("Expected the scheduled job to wait, got %s", status)
+	}
+
+	now = now.Add(time.Hour)
+	if ran, _ := queue.RunDue(ctx); ran != 1 || got[1] != "Grace" {
+		t.Errorf("Expected the scheduled job to run once due, got %d runs: %v", ran, got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a kind twice to panic")
+		}
+	}()
+	Register(queue, "greet", func(context.Context, greeting) error { return nil })
+}
+
+func TestEnqueueTx(t *testing.T) {
+	queue, db := setupQueue(t, Options{})
+	ctx := context.Background()
+
+	greet := Register(queue, "greet", func(context.Context, greeting) error { return nil })
+
+	enqueue := func(commit bool) int64 {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := greet.EnqueueTx(ctx, queue.db.WithTx(tx), greeting{Name: "Ada"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commit {
+			tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		return id
+	}
+
+	if status, _, _ := jobState(t, db, enqueue(false)); status != "" {
+		t.Errorf("Expected a rolled back job to be gone, got %s", status)
+	}
+	if status, _, _ := jobState(t, db, enqueue(true)); status != StatusPending {
+		t.Errorf("Expected a committed job to be pending, got %s", status)
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	queue, _ := setupQueue(t, Options{Backoff: time.Nanosecond})
+	ctx := context.Background()
+
+	if _, _, ok := Attempt(ctx); ok {
+		t.Error("Expected no attempt outside a job")
+	}
+
+	var seen []int
+	flaky := Register(queue, "flaky", func(ctx context.Context, _ greeting) error {
+		n, limit, ok := Attempt(ctx)
+		if !ok || limit != 3 {
+			t.Errorf("Expected a limit of 3, got %d (%v)", limit, ok)
+		}
+		seen = append(seen, n)
+		return errors.New("not yet")
+	})
+	flaky.Enqueue(ctx, greeting{}, MaxAttempts(3))
+
+	for range 3 {
+		queue.RunDue(ctx)
+	}
+	if len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+		t.Errorf("Expected attempts 1 to 3, got %v", seen)
+	}
+}
+
+func TestRetriesAndDeadJobs(t *testing.T) {
+	queue, db := setupQueue(t, Options{Backoff: time.Minute, MaxBackoff: time.Hour})
+	ctx := context.Background()
+
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	var calls atomic.Int32
+	flaky := Register(queue, "flaky", func(ctx context.Context, g greeting) error {
+		calls.Add(1)
+		switch g.Name {
+		case "permanent":
+			return Permanent(errors.New("bad input"))
+		case "panic":
+			panic("boom")
+		}
+		return errors.New("try again")
+	})
+
+	id, err := flaky.Enqueue(ctx, greeting{Name: "transient"}, MaxAttempts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue.RunDue(ctx)
+	if status, attempts, lastError := jobState(t, db, id); status != StatusPending || attempts != 1 || lastError != "try again" {
+		t.Fatalf("Expected a pending retry, got %s after %d attempts: %q", status, attempts, lastError)
+	}
+
+	// The first retry waits a minute, the second two
+	if ran, _ := queue.RunDue(ctx); ran != 0 {
+		t.Errorf("Expected nothing due before the backoff passes, got %d", ran)
+	}
+	now = now.Add(time.Minute)
+	queue.RunDue(ctx)
+	now = now.Add(time.Minute)
+	if ran, _ := queue.RunDue(ctx); ran != 0 {
+		t.Errorf("Expected the second retry to back off further, got %d", ran)
+	}
+	now = now.Add(time.Minute)
+	queue.RunDue(ctx)
+	if status, attempts, _ := jobState(t, db, id); status != StatusDead || attempts != 3 {
+		t.Errorf("Expected the job to be dead after 3 attempts, got %s after %d", status, attempts)
+	}
+
+	permanent, _ := flaky.Enqueue(ctx, greeting{Name: "permanent"})
+	panicky, _ := flaky.Enqueue(ctx, greeting{Name: "panic"})
+	unknown, err := Kind[greeting]{queue: queue, name: "missing"}.Enqueue(ctx, greeting{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.RunDue(ctx)
+
+	if status, attempts, _ := jobState(t, db, permanent); status != StatusDead || attempts != 1 {
+		t.Errorf("Expected a permanent failure to kill the job at once, got %s after %d", status, attempts)
+	}
+	if status, _, lastError := jobState(t, db, panicky); status != StatusPending || lastError != "panic: boom" {
+		t.Errorf("Expected a panic to be retried, got %s: %q", status, lastError)
+	}
+	if status, _, lastError := jobState(t, db, unknown); status != StatusDead || lastError == "" {
+		t.Errorf("Expected a job without a handler to be dead, got %s: %q", status, lastError)
+	}
+
+	// A dead job can be requeued
+	if n, err := queue.db.RequeueDeadJob(ctx, id); err != nil || n != 1 {
+		t.Fatalf("Expected to requeue the dead job, got %d: %v", n, err)
+	}
+	if status, attempts, _ := jobState(t, db, id); status != StatusPending || attempts != 0 {
+		t.Errorf("Expected a requeued job to start over, got %s after %d", status, attempts)
+	}
+}
+
+func TestExpiredLeaseIsReclaimed(t *testing.T) {
+	queue, db := setupQueue(t, Options{Lease: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	ran := 0
+	greet := Register(queue, "greet", func(context.Context, greeting) error {
+		ran++
+		return nil
+	})
+	id, _ := greet.Enqueue(ctx, greeting{})
+
+	// A worker claimed the job and then its process died
+	if _, err := queue.db.ClaimJob(ctx, database.ClaimJobParams{LockedUntil: timestamp(now.Add(time.Minute)), Now: timestamp(now)}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := queue.RunDue(ctx); n != 0 {
+		t.Errorf("Expected a leased job to be left alone, got %d runs", n)
+	}
+
+	now = now.Add(time.Minute)
+	if n, _ := queue.RunDue(ctx); n != 1 || ran != 1 {
+		t.Errorf("Expected the abandoned job to run again, got %d runs", n)
+	}
+	if status, _, _ := jobState(t, db, id); status != "" {
+		t.Errorf("Expected the job to finish, got %s", status)
+	}
+}
+
+func TestLostLeaseIsFenced(t *testing.T) {
+	queue, db := setupQueue(t, Options{Lease: time.Minute})
+	ctx := context.Background()
+
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	slow := Register(queue, "slow", func(context.Context, greeting) error {
+		// The run outlives its lease and another worker claims the job
+		now = now.Add(2 * time.Minute)
+		if _, err := queue.db.ClaimJob(ctx, database.ClaimJobParams{LockedUntil: timestamp(now.Add(time.Minute)), Now: timestamp(now)}); err != nil {
+			t.Fatal(err)
+		}
+		return errors.New("too slow")
+	})
+	id, _ := slow.Enqueue(ctx, greeting{})
+
+	if n, err := queue.RunDue(ctx); n != 1 || err != nil {
+		t.Fatalf("Expected one run, got %d: %v", n, err)
+	}
+
+	// The late failure doesn't put the job back while the new claim holds it
+	if status, attempts, lastError := jobState(t, db, id); status != StatusRunning || attempts != 2 || lastError != "" {
+		t.Errorf("Expected the second claim to be untouched, got %s after %d: %q", status, attempts, lastError)
+	}
+
+	if n, _ := queue.db.CompleteJob(ctx, database.CompleteJobParams{ID: id, Attempts: 1}); n != 0 {
+		t.Error("Expected a stale worker not to complete the job")
+	}
+	if n, _ := queue.db.CompleteJob(ctx, database.CompleteJobParams{ID: id, Attempts: 2}); n != 1 {
+		t.Error("Expected the current worker to complete the job")
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	queue, db := setupQueue(t, Options{Concurrency: 3, PollInterval: time.Hour})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	done := make(chan string, 6)
+
+	greet := Register(queue, "greet", func(ctx context.Context, g greeting) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		done <- g.Name
+		return nil
+	})
+
+	queue.Start(ctx)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		if _, err := greet.Enqueue(ctx, greeting{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for the pool to fill up before letting anything finish
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := running
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 jobs running at once, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+
+	for range 6 {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected every job to run")
+		}
+	}
+	if peak != 3 {
+		t.Errorf("Expected at most 3 jobs at once, got %d", peak)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := queue.Shutdown(shutdownCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	var left int
+	db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&left)
+	if left != 0 {
+		t.Errorf("Expected every job to be deleted, %d left", left)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	queue, db := setupQueue(t, Options{Concurrency: 1, PollInterval: time.Hour})
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	slow := Register(queue, "slow", func(ctx context.Context, g greeting) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	queue.Start(ctx)
+	id, _ := slow.Enqueue(ctx, greeting{})
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to start")
+	}
+
+	// The job ignores everything but cancellation, so shutdown times out,
+	// cancels it and the job is left to be retried
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := queue.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected shutdown to time out, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, attempts, _ := jobState(t, db, id)
+		if status == StatusPending && attempts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the cancelled job to be retried, got %s after %d attempts", status, attempts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := queue.Shutdown(ctx); err != nil {
+		t.Errorf("Expected a second shutdown to do nothing, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts, 30*time.Second, time.Hour); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
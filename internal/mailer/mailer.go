@@ -0,0 +1,58 @@
+// Package mailer sends email. Callers depend on the Mailer interface so
+// the transport can be swapped: SMTP in production, Log when no server is
+// configured, and fakes in tests.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// ErrHeader is returned for a message whose recipient, subject or headers
+// contain line breaks, which would let them inject headers of their own.
+var ErrHeader = errors.New("mailer: invalid header value")
+
+// Message is one email to one recipient. Text is required; HTML, when
+// set, is sent as an alternative to it.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+
+	// Headers are added as given, e.g. List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Log is a Mailer that writes messages to the log instead of sending
+// them, for running without a mail server.
+type Log struct{}
+
+// Send logs msg.
+func (Log) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "email not sent, no mail server configured", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+func (msg Message) validate() error {
+	values := []string{msg.To, msg.Subject}
+	for name, value := range msg.Headers {
+		values = append(values, name, value)
+	}
+	for _, v := range values {
+		if strings.ContainsAny(v, "\r\n") {
+			return ErrHeader
+		}
+	}
+	return nil
+}
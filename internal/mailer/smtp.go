@@ -0,0 +1,216 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeout bounds a whole SMTP conversation when ctx has no
+// deadline of its own.
+const defaultTimeout = 30 * time.Second
+
+// SMTPConfig says where and as whom to send.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// From is the sender, either a bare address or "Name <address>".
+	From string
+}
+
+// SMTP is a Mailer that delivers through an SMTP server. Port 465 uses
+// implicit TLS; on other ports the connection is upgraded with STARTTLS
+// when the server offers it. Credentials are only sent over TLS, or to a
+// server on localhost.
+type SMTP struct {
+	config SMTPConfig
+	from   *mail.Address
+	dialer net.Dialer
+	tls    *tls.Config
+	now    func() time.Time
+}
+
+// NewSMTP returns an SMTP mailer, or an error if config.From is not a
+// valid address.
+func NewSMTP(config SMTPConfig) (*SMTP, error) {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", config.From, err)
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &SMTP{
+		config: config,
+		from:   from,
+		tls:    &tls.Config{ServerName: config.Host},
+		now:    time.Now,
+	}, nil
+}
+
+// Send delivers msg, giving up when ctx is done.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid recipient %q: %w", msg.To, err)
+	}
+	body, err := s.compose(to, msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	conn, err := s.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = s.now().Add(defaultTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	if s.config.Port == 465 {
+		conn = tls.Client(conn, s.tls)
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mailer: %w", err)
+	}
+	defer c.Close()
+
+	if err := s.deliver(c, to.Address, body); err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTP) deliver(c *smtp.Client, to string, body []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); ok && s.config.Port != 465 {
+		if err := c.StartTLS(s.tls); err != nil {
+			return err
+		}
+	}
+	if s.config.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("%s does not support authentication", s.config.Host)
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose renders msg as an RFC 5322 message with CRLF line endings: a
+// single text part, or text and HTML as multipart/alternative.
+func (s *SMTP) compose(to *mail.Address, msg Message) ([]byte, error) {
+	if err := msg.validate(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", s.now().Format(time.RFC1123Z))
+	header("Message-ID", s.messageID())
+	header("MIME-Version", "1.0")
+
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		header(textproto.CanonicalMIMEHeaderKey(name), msg.Headers[name])
+	}
+
+	if msg.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{`text/plain; charset="utf-8"`, msg.Text},
+		{`text/html; charset="utf-8"`, msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *SMTP) messageID() string {
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+	return "<" + rand.Text() + "@" + domain + ">"
+}
+
+// writeQuotedPrintable encodes text, which also turns its line breaks
+// into CRLF.
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,284 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal SMTP server on localhost that accepts everything
+// and records what it was sent.
+type fakeSMTP struct {
+	listener net.Listener
+	auth     bool
+
+	mu       sync.Mutex
+	received []received
+	rejectTo string
+}
+
+type received struct {
+	From, To string
+	Auth     string
+	Data     string
+}
+
+func newFakeSMTP(t *testing.T, auth bool) *fakeSMTP {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTP{listener: listener, auth: auth}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	var msg received
+	text.PrintfLine("220 localhost fake SMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			if s.auth {
+				text.PrintfLine("250-localhost")
+				text.PrintfLine("250 AUTH PLAIN")
+			} else {
+				text.PrintfLine("250 localhost")
+			}
+		case "AUTH":
+			// AUTH PLAIN <base64 of "\x00user\x00password">
+			_, encoded, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			msg.Auth = strings.ReplaceAll(string(decoded), "\x00", " ")
+			text.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			text.PrintfLine("250 ok")
+		case "RCPT":
+			msg.To = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			s.mu.Lock()
+			reject := msg.To == s.rejectTo
+			s.mu.Unlock()
+			if reject {
+				text.PrintfLine("550 no such user")
+			} else {
+				text.PrintfLine("250 ok")
+			}
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.received = append(s.received, msg)
+			s.mu.Unlock()
+			text.PrintfLine("250 queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTP) take() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	received := s.received
+	s.received = nil
+	return received
+}
+
+func TestSMTPSend(t *testing.T) {
+	server := newFakeSMTP(t, true)
+	smtp, err := NewSMTP(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "journal",
+		Password: "hunter2",
+		From:     "My Journal <journal@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = smtp.Send(context.Background(), Message{
+		To:      "reader@example.org",
+		Subject: "New entries ✍️",
+		Text:    "Hello,\nThere are new entries.",
+		HTML:    "<p>Hello, there are <b>new</b> entries.</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.take()
+	if len(got) != 1 {
+		t.Fatalf("Expected one message, got %d", len(got))
+	}
+	if got[0].From != "journal@example.com" || got[0].To != "reader@example.org" {
+		t.Errorf("Unexpected envelope %s -> %s", got[0].From, got[0].To)
+	}
+	if got[0].Auth != " journal hunter2" {
+		t.Errorf("Unexpected credentials %q", got[0].Auth)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "New entries ✍️" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if msg.Header.Get("List-Unsubscribe") != "<https://example.com/unsubscribe>" {
+		t.Errorf("Expected the List-Unsubscribe header, got %v", msg.Header)
+	}
+	if from := msg.Header.Get("From"); from != `"My Journal" <journal@example.com>` {
+		t.Errorf("Unexpected From %q", from)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// NextPart decodes quoted-printable, and the fake server's
+		// DotReader has already turned CRLF into LF
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+strings.TrimSpace(string(body)))
+	}
+	want := []string{
+		`text/plain; charset="utf-8": Hello,` + "\nThere are new entries.",
+		`text/html; charset="utf-8": <p>Hello, there are <b>new</b> entries.</p>`,
+	}
+	if len(bodies) != 2 || bodies[0] != want[0] || bodies[1] != want[1] {
+		t.Errorf("Unexpected parts %q", bodies)
+	}
+}
+
+func TestSMTPErrors(t *testing.T) {
+	server := newFakeSMTP(t, false)
+	server.mu.Lock()
+	server.rejectTo = "nobody@example.org"
+	server.mu.Unlock()
+	send := func(config SMTPConfig, msg Message) error {
+		config.Host = "127.0.0.1"
+		config.Port = server.port()
+		config.From = "journal@example.com"
+		smtp, err := NewSMTP(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return smtp.Send(ctx, msg)
+	}
+
+	if err := send(SMTPConfig{}, Message{To: "nobody@example.org", Subject: "Hi", Text: "Hi"}); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Expected a rejected recipient to fail, got %v", err)
+	}
+	if err := send(SMTPConfig{}, Message{To: "reader@example.org", Subject: "Hi\r\nBcc: everyone@example.org", Text: "Hi"}); !errors.Is(err, ErrHeader) {
+		t.Errorf("Expected a header injection to be refused, got %v", err)
+	}
+	if err := send(SMTPConfig{}, Message{To: "not an address", Text: "Hi"}); err == nil {
+		t.Error("Expected an invalid recipient to fail")
+	}
+	if err := send(SMTPConfig{Username: "journal", Password: "pw"}, Message{To: "reader@example.org", Text: "Hi"}); err == nil {
+		t.Error("Expected credentials to fail against a server without AUTH")
+	}
+	if len(server.take()) != 0 {
+		t.Error("Expected nothing to be delivered")
+	}
+
+	// A plain text message is a single part
+	if err := send(SMTPConfig{}, Message{To: "reader@example.org", Subject: "Hi", Text: "Just text"}); err != nil {
+		t.Fatal(err)
+	}
+	got := server.take()
+	msg, err := mail.ReadMessage(strings.NewReader(got[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "text/plain") || strings.TrimSpace(string(body)) != "Just text" {
+		t.Errorf("Unexpected message %v %q", msg.Header, body)
+	}
+
+	if _, err := NewSMTP(SMTPConfig{From: "not an address"}); err == nil {
+		t.Error("Expected an invalid from address to be refused")
+	}
+
+	// A server that never answers is abandoned when ctx is done
+	quiet, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer quiet.Close()
+	go func() {
+		conn, err := quiet.Accept()
+		if err == nil {
+			defer conn.Close()
+			bufio.NewReader(conn).ReadString('\n')
+		}
+	}()
+	smtp, _ := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: quiet.Addr().(*net.TCPAddr).Port, From: "journal@example.com"})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := smtp.Send(ctx, Message{To: "reader@example.org", Text: "Hi"}); err == nil {
+		t.Error("Expected a silent server to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to give up quickly, took %v", elapsed)
+	}
+}
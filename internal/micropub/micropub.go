@@ -0,0 +1,343 @@
+// Package micropub parses W3C Micropub requests into a form the journal
+// can act on. It knows the protocol's two encodings, form-encoded and
+// JSON, and the create, update, delete and undelete actions; mapping
+// properties onto journal entries is left to the caller.
+package micropub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Actions a request can ask for.
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionUndelete = "undelete"
+)
+
+// ErrInvalidRequest is wrapped by every parse error. Servers answer it
+// with the invalid_request error code.
+var ErrInvalidRequest = errors.New("invalid_request")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRequest, fmt.Sprintf(format, args...))
+}
+
+// Properties are microformats2 properties. Every property holds a list;
+// values are strings or, in JSON requests, objects such as
+// {"html": "..."} or {"value": "...", "alt": "..."}.
+type Properties map[string][]any
+
+// Strings returns the string values of a property. Objects contribute
+// their "value" member, if any.
+func (p Properties) Strings(name string) []string {
+	var values []string
+	for _, v := range p[name] {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case map[string]any:
+			if s, ok := v["value"].(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// String returns the first string value of a property, or "".
+func (p Properties) String(name string) string {
+	if values := p.Strings(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Content is the first value of the content property. HTML is set when
+// the client sent {"html": ...}; otherwise Text holds plain content.
+type Content struct {
+	Text string
+	HTML string
+}
+
+// Content returns the entry's content, reporting false if there is none.
+func (p Properties) Content() (Content, bool) {
+	if len(p["content"]) == 0 {
+		return Content{}, false
+	}
+
+	switch v := p["content"][0].(type) {
+	case string:
+		return Content{Text: v}, true
+	case map[string]any:
+		if html, ok := v["html"].(string); ok {
+			return Content{HTML: html}, true
+		}
+		if text, ok := v["value"].(string); ok {
+			return Content{Text: text}, true
+		}
+	}
+	return Content{}, false
+}
+
+// Photo is one value of the photo property.
+type Photo struct {
+	URL string
+	Alt string
+}
+
+// Photos returns the entry's photos, with alt text where it was given.
+func (p Properties) Photos() []Photo {
+	var photos []Photo
+	for _, v := range p["photo"] {
+		switch v := v.(type) {
+		case string:
+			photos = append(photos, Photo{URL: v})
+		case map[string]any:
+			u, _ := v["value"].(string)
+			alt, _ := v["alt"].(string)
+			if u != "" {
+				photos = append(photos, Photo{URL: u, Alt: alt})
+			}
+		}
+	}
+	return photos
+}
+
+// Filter returns only the named properties. No names returns them all.
+func (p Properties) Filter(names []string) Properties {
+	if len(names) == 0 {
+		return p
+	}
+	filtered := Properties{}
+	for _, name := range names {
+		if values, ok := p[name]; ok {
+			filtered[name] = values
+		}
+	}
+	return filtered
+}
+
+// Request is a parsed Micropub request.
+type Request struct {
+	Action string
+
+	// Type is the object to create, e.g. "h-entry". Create only.
+	Type string
+
+	// Properties of the object to create.
+	Properties Properties
+
+	// URL of the post to update, delete or undelete.
+	URL string
+
+	// Update operations. A Delete entry with no values removes the whole
+	// property; one with values removes just those values.
+	Replace Properties
+	Add     Properties
+	Delete  Properties
+}
+
+// Apply carries out an update request's operations on props, in the
+// order the spec gives: replace, then add, then delete.
+func (req Request) Apply(props Properties) {
+	for name, values := range req.Replace {
+		props[name] = values
+	}
+	for name, values := range req.Add {
+		props[name] = append(props[name], values...)
+	}
+	for name, values := range req.Delete {
+		if len(values) == 0 {
+			delete(props, name)
+			continue
+		}
+		kept := props[name][:0:0]
+		for _, existing := range props[name] {
+			if !containsValue(values, existing) {
+				kept = append(kept, existing)
+			}
+		}
+		if len(kept) == 0 {
+			delete(props, name)
+		} else {
+			props[name] = kept
+		}
+	}
+}
+
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if fmt.Sprint(candidate) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedFormKeys are form fields that are not properties.
+var reservedFormKeys = map[string]bool{
+	"h":            true,
+	"action":       true,
+	"url":          true,
+	"access_token": true,
+}
+
+// ParseForm reads a form-encoded or multipart request. Forms can create
+// posts and delete or undelete them; updates need JSON.
+func ParseForm(form url.Values) (Request, error) {
+	action := form.Get("action")
+	if action == "" {
+		action = ActionCreate
+	}
+
+	switch action {
+	case ActionCreate:
+		h := form.Get("h")
+		if h == "" {
+			h = "entry"
+		}
+		req := Request{Action: ActionCreate, Type: "h-" + h, Properties: Properties{}}
+
+		// Sort so repeated keys like category and category[] merge in a
+		// predictable order
+		keys := make([]string, 0, len(form))
+		for key := range form {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if reservedFormKeys[key] {
+				continue
+			}
+			name := strings.TrimSuffix(key, "[]")
+			for _, v := range form[key] {
+				req.Properties[name] = append(req.Properties[name], v)
+			}
+		}
+		return req, nil
+	case ActionDelete, ActionUndelete:
+		if form.Get("url") == "" {
+			return Request{}, invalid("%s requires a url", action)
+		}
+		return Request{Action: action, URL: form.Get("url")}, nil
+	case ActionUpdate:
+		return Request{}, invalid("updates must be sent as JSON")
+	default:
+		return Request{}, invalid("unknown action %q", action)
+	}
+}
+
+type jsonRequest struct {
+	Type       []string                   `json:"type"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Action     string                     `json:"action"`
+	URL        string                     `json:"url"`
+	Replace    map[string]json.RawMessage `json:"replace"`
+	Add        map[string]json.RawMessage `json:"add"`
+	Delete     json.RawMessage            `json:"delete"`
+}
+
+// ParseJSON reads a JSON request body.
+func ParseJSON(r io.Reader) (Request, error) {
+	var body jsonRequest
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return Request{}, invalid("malformed JSON: %v", err)
+	}
+
+	action := body.Action
+	if action == "" {
+		action = ActionCreate
+	}
+
+	switch action {
+	case ActionCreate:
+		if len(body.Type) != 1 {
+			return Request{}, invalid("type must list exactly one type")
+		}
+		props, err := parseProperties(body.Properties)
+		if err != nil {
+			return Request{}, err
+		}
+		return Request{Action: ActionCreate, Type: body.Type[0], Properties: props}, nil
+	case ActionUpdate:
+		if body.URL == "" {
+			return Request{}, invalid("update requires a url")
+		}
+		req := Request{Action: ActionUpdate, URL: body.URL}
+		var err error
+		if req.Replace, err = parseProperties(body.Replace); err != nil {
+			return Request{}, err
+		}
+		if req.Add, err = parseProperties(body.Add); err != nil {
+			return Request{}, err
+		}
+		if req.Delete, err = parseDelete(body.Delete); err != nil {
+			return Request{}, err
+		}
+		if len(req.Replace)+len(req.Add)+len(req.Delete) == 0 {
+			return Request{}, invalid("update has nothing to replace, add or delete")
+		}
+		return req, nil
+	case ActionDelete, ActionUndelete:
+		if body.URL == "" {
+			return Request{}, invalid("%s requires a url", action)
+		}
+		return Request{Action: action, URL: body.URL}, nil
+	default:
+		return Request{}, invalid("unknown action %q", action)
+	}
+}
+
+// parseProperties insists every property is a list, as the spec requires.
+func parseProperties(raw map[string]json.RawMessage) (Properties, error) {
+	props := Properties{}
+	for name, value := range raw {
+		var values []any
+		if err := json.Unmarshal(value, &values); err != nil {
+			return nil, invalid("property %q must be an array", name)
+		}
+		props[name] = values
+	}
+	return props, nil
+}
+
+// parseDelete accepts either a list of property names or an object of
+// values to remove.
+func parseDelete(raw json.RawMessage) (Properties, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return Properties{}, nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		props := Properties{}
+		for _, name := range names {
+			props[name] = nil
+		}
+		return props, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, invalid("delete must be an array of property names or an object of values")
+	}
+	props, err := parseProperties(values)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range props {
+		if len(v) == 0 {
+			return nil, invalid("delete values for %q must not be empty", name)
+		}
+	}
+	return props, nil
+}
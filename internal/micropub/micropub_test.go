@@ -0,0 +1,127 @@
+package micropub
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseForm(t *testing.T) {
+	form := url.Values{
+		"h":            {"entry"},
+		"content":      {"Hello **world**"},
+		"category[]":   {"go", "indieweb"},
+		"access_token": {"secret"},
+	}
+
+	req, err := ParseForm(form)
+	if err != nil {
+		t.Fatalf("ParseForm: %v", err)
+	}
+	if req.Action != ActionCreate || req.Type != "h-entry" {
+		t.Errorf("Unexpected request %+v", req)
+	}
+	if got := req.Properties.Strings("category"); !reflect.DeepEqual(got, []string{"go", "indieweb"}) {
+		t.Errorf("Unexpected categories %v", got)
+	}
+	if _, ok := req.Properties["access_token"]; ok {
+		t.Error("Expected the access token not to become a property")
+	}
+	if content, ok := req.Properties.Content(); !ok || content.Text != "Hello **world**" {
+		t.Errorf("Unexpected content %+v", content)
+	}
+
+	req, err = ParseForm(url.Values{"action": {"delete"}, "url": {"https://me.example/journals/1"}})
+	if err != nil || req.Action != ActionDelete || req.URL != "https://me.example/journals/1" {
+		t.Errorf("Unexpected delete request %+v, %v", req, err)
+	}
+
+	for _, form := range []url.Values{
+		{"action": {"delete"}},
+		{"action": {"update"}, "url": {"https://me.example/journals/1"}},
+		{"action": {"explode"}},
+	} {
+		if _, err := ParseForm(form); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("Expected %v to be rejected, got %v", form, err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	req, err := ParseJSON(strings.NewReader(`{
+		"type": ["h-entry"],
+		"properties": {
+			"name": ["Title"],
+			"content": [{"html": "<p>Hi</p>"}],
+			"photo": [{"value": "https://me.example/a.jpg", "alt": "A cat"}, "https://me.example/b.jpg"]
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	if req.Type != "h-entry" || req.Properties.String("name") != "Title" {
+		t.Errorf("Unexpected request %+v", req)
+	}
+	if content, _ := req.Properties.Content(); content.HTML != "<p>Hi</p>" {
+		t.Errorf("Unexpected content %+v", content)
+	}
+	want := []Photo{{URL: "https://me.example/a.jpg", Alt: "A cat"}, {URL: "https://me.example/b.jpg"}}
+	if got := req.Properties.Photos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got photos %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not JSON", `{`},
+		{"scalar property", `{"type": ["h-entry"], "properties": {"name": "Title"}}`},
+		{"no type", `{"properties": {}}`},
+		{"update without url", `{"action": "update", "replace": {"name": ["x"]}}`},
+		{"empty update", `{"action": "update", "url": "https://me.example/journals/1"}`},
+		{"bad delete", `{"action": "update", "url": "https://me.example/journals/1", "delete": "category"}`},
+		{"empty delete values", `{"action": "update", "url": "https://me.example/journals/1", "delete": {"category": []}}`},
+	}
+	for _, tt := range tests {
+		if _, err := ParseJSON(strings.NewReader(tt.body)); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%s: expected ErrInvalidRequest, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	req, err := ParseJSON(strings.NewReader(`{
+		"action": "update",
+		"url": "https://me.example/journals/1",
+		"replace": {"content": ["New content"]},
+		"add": {"category": ["added"]},
+		"delete": {"category": ["old"]}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+
+	props := Properties{
+		"content":  {"Old content"},
+		"category": {"old", "kept"},
+		"summary":  {"A summary"},
+	}
+	req.Apply(props)
+
+	want := Properties{
+		"content":  {"New content"},
+		"category": {"kept", "added"},
+		"summary":  {"A summary"},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("got %v, want %v", props, want)
+	}
+
+	req, _ = ParseJSON(strings.NewReader(`{"action": "update", "url": "x", "delete": ["summary"]}`))
+	req.Apply(props)
+	if _, ok := props["summary"]; ok {
+		t.Error("Expected summary to be deleted")
+	}
+}
@@ -0,0 +1,335 @@
+// Package ogimage renders the PNG share cards that social networks show
+// when a journal entry or project is linked.
+package ogimage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Width and Height are the recommended OpenGraph image dimensions.
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// version is mixed into every hash so a layout change invalidates the
+// cached cards.
+const version = "1"
+
+const (
+	padding       = 80
+	titleSize     = 64
+	titleLeading  = 80
+	titleMaxLines = 3
+	labelSize     = 28
+	metaSize      = 30
+)
+
+var (
+	background = color.RGBA{0x11, 0x18, 0x27, 0xff} // gray-900
+	accent     = color.RGBA{0x37, 0x41, 0x51, 0xff} // gray-700
+	primary    = color.RGBA{0xf9, 0xfa, 0xfb, 0xff} // gray-50
+	secondary  = color.RGBA{0x9c, 0xa3, 0xaf, 0xff} // gray-400
+)
+
+// Card holds everything drawn on a share image. Date is preformatted so
+// the caller decides the timezone and layout.
+type Card struct {
+	Kind     string // e.g. "Journal" or "Project"
+	Title    string
+	Date     string
+	Author   string
+	Tags     []string
+	SiteName string
+}
+
+// Hash identifies the rendered output of c. Two cards with the same hash
+// produce the same image.
+func (c Card) Hash() string {
+	h := sha256.New()
+	for _, field := range append([]string{version, c.Kind, c.Title, c.Date, c.Author, c.SiteName}, c.Tags...) {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+type fonts struct {
+	bold, medium, regular *opentype.Font
+}
+
+var loadFonts = sync.OnceValues(func() (fonts, error) {
+	var f fonts
+	var err error
+	if f.bold, err = opentype.Parse(gobold.TTF); err != nil {
+		return f, err
+	}
+	if f.medium, err = opentype.Parse(gomedium.TTF); err != nil {
+		return f, err
+	}
+	if f.regular, err = opentype.Parse(goregular.TTF); err != nil {
+		return f, err
+	}
+	return f, nil
+})
+
+func newFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// Render draws c and encodes it as a PNG.
+func Render(w io.Writer, c Card) error {
+	f, err := loadFonts()
+	if err != nil {
+		return fmt.Errorf("loading fonts: %w", err)
+	}
+
+	// Faces keep per-glyph scratch buffers, so each render gets its own
+	titleFace, err := newFace(f.bold, titleSize)
+	if err != nil {
+		return err
+	}
+	labelFace, err := newFace(f.medium, labelSize)
+	if err != nil {
+		return err
+	}
+	metaFace, err := newFace(f.regular, metaSize)
+	if err != nil {
+		return err
+	}
+	siteFace, err := newFace(f.medium, metaSize)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 16, Height), image.NewUniform(accent), image.Point{}, draw.Src)
+
+	textWidth := fixed.I(Width - 2*padding)
+
+	if c.Kind != "" {
+		drawText(img, labelFace, secondary, padding, 130, strings.ToUpper(c.Kind))
+	}
+
+	y := 230
+	for _, line := range wrap(titleFace, c.Title, textWidth, titleMaxLines) {
+		drawText(img, titleFace, primary, padding, y, line)
+		y += titleLeading
+	}
+
+	if len(c.Tags) > 0 {
+		tags := make([]string, len(c.Tags))
+		for i, tag := range c.Tags {
+			tags[i] = "#" + tag
+		}
+		line := wrap(metaFace, strings.Join(tags, "  "), textWidth, 1)
+		drawText(img, metaFace, secondary, padding, Height-150, line[0])
+	}
+
+	var byline []string
+	for _, s := range []string{c.Author, c.Date} {
+		if s != "" {
+			byline = append(byline, s)
+		}
+	}
+	siteWidth := font.MeasureString(siteFace, c.SiteName)
+	if len(byline) > 0 {
+		line := wrap(metaFace, strings.Join(byline, " · "), textWidth-siteWidth-fixed.I(40), 1)
+		drawText(img, metaFace, secondary, padding, Height-padding, line[0])
+	}
+	if c.SiteName != "" {
+		x := Width - padding - siteWidth.Ceil()
+		drawText(img, siteFace, primary, x, Height-padding, c.SiteName)
+	}
+
+	return png.Encode(w, img)
+}
+
+func drawText(dst draw.Image, face font.Face, c color.Color, x, y int, s string) {
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// wrap breaks s into at most maxLines lines no wider than width, ending
+// the last line with an ellipsis if the text didn't fit.
+func wrap(face font.Face, s string, width fixed.Int26_6, maxLines int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if font.MeasureString(face, candidate) <= width {
+			current = candidate
+			continue
+		}
+
+		if current != "" {
+			lines = append(lines, current)
+		}
+		current = word
+		// A single word wider than the line is cut rather than overflowing
+		for font.MeasureString(face, current) > width && len(lines) < maxLines {
+			head := fit(face, current, width)
+			lines = append(lines, head)
+			current = current[len(head):]
+		}
+
+		// Out of lines with text still to place
+		if len(lines) >= maxLines {
+			lines[maxLines-1] = ellipsize(face, lines[maxLines-1], width)
+			return lines[:maxLines]
+		}
+	}
+	return append(lines, current)
+}
+
+// fit returns the longest prefix of s that fits in width.
+func fit(face font.Face, s string, width fixed.Int26_6) string {
+	runes := []rune(s)
+	for n := len(runes) - 1; n > 0; n-- {
+		if font.MeasureString(face, string(runes[:n])) <= width {
+			return string(runes[:n])
+		}
+	}
+	return string(runes[:1])
+}
+
+func ellipsize(face font.Face, s string, width fixed.Int26_6) string {
+	for s != "" && font.MeasureString(face, s+"…") > width {
+		runes := []rune(s)
+		s = strings.TrimRight(string(runes[:len(runes)-1]), " ")
+	}
+	return s + "…"
+}
+
+// Cache stores rendered cards on disk, named by their hash, so each card
+// is only drawn once per distinct content. A changed title or setting
+// gives a new hash, so old cards are left behind until Prune removes them.
+type Cache struct {
+	dir string
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Get returns the PNG for c, rendering and storing it on first use. The
+// cache is best effort: when the disk can't be read or written, e.g.
+// because it is full or read-only, the card is rendered and returned.
+func (c *Cache) Get(card Card) ([]byte, error) {
+	path := filepath.Join(c.dir, card.Hash()+".png")
+
+	// #nosec G304 -- the file name is a hex digest, not user input
+	b, err := os.ReadFile(path)
+	if err == nil {
+		// Mark the card as used so Prune keeps it
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return b, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to read cached share image", "path", path, "error", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, card); err != nil {
+		return nil, err
+	}
+
+	if err := c.store(path, buf.Bytes()); err != nil {
+		slog.Warn("failed to cache share image", "path", path, "error", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Prune deletes cards that haven't been served for maxAge, along with
+// temporary files left by an interrupted write. It returns the number of
+// files deleted. Pruned cards are rendered again if they are asked for.
+func (c *Cache) Prune(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	deleted := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".png") && !strings.HasSuffix(name, ".png.tmp")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// store writes b to path via a temporary file so concurrent readers never
+// see a partial image.
+func (c *Cache) store(path string, b []byte) error {
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "*.png.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,178 @@
+package ogimage
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestRender(t *testing.T) {
+	card := Card{
+		Kind:     "Journal",
+		Title:    strings.Repeat("A rather long journal title that wraps ", 6),
+		Date:     "Mar 1, 2025",
+		Author:   "Sam",
+		Tags:     []string{"go", "sqlite"},
+		SiteName: "My Journal",
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, card); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+		t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), Width, Height)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	f, err := loadFonts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := newFace(f.bold, titleSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	width := fixed.I(Width - 2*padding)
+
+	if got := wrap(face, "Short title", width, 3); len(got) != 1 || got[0] != "Short title" {
+		t.Errorf("wrap(short) = %q", got)
+	}
+
+	lines := wrap(face, strings.Repeat("word ", 200), width, 3)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[2], "…") {
+		t.Errorf("expected the last line to be ellipsized, got %q", lines[2])
+	}
+
+	lines = wrap(face, strings.Repeat("x", 500), width, 3)
+	for _, line := range lines {
+		if font.MeasureString(face, line) > width {
+			t.Errorf("line %q overflows", line)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := Card{Title: "Hello", Tags: []string{"go"}}
+	b := Card{Title: "Hello", Tags: []string{"go"}}
+	if a.Hash() != b.Hash() {
+		t.Error("identical cards should hash the same")
+	}
+
+	b.Tags = []string{"g", "o"}
+	if a.Hash() == b.Hash() {
+		t.Error("tag boundaries should affect the hash")
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "og"))
+	card := Card{Title: "Cached"}
+
+	first, err := cache.Get(card)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	path := filepath.Join(dir, "og", card.Hash()+".png")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected cached file at %s: %v", path, err)
+	}
+
+	// A second Get must come from disk, so overwrite the file and check
+	// that its contents are returned untouched
+	if err := os.WriteFile(path, []byte("cached"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	second, err := cache.Get(card)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(second) != "cached" || len(first) == 0 {
+		t.Errorf("expected the cached bytes to be served, got %q", second)
+	}
+}
+
+func TestCacheWriteFailure(t *testing.T) {
+	// The cache directory can't be created under a regular file
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "og")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache(blocker)
+
+	b, err := cache.Get(Card{Title: "Uncached"})
+	if err != nil {
+		t.Fatalf("expected the card to be served without caching, got %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(b)); err != nil {
+		t.Errorf("expected a PNG, got %v", err)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir)
+
+	stale, fresh := Card{Title: "Old title"}, Card{Title: "New title"}
+	for _, card := range []Card{stale, fresh} {
+		if _, err := cache.Get(card); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{stale.Hash() + ".png", "leftover.png.tmp", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			os.WriteFile(path, []byte("x"), 0o600)
+		}
+		os.Chtimes(path, old, old)
+	}
+
+	deleted, err := cache.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected the stale card and temp file to be deleted, deleted %d", deleted)
+	}
+	for name, want := range map[string]bool{
+		stale.Hash() + ".png": false,
+		"leftover.png.tmp":    false,
+		"notes.txt":           true,
+		fresh.Hash() + ".png": true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+
+	// Serving a card keeps it
+	os.Chtimes(filepath.Join(dir, fresh.Hash()+".png"), old, old)
+	cache.Get(fresh)
+	if deleted, _ := cache.Prune(24 * time.Hour); deleted != 0 {
+		t.Errorf("expected a recently served card to be kept, deleted %d", deleted)
+	}
+
+	if _, err := NewCache(filepath.Join(dir, "missing")).Prune(time.Hour); err != nil {
+		t.Errorf("expected a missing directory to be empty, got %v", err)
+	}
+}
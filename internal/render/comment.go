@@ -0,0 +1,41 @@
+package render
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// commentMarkdown renders reader comments. Unlike entries, raw HTML is
+// dropped rather than passed through, and bare URLs become links.
+var commentMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.Linkify, extension.Strikethrough),
+)
+
+// commentPolicy allows basic text formatting and links, nothing more: no
+// images, headings or tables. Links are marked nofollow so comment spam
+// earns nothing.
+var commentPolicy = newCommentPolicy()
+
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "em", "strong", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Comment converts a reader's comment, written as Markdown, to sanitized
+// HTML.
+func Comment(body string) string {
+	var buf bytes.Buffer
+	if err := commentMarkdown.Convert([]byte(body), &buf); err != nil {
+		// Fall back to the escaped text; the policy keeps it safe either way
+		return commentPolicy.Sanitize("<p>" + bluemonday.StrictPolicy().Sanitize(body) + "</p>")
+	}
+	return commentPolicy.Sanitize(buf.String())
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "markdown",
+			body: "Nice **post**!\n\nSee https://example.com",
+			want: []string{"<strong>post</strong>", `<a href="https://example.com" rel="nofollow noopener" target="_blank">https://example.com</a>`},
+		},
+		{
+			name:    "raw html",
+			body:    `<script>alert(1)</script><img src=x onerror=alert(1)>hi`,
+			notWant: []string{"<script", "<img", "onerror"},
+		},
+		{
+			name:    "javascript link",
+			body:    "[click](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "heading and image",
+			body:    "# Big\n\n![x](https://example.com/x.png)",
+			want:    []string{"Big"},
+			notWant: []string{"<h1", "<img"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Comment(tt.body)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected %q in %s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Expected no %q in %s", notWant, got)
+				}
+			}
+		})
+	}
+}
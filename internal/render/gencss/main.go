@@ -0,0 +1,28 @@
+// Command gencss regenerates static/css/highlight.css from the chroma
+// styles used for code blocks.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/sianwa11/my-journal/internal/render"
+)
+
+func main() {
+	out := flag.String("o", "static/css/highlight.css", "output file")
+	light := flag.String("light", "github", "chroma style for light mode")
+	dark := flag.String("dark", "github-dark", "chroma style for dark mode")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := render.WriteHighlightCSS(f, *light, *dark); err != nil {
+		log.Fatal(err)
+	}
+}
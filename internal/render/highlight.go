@@ -0,0 +1,183 @@
+package render
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"golang.org/x/net/html"
+)
+
+// codeFormatter emits CSS classes rather than inline styles so the theme
+// lives in static/css/highlight.css and can follow the reader's light or
+// dark preference.
+var codeFormatter = chromahtml.New(chromahtml.WithClasses(true), chromahtml.TabWidth(4))
+
+// HighlightClasses lists every class highlighted code can carry, for the
+// HTML sanitizer to allow.
+func HighlightClasses() []string {
+	seen := map[string]bool{"chroma": true, "line": true, "cl": true}
+	for _, class := range chroma.StandardTypes {
+		if class != "" {
+			seen[class] = true
+		}
+	}
+
+	classes := make([]string, 0, len(seen))
+	for class := range seen {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// WriteHighlightCSS writes the stylesheet for highlighted code: the light
+// style by default and the dark one when the reader prefers it.
+func WriteHighlightCSS(w io.Writer, light, dark string) error {
+	if _, err := io.WriteString(w, "/* Generated by internal/render/gencss; do not edit. */\n\n"); err != nil {
+		return err
+	}
+	if err := codeFormatter.WriteCSS(w, styles.Get(light)); err != nil {
+		return err
+	}
+
+	var darkCSS strings.Builder
+	if err := codeFormatter.WriteCSS(&darkCSS, styles.Get(dark)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n@media (prefers-color-scheme: dark) {\n"+indent(darkCSS.String())+"}\n")
+	return err
+}
+
+func indent(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// highlightCode replaces <pre><code class="language-x"> blocks in src with
+// highlighted markup. Goldmark emits exactly this shape for fenced code and
+// it is also what HTML editors produce. Blocks in an unknown language, or
+// containing markup of their own, are left untouched.
+func highlightCode(src string) string {
+	if !strings.Contains(src, "language-") {
+		return src
+	}
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	var out strings.Builder
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return src
+			}
+			return out.String()
+		}
+
+		raw := string(z.Raw())
+		if tt != html.StartTagToken || tagName(z) != "pre" {
+			out.WriteString(raw)
+			continue
+		}
+
+		out.WriteString(highlightBlock(z, raw))
+	}
+}
+
+// highlightBlock consumes the tokens after an opening <pre> and returns
+// either the highlighted block or the original markup.
+func highlightBlock(z *html.Tokenizer, pre string) string {
+	consumed := pre
+
+	tt := z.Next()
+	consumed += string(z.Raw())
+	if tt != html.StartTagToken || tagName(z) != "code" {
+		return consumed
+	}
+
+	lang := ""
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == "class" {
+			lang = languageFromClass(string(val))
+		}
+		if !more {
+			break
+		}
+	}
+	if lang == "" {
+		return consumed
+	}
+
+	var code strings.Builder
+	for {
+		tt = z.Next()
+		consumed += string(z.Raw())
+
+		switch {
+		case tt == html.TextToken:
+			code.Write(z.Text())
+			continue
+		case tt == html.EndTagToken && tagName(z) == "code":
+		default:
+			return consumed
+		}
+		break
+	}
+
+	tt = z.Next()
+	consumed += string(z.Raw())
+	if tt != html.EndTagToken || tagName(z) != "pre" {
+		return consumed
+	}
+
+	highlighted, ok := highlight(lang, code.String())
+	if !ok {
+		return consumed
+	}
+	return highlighted
+}
+
+func tagName(z *html.Tokenizer) string {
+	name, _ := z.TagName()
+	return string(name)
+}
+
+// languageFromClass returns x from a "language-x" class, if present.
+func languageFromClass(class string) string {
+	for _, c := range strings.Fields(class) {
+		if lang, ok := strings.CutPrefix(c, "language-"); ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+func highlight(lang, code string) (string, bool) {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return "", false
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", false
+	}
+
+	var buf strings.Builder
+	if err := codeFormatter.Format(&buf, styles.Fallback, iterator); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightMarkdownFence(t *testing.T) {
+	got, err := HTML(FormatMarkdown, "```go\nfunc main() {}\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`<pre class="chroma">`, `<span class="kd">func</span>`, `<span class="nf">main</span>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("highlighted output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		highlighted bool
+	}{
+		{"html entry", `<p>x</p><pre><code class="language-python">print(&#34;hi&#34;)</code></pre>`, true},
+		{"extra classes", `<pre><code class="block language-js">let a = 1 &lt; 2</code></pre>`, true},
+		{"unknown language", `<pre><code class="language-nope">text</code></pre>`, false},
+		{"no language", `<pre><code>plain</code></pre>`, false},
+		{"markup inside", `<pre><code class="language-go">a<br>b</code></pre>`, false},
+		{"pre without code", `<pre class="language-go">x</pre>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightCode(tt.input)
+			if tt.highlighted != strings.Contains(got, `class="chroma"`) {
+				t.Errorf("highlightCode(%q) = %q", tt.input, got)
+			}
+			if !tt.highlighted && got != tt.input {
+				t.Errorf("untouched blocks must be preserved exactly, got %q", got)
+			}
+		})
+	}
+}
+
+func TestHighlightDecodesEntities(t *testing.T) {
+	got := highlightCode(`<pre><code class="language-python">print(&#34;a &amp; b&#34;)</code></pre>`)
+	if !strings.Contains(got, "&amp;") || strings.Contains(got, "&amp;amp;") {
+		t.Errorf("entities should be decoded once before highlighting: %s", got)
+	}
+}
@@ -0,0 +1,55 @@
+// Package render turns stored journal content into sanitized HTML: Markdown
+// is converted, code blocks highlighted, the result sanitized and headings
+// given anchors for the table of contents.
+package render
+
+//go:generate go run ./gencss -o ../../static/css/highlight.css
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Formats a journal entry's content can be stored in.
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// markdown renders CommonMark with the GFM extensions (tables, task lists,
+// strikethrough, autolinks) and footnotes. Raw HTML is passed through as
+// it would be for an HTML entry; sanitization happens afterwards.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// ValidFormat reports whether format is one render understands.
+func ValidFormat(format string) bool {
+	return format == FormatHTML || format == FormatMarkdown
+}
+
+// HTML converts content stored in format to sanitized HTML.
+func HTML(format, content string) (string, error) {
+	var out string
+	switch format {
+	case FormatHTML, "":
+		out = content
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return "", fmt.Errorf("rendering markdown: %w", err)
+		}
+		out = buf.String()
+	default:
+		return "", fmt.Errorf("unknown content format %q", format)
+	}
+
+	// Anchors are added after sanitizing so the ids are ours, not the
+	// author's; the policy lets them through when the page is displayed
+	return anchorHeadings(Sanitize(highlightCode(out))), nil
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLMarkdown(t *testing.T) {
+	src := `# Title
+
+| a | b |
+|---|---|
+| 1 | 2 |
+
+- [x] done
+- [ ] todo
+
+A note.[^1]
+
+~~gone~~
+
+[^1]: The footnote.
+`
+
+	got, err := HTML(FormatMarkdown, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		"<table>",
+		"<td>1</td>",
+		`<input checked="" disabled="" type="checkbox"`,
+		`<input disabled="" type="checkbox"`,
+		`<a href="#fn:1"`,
+		`<li id="fn:1">`,
+		"<del>gone</del>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered markdown missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHTMLPassthrough(t *testing.T) {
+	src := "<p>Already <b>HTML</b></p>\n# not a heading"
+	got, err := HTML(FormatHTML, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != src {
+		t.Errorf("HTML format should be returned untouched, got %q", got)
+	}
+}
+
+func TestHTMLUnknownFormat(t *testing.T) {
+	if _, err := HTML("rst", "text"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if ValidFormat("rst") || !ValidFormat(FormatMarkdown) || !ValidFormat(FormatHTML) {
+		t.Error("ValidFormat disagrees with the supported formats")
+	}
+}
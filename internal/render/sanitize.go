@@ -0,0 +1,38 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// AnchorClass marks the deep-link anchors added to headings.
+const AnchorClass = "heading-anchor"
+
+// policy is the UGC policy plus the markup this package produces that UGC
+// would otherwise strip: task list checkboxes, the classes used by syntax
+// highlighting and heading anchors.
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	classes := HighlightClasses()
+	for i, class := range classes {
+		classes[i] = regexp.QuoteMeta(class)
+	}
+	highlightClass := regexp.MustCompile(`^(?:` + strings.Join(classes, "|") + `)$`)
+	p.AllowAttrs("class").Matching(highlightClass).OnElements("pre", "code", "span")
+
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^` + AnchorClass + `$`)).OnElements("a")
+
+	return p
+}
+
+// Sanitize strips anything from s that isn't safe to serve to readers.
+func Sanitize(s string) string {
+	return policy.Sanitize(s)
+}
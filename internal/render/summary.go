@@ -0,0 +1,85 @@
+package render
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/sianwa11/my-journal/internal/seo"
+)
+
+const (
+	// ExcerptLength is the longest excerpt Summarize returns, in runes.
+	ExcerptLength = 240
+
+	// wordsPerMinute is a typical adult silent reading speed.
+	wordsPerMinute = 200
+)
+
+// Summary holds the figures shown alongside an entry in lists.
+type Summary struct {
+	WordCount      int
+	ReadingMinutes int
+	Excerpt        string
+}
+
+// Summarize measures rendered HTML. Reading time rounds up, so any entry
+// with words in it takes at least a minute. Code blocks count towards the
+// reading time but are left out of the excerpt, as are footnotes.
+func Summarize(renderedHTML string) Summary {
+	words := len(strings.Fields(seo.PlainText(renderedHTML)))
+
+	return Summary{
+		WordCount:      words,
+		ReadingMinutes: (words + wordsPerMinute - 1) / wordsPerMinute,
+		Excerpt:        seo.Excerpt(prose(renderedHTML), ExcerptLength),
+	}
+}
+
+// prose drops the parts of src that read badly out of context: code
+// blocks, footnote references and the footnote list.
+func prose(src string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(src))
+	skip := ""
+	depth := 0
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return b.String()
+		}
+
+		raw := string(z.Raw())
+		token := z.Token()
+		if skip != "" {
+			switch {
+			case tt == html.StartTagToken && token.Data == skip:
+				depth++
+			case tt == html.EndTagToken && token.Data == skip:
+				if depth--; depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		if tt == html.StartTagToken && isNonProse(token) {
+			skip, depth = token.Data, 1
+			continue
+		}
+		b.WriteString(raw)
+	}
+}
+
+func isNonProse(t html.Token) bool {
+	if t.Data == "pre" {
+		return true
+	}
+	for _, attr := range t.Attr {
+		if attr.Key == "class" && (attr.Val == "footnotes" || attr.Val == "footnote-ref") {
+			return true
+		}
+	}
+	return false
+}
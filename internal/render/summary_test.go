@@ -0,0 +1,41 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		wantWords   int
+		wantMinutes int
+	}{
+		{"empty", "", 0, 0},
+		{"short", "<p>Hello <b>there</b> world</p>", 3, 1},
+		{"exactly one minute", "<p>" + strings.Repeat("word ", 200) + "</p>", 200, 1},
+		{"just over", "<p>" + strings.Repeat("word ", 201) + "</p>", 201, 2},
+		{"blocks", "<h2>One</h2><p>Two</p><ul><li>Three</li></ul>", 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summarize(tt.html)
+			if got.WordCount != tt.wantWords || got.ReadingMinutes != tt.wantMinutes {
+				t.Errorf("Summarize = %d words, %d min; want %d words, %d min",
+					got.WordCount, got.ReadingMinutes, tt.wantWords, tt.wantMinutes)
+			}
+		})
+	}
+
+	long := Summarize("<p>" + strings.Repeat("lorem ipsum ", 100) + "</p>")
+	if n := len([]rune(long.Excerpt)); n > ExcerptLength || !strings.HasSuffix(long.Excerpt, "…") {
+		t.Errorf("excerpt should be cut to %d runes with an ellipsis, got %d: %q", ExcerptLength, n, long.Excerpt)
+	}
+
+	code := Summarize(`<p>Intro<sup id="fnref:1"><a href="#fn:1" class="footnote-ref">1</a></sup></p><pre class="chroma"><code>fmt.Println()</code></pre><p>Outro</p><div class="footnotes"><ol><li>Note</li></ol></div>`)
+	if code.Excerpt != "Intro Outro" {
+		t.Errorf("excerpt should skip code and footnotes, got %q", code.Excerpt)
+	}
+}
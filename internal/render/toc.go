@@ -0,0 +1,169 @@
+package render
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Heading is one entry in a table of contents.
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// tocLevels are the heading levels that get anchors and appear in the
+// table of contents; h1 is the entry title.
+var tocLevels = map[string]int{"h2": 2, "h3": 3, "h4": 4}
+
+// anchorHeadings gives every h2–h4 in src a slug id derived from its text,
+// replacing any id it had, and appends a deep-link anchor to it.
+func anchorHeadings(src string) string {
+	z := html.NewTokenizer(strings.NewReader(src))
+	var out strings.Builder
+	used := map[string]int{}
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return src
+			}
+			return out.String()
+		}
+
+		raw := string(z.Raw())
+		token := z.Token()
+		if _, ok := tocLevels[token.Data]; !ok || tt != html.StartTagToken {
+			out.WriteString(raw)
+			continue
+		}
+
+		// Buffer the heading's contents to slug its text
+		var inner strings.Builder
+		var text strings.Builder
+		for depth := 1; depth > 0; {
+			tt = z.Next()
+			if tt == html.ErrorToken {
+				break
+			}
+			t := z.Token()
+			switch {
+			case tt == html.StartTagToken && t.Data == token.Data:
+				depth++
+			case tt == html.EndTagToken && t.Data == token.Data:
+				depth--
+			case tt == html.TextToken:
+				text.WriteString(t.Data)
+			}
+			if depth > 0 {
+				inner.WriteString(t.String())
+			}
+		}
+
+		id := uniqueSlug(text.String(), used)
+		attrs := []html.Attribute{{Key: "id", Val: id}}
+		for _, a := range token.Attr {
+			if a.Key != "id" {
+				attrs = append(attrs, a)
+			}
+		}
+		token.Attr = attrs
+
+		out.WriteString(token.String())
+		out.WriteString(inner.String())
+		out.WriteString(`<a href="#` + id + `" class="` + AnchorClass + `" title="Link to this section"></a>`)
+		out.WriteString("</" + token.Data + ">")
+	}
+}
+
+// TableOfContents lists the anchored headings in rendered HTML, in
+// document order.
+func TableOfContents(src string) []Heading {
+	z := html.NewTokenizer(strings.NewReader(src))
+	headings := []Heading{}
+
+	var current *Heading
+	var text strings.Builder
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return headings
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			level, ok := tocLevels[string(name)]
+			if !ok || current != nil {
+				continue
+			}
+			id := ""
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "id" {
+					id = string(val)
+				}
+			}
+			if id != "" {
+				current = &Heading{Level: level, ID: id}
+				text.Reset()
+			}
+		case html.TextToken:
+			if current != nil {
+				text.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if current != nil && tocLevels[string(name)] == current.Level {
+				current.Text = strings.Join(strings.Fields(text.String()), " ")
+				headings = append(headings, *current)
+				current = nil
+			}
+		}
+	}
+}
+
+// slugify lowercases s and joins its ASCII letters and digits with
+// hyphens. Ids must stay within what the sanitizer accepts.
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		default:
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+// uniqueSlug suffixes repeated slugs with -1, -2, … so every heading on a
+// page gets its own id.
+func uniqueSlug(text string, used map[string]int) string {
+	slug := slugify(text)
+	n := used[slug]
+	used[slug] = n + 1
+	if n == 0 {
+		return slug
+	}
+
+	candidate := slug + "-" + strconv.Itoa(n)
+	for used[candidate] > 0 {
+		n++
+		candidate = slug + "-" + strconv.Itoa(n)
+	}
+	used[candidate] = 1
+	return candidate
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnchorHeadings(t *testing.T) {
+	got, err := HTML(FormatMarkdown, "# Title\n\n## Getting Started\n\n### Install `go`\n\n## Getting Started\n\n##### Too deep\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<h1>Title</h1>",
+		`<h2 id="getting-started">Getting Started<a href="#getting-started" class="heading-anchor" title="Link to this section"></a></h2>`,
+		`<h3 id="install-go">Install <code>go</code><a href="#install-go"`,
+		`<h2 id="getting-started-1">`,
+		"<h5>Too deep</h5>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered HTML missing %q:\n%s", want, got)
+		}
+	}
+
+	// Anchors must survive a second pass through the sanitizer, which is
+	// what happens when the page is displayed
+	if again := Sanitize(got); !strings.Contains(again, `<h2 id="getting-started">`) || !strings.Contains(again, `class="heading-anchor"`) {
+		t.Errorf("anchors were stripped by the sanitizer:\n%s", again)
+	}
+}
+
+func TestAnchorHeadingsReplacesAuthorIDs(t *testing.T) {
+	got, err := HTML(FormatHTML, `<h2 id="custom" title="x">Hello <em>World</em></h2>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, `<h2 id="hello-world" title="x">Hello <em>World</em>`) {
+		t.Errorf("unexpected heading: %s", got)
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	rendered, err := HTML(FormatMarkdown, "## One\n\ntext\n\n### One & a half\n\n#### Deep\n\n## Two\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Heading{
+		{Level: 2, ID: "one", Text: "One"},
+		{Level: 3, ID: "one-a-half", Text: "One & a half"},
+		{Level: 4, ID: "deep", Text: "Deep"},
+		{Level: 2, ID: "two", Text: "Two"},
+	}
+	if got := TableOfContents(rendered); !reflect.DeepEqual(got, want) {
+		t.Errorf("TableOfContents = %+v, want %+v", got, want)
+	}
+
+	if got := TableOfContents("<p>no headings</p>"); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty, non-nil TOC, got %#v", got)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":    "hello-world",
+		"  spaces  around": "spaces-around",
+		"Café au lait":     "caf-au-lait",
+		"???":              "section",
+		"v1.2 release":     "v1-2-release",
+	}
+	for input, want := range tests {
+		if got := slugify(input); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
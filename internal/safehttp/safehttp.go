@@ -0,0 +1,64 @@
+// Package safehttp builds HTTP clients for fetching URLs that strangers
+// hand us, such as Webmention sources and ActivityPub actors.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxRedirects is how many redirects a request follows.
+	maxRedirects = 5
+
+	// requestTimeout bounds a single request, including redirects.
+	requestTimeout = 10 * time.Second
+)
+
+// ErrPrivateAddress is returned when a request would reach a loopback,
+// private or link-local address.
+var ErrPrivateAddress = errors.New("safehttp: refusing to connect to a private address")
+
+// NewClient returns an *http.Client that times out, follows a handful of
+// redirects and refuses to dial anything but public addresses. The check
+// runs on the resolved address, so DNS tricks can't get around it.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !publicIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("safehttp: stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast())
+}
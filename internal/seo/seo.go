@@ -0,0 +1,170 @@
+// Package seo builds the metadata that search engines and social networks
+// read from a page: descriptions, OpenGraph/Twitter tags and JSON-LD.
+package seo
+
+import (
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const DescriptionLength = 160
+
+var strictPolicy = bluemonday.StrictPolicy()
+
+// Meta is rendered by the "seo" template partial into <title>, meta,
+// OpenGraph, Twitter card and JSON-LD tags.
+type Meta struct {
+	Title       string
+	Description string
+	Canonical   string
+	Type        string // OpenGraph type: "website", "article" or "profile"
+	Image       string
+	ImageWidth  int
+	ImageHeight int
+	SiteName    string
+	Author      string
+	Published   time.Time
+	Modified    time.Time
+	Tags        []string
+	JSONLD      interface{}
+	NoIndex     bool // keep the page out of search results
+}
+
+// PlainText strips all markup from s and collapses whitespace.
+func PlainText(s string) string {
+	// Keep words in adjacent block elements apart once the tags are gone
+	s = strings.ReplaceAll(s, "<", " <")
+	s = html.UnescapeString(strictPolicy.Sanitize(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Excerpt returns the plain text of s cut to at most maxLen runes on a word
+// boundary, with an ellipsis when anything was dropped.
+func Excerpt(s string, maxLen int) string {
+	text := PlainText(s)
+	if utf8.RuneCountInString(text) <= maxLen {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:maxLen-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+// FirstNonEmpty returns the first argument that isn't blank.
+func FirstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Person is a schema.org Person.
+type Person struct {
+	Context     string   `json:"@context,omitempty"`
+	Type        string   `json:"@type"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url,omitempty"`
+	Description string   `json:"description,omitempty"`
+	SameAs      []string `json:"sameAs,omitempty"`
+}
+
+// BlogPosting is a schema.org BlogPosting.
+type BlogPosting struct {
+	Context          string   `json:"@context"`
+	Type             string   `json:"@type"`
+	Headline         string   `json:"headline"`
+	Description      string   `json:"description,omitempty"`
+	URL              string   `json:"url"`
+	MainEntityOfPage string   `json:"mainEntityOfPage"`
+	Image            string   `json:"image,omitempty"`
+	DatePublished    string   `json:"datePublished,omitempty"`
+	DateModified     string   `json:"dateModified,omitempty"`
+	Author           Person   `json:"author"`
+	Keywords         []string `json:"keywords,omitempty"`
+}
+
+// CreativeWork is a schema.org CreativeWork.
+type CreativeWork struct {
+	Context         string   `json:"@context"`
+	Type            string   `json:"@type"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description,omitempty"`
+	URL             string   `json:"url"`
+	Image           string   `json:"image,omitempty"`
+	CodeRepository  string   `json:"codeRepository,omitempty"`
+	CreativeWorkURL string   `json:"sameAs,omitempty"`
+	DateCreated     string   `json:"dateCreated,omitempty"`
+	DateModified    string   `json:"dateModified,omitempty"`
+	Author          Person   `json:"author"`
+	Keywords        []string `json:"keywords,omitempty"`
+}
+
+// NewPerson returns a top-level schema.org Person document.
+func NewPerson(name, url, description string, sameAs []string) Person {
+	return Person{
+		Context:     "https://schema.org",
+		Type:        "Person",
+		Name:        name,
+		URL:         url,
+		Description: description,
+		SameAs:      sameAs,
+	}
+}
+
+// Author returns a Person suitable for nesting inside another document.
+func Author(name, url string) Person {
+	return Person{Type: "Person", Name: name, URL: url}
+}
+
+// NewBlogPosting describes a journal entry.
+func NewBlogPosting(m Meta, author Person) BlogPosting {
+	return BlogPosting{
+		Context:          "https://schema.org",
+		Type:             "BlogPosting",
+		Headline:         m.Title,
+		Description:      m.Description,
+		URL:              m.Canonical,
+		MainEntityOfPage: m.Canonical,
+		Image:            m.Image,
+		DatePublished:    formatDate(m.Published),
+		DateModified:     formatDate(m.Modified),
+		Author:           author,
+		Keywords:         m.Tags,
+	}
+}
+
+// NewCreativeWork describes a project.
+func NewCreativeWork(m Meta, author Person, repository, link string) CreativeWork {
+	return CreativeWork{
+		Context:         "https://schema.org",
+		Type:            "CreativeWork",
+		Name:            m.Title,
+		Description:     m.Description,
+		URL:             m.Canonical,
+		Image:           m.Image,
+		CodeRepository:  repository,
+		CreativeWorkURL: link,
+		DateCreated:     formatDate(m.Published),
+		DateModified:    formatDate(m.Modified),
+		Author:          author,
+		Keywords:        m.Tags,
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
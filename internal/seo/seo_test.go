@@ -0,0 +1,71 @@
+package seo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		maxLen int
+		want   string
+	}{
+		{"short", "<p>Hello <b>world</b></p>", 160, "Hello world"},
+		{"blocks kept apart", "<p>One</p><p>Two</p>", 160, "One Two"},
+		{"entities decoded", "<p>Fish &amp; chips</p>", 160, "Fish & chips"},
+		{"scripts dropped", "<script>alert(1)</script>Safe", 160, "Safe"},
+		{"word boundary", "The quick brown fox jumps", 12, "The quick…"},
+		{"trailing punctuation", "Hello, world again", 8, "Hello…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.input, tt.maxLen); got != tt.want {
+				t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.input, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstNonEmpty(t *testing.T) {
+	if got := FirstNonEmpty("", "  ", "b", "c"); got != "b" {
+		t.Errorf("FirstNonEmpty = %q, want %q", got, "b")
+	}
+	if got := FirstNonEmpty("", ""); got != "" {
+		t.Errorf("FirstNonEmpty = %q, want empty", got)
+	}
+}
+
+func TestNewBlogPosting(t *testing.T) {
+	published := time.Date(2025, 3, 1, 9, 30, 0, 0, time.FixedZone("EAT", 3*60*60))
+	meta := Meta{
+		Title:     "Hello",
+		Canonical: "https://example.com/journals/1",
+		Published: published,
+	}
+
+	b, err := json.Marshal(NewBlogPosting(meta, Author("Sam", "https://example.com/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(b)
+	for _, want := range []string{
+		`"@context":"https://schema.org"`,
+		`"@type":"BlogPosting"`,
+		`"headline":"Hello"`,
+		`"datePublished":"2025-03-01T06:30:00Z"`,
+		`"author":{"@type":"Person","name":"Sam","url":"https://example.com/"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JSON-LD %s missing %s", got, want)
+		}
+	}
+	if strings.Contains(got, "dateModified") {
+		t.Errorf("zero modified time should be omitted: %s", got)
+	}
+}
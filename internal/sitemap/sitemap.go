@@ -0,0 +1,110 @@
+// Package sitemap writes sitemaps and sitemap indexes as described at
+// https://www.sitemaps.org/protocol.html.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// MaxURLs is the most URLs a single sitemap file may list.
+const MaxURLs = 50000
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL is a single <url> entry. LastMod is omitted when zero.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// Pages returns how many sitemap files are needed for n URLs.
+func Pages(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	return (n + MaxURLs - 1) / MaxURLs
+}
+
+// Page returns the URLs belonging to the 1-based sitemap file page, or nil
+// if page is out of range.
+func Page(urls []URL, page int) []URL {
+	if page < 1 || page > Pages(len(urls)) {
+		return nil
+	}
+	start := (page - 1) * MaxURLs
+	end := min(start+MaxURLs, len(urls))
+	return urls[start:end]
+}
+
+// WriteURLSet writes urls as a <urlset> document.
+func WriteURLSet(w io.Writer, urls []URL) error {
+	set := urlSet{Xmlns: xmlns, URLs: make([]urlEntry, 0, len(urls))}
+	for _, u := range urls {
+		set.URLs = append(set.URLs, urlEntry{Loc: u.Loc, LastMod: formatLastMod(u.LastMod)})
+	}
+	return write(w, set)
+}
+
+// WriteIndex writes a <sitemapindex> pointing at each of the given sitemap
+// files. The entries' LastMod is the newest of the URLs they contain.
+func WriteIndex(w io.Writer, sitemaps []URL) error {
+	index := sitemapIndex{Xmlns: xmlns, Sitemaps: make([]sitemapEntry, 0, len(sitemaps))}
+	for _, s := range sitemaps {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: s.Loc, LastMod: formatLastMod(s.LastMod)})
+	}
+	return write(w, index)
+}
+
+// Newest returns the most recent LastMod among urls.
+func Newest(urls []URL) time.Time {
+	var newest time.Time
+	for _, u := range urls {
+		if u.LastMod.After(newest) {
+			newest = u.LastMod
+		}
+	}
+	return newest
+}
+
+func write(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,76 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteURLSet(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteURLSet(&buf, []URL{
+		{Loc: "https://example.com/"},
+		{Loc: "https://example.com/journals/1?a=1&b=2", LastMod: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		xml.Header,
+		`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`,
+		`<loc>https://example.com/</loc>`,
+		`<loc>https://example.com/journals/1?a=1&amp;b=2</loc>`,
+		`<lastmod>2025-01-02T03:04:05Z</lastmod>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sitemap missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "<lastmod>") != 1 {
+		t.Errorf("expected lastmod only on the dated URL:\n%s", got)
+	}
+}
+
+func TestPaging(t *testing.T) {
+	urls := make([]URL, MaxURLs+10)
+	for i := range urls {
+		urls[i] = URL{Loc: "https://example.com/" + strconv.Itoa(i)}
+	}
+
+	if got := Pages(len(urls)); got != 2 {
+		t.Fatalf("Pages = %d, want 2", got)
+	}
+	if got := Pages(0); got != 1 {
+		t.Errorf("Pages(0) = %d, want 1", got)
+	}
+	if got := len(Page(urls, 1)); got != MaxURLs {
+		t.Errorf("len(Page 1) = %d, want %d", got, MaxURLs)
+	}
+	if got := len(Page(urls, 2)); got != 10 {
+		t.Errorf("len(Page 2) = %d, want 10", got)
+	}
+	if Page(urls, 3) != nil || Page(urls, 0) != nil {
+		t.Error("out of range pages should be nil")
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteIndex(&buf, []URL{{Loc: "https://example.com/sitemap/1.xml"}, {Loc: "https://example.com/sitemap/2.xml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	if !strings.Contains(got, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`) {
+		t.Errorf("missing sitemapindex root:\n%s", got)
+	}
+	if strings.Count(got, "<sitemap>") != 2 {
+		t.Errorf("expected two sitemap entries:\n%s", got)
+	}
+}
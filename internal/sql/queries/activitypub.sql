@@ -0,0 +1,31 @@
+-- name: GetActorKey :one
+SELECT * FROM activitypub_keys
+WHERE id = 1;
+
+-- name: CreateActorKey :exec
+INSERT OR IGNORE INTO activitypub_keys (id, private_key_pem, public_key_pem)
+VALUES (1, ?, ?);
+
+-- name: UpsertFollower :exec
+INSERT INTO activitypub_followers (actor_id, inbox, shared_inbox, follow_id)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (actor_id) DO UPDATE SET
+  inbox = excluded.inbox,
+  shared_inbox = excluded.shared_inbox,
+  follow_id = excluded.follow_id;
+
+-- name: GetFollower :one
+SELECT * FROM activitypub_followers
+WHERE actor_id = ?;
+
+-- name: DeleteFollower :execrows
+DELETE FROM activitypub_followers
+WHERE actor_id = ?;
+
+-- name: ListFollowerInboxes :many
+SELECT DISTINCT CAST(COALESCE(NULLIF(shared_inbox, ''), inbox) AS TEXT) AS inbox
+FROM activitypub_followers
+ORDER BY 1;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM activitypub_followers;
@@ -0,0 +1,69 @@
+-- name: CreateAnalyticsSalt :exec
+INSERT INTO analytics_salts (day, salt)
+VALUES (?, ?)
+ON CONFLICT (day) DO NOTHING;
+
+-- name: GetAnalyticsSalt :one
+SELECT salt FROM analytics_salts
+WHERE day = ?;
+
+-- name: DeleteAnalyticsSaltsBefore :exec
+DELETE FROM analytics_salts
+WHERE day < ?;
+
+-- name: RecordPageView :exec
+INSERT INTO page_views (day, path, referrer, visitor)
+VALUES (?, ?, ?, ?);
+
+-- name: RollupSiteViews :exec
+-- Totals are recomputed from scratch, so rolling up the same day twice
+-- is harmless.
+INSERT INTO page_view_rollups (day, dimension, value, views, visitors)
+SELECT day, 'site', '', COUNT(*), COUNT(DISTINCT visitor)
+FROM page_views
+WHERE true
+GROUP BY day
+ON CONFLICT (day, dimension, value) DO UPDATE SET views = excluded.views, visitors = excluded.visitors;
+
+-- name: RollupPageViews :exec
+INSERT INTO page_view_rollups (day, dimension, value, views, visitors)
+SELECT day, 'page', path, COUNT(*), COUNT(DISTINCT visitor)
+FROM page_views
+WHERE true
+GROUP BY day, path
+ON CONFLICT (day, dimension, value) DO UPDATE SET views = excluded.views, visitors = excluded.visitors;
+
+-- name: RollupReferrerViews :exec
+INSERT INTO page_view_rollups (day, dimension, value, views, visitors)
+SELECT day, 'referrer', referrer, COUNT(*), COUNT(DISTINCT visitor)
+FROM page_views
+WHERE referrer != ''
+GROUP BY day, referrer
+ON CONFLICT (day, dimension, value) DO UPDATE SET views = excluded.views, visitors = excluded.visitors;
+
+-- name: DeletePageViewsBefore :execrows
+DELETE FROM page_views
+WHERE day < ?;
+
+-- name: GetDailyViews :many
+SELECT day, views, visitors FROM page_view_rollups
+WHERE dimension = 'site' AND day >= ?
+ORDER BY day;
+
+-- name: GetTopPages :many
+-- Visitors are summed over days, so someone who came back on another day
+-- counts again.
+SELECT value AS path, CAST(SUM(views) AS INTEGER) AS views, CAST(SUM(visitors) AS INTEGER) AS visitors
+FROM page_view_rollups
+WHERE dimension = 'page' AND day >= ?
+GROUP BY value
+ORDER BY views DESC, value
+LIMIT ?;
+
+-- name: GetTopReferrers :many
+SELECT value AS referrer, CAST(SUM(views) AS INTEGER) AS views, CAST(SUM(visitors) AS INTEGER) AS visitors
+FROM page_view_rollups
+WHERE dimension = 'referrer' AND day >= ?
+GROUP BY value
+ORDER BY views DESC, value
+LIMIT ?;
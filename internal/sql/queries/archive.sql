@@ -0,0 +1,14 @@
+-- name: ListJournalDates :many
+SELECT created_at FROM journal_entries
+WHERE deleted_at IS NULL AND visibility = 'public'
+ORDER BY created_at DESC;
+
+-- name: GetJournalsBetween :many
+-- Bounds are UTC "YYYY-MM-DD HH:MM:SS" strings, the format CURRENT_TIMESTAMP
+-- stores, so the comparison can use the created_at index.
+SELECT id, title, excerpt, word_count, reading_minutes, created_at FROM journal_entries
+WHERE created_at >= CAST(sqlc.arg(start) AS TEXT)
+  AND created_at < CAST(sqlc.arg(end) AS TEXT)
+  AND deleted_at IS NULL
+  AND visibility = 'public'
+ORDER BY created_at DESC;
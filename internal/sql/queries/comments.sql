@@ -0,0 +1,42 @@
+-- name: CreateComment :one
+INSERT INTO comments (journal_id, parent_id, author_name, author_email, author_url, body, status, is_owner)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM comments
+WHERE id = ?;
+
+-- name: ListApprovedComments :many
+SELECT * FROM comments
+WHERE journal_id = ? AND status = 'approved'
+ORDER BY created_at, id;
+
+-- name: ListCommentsByStatus :many
+SELECT comments.id, comments.journal_id, comments.parent_id, comments.author_name,
+  comments.author_email, comments.author_url, comments.body, comments.status,
+  comments.is_owner, comments.created_at, journal_entries.title AS journal_title
+FROM comments
+JOIN journal_entries ON journal_entries.id = comments.journal_id
+WHERE comments.status = ?
+ORDER BY comments.created_at DESC, comments.id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountCommentsByStatus :many
+SELECT status, COUNT(*) AS count FROM comments
+GROUP BY status;
+
+-- name: SetCommentStatus :execrows
+UPDATE comments
+set status = ?,
+updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeleteComment :execrows
+-- Removes a comment along with the owner's replies to it.
+DELETE FROM comments
+WHERE id = sqlc.arg(id) OR parent_id = sqlc.arg(id);
+
+-- name: DeleteJournalComments :exec
+DELETE FROM comments
+WHERE journal_id = ?;
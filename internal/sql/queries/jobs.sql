@@ -0,0 +1,55 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (kind, payload, max_attempts, run_at)
+VALUES (sqlc.arg(kind), sqlc.arg(payload), sqlc.arg(max_attempts), CAST(sqlc.arg(run_at) AS TEXT))
+RETURNING id;
+
+-- name: ClaimJob :one
+-- Takes the oldest due job, or a running one whose lease has run out, in
+-- a single statement so two workers can't claim the same job.
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1,
+  locked_until = CAST(sqlc.arg(locked_until) AS TEXT), updated_at = CURRENT_TIMESTAMP
+WHERE id = (
+  SELECT id FROM jobs
+  WHERE (status = 'pending' AND run_at <= CAST(sqlc.arg(now) AS TEXT))
+    OR (status = 'running' AND locked_until <= CAST(sqlc.arg(now) AS TEXT))
+  ORDER BY run_at, id
+  LIMIT 1
+)
+RETURNING *;
+
+-- name: CompleteJob :execrows
+-- The status and attempts fence the update: a worker whose lease ran out
+-- while another claimed the job matches nothing.
+DELETE FROM jobs
+WHERE id = sqlc.arg(id) AND status = 'running' AND attempts = sqlc.arg(attempts);
+
+-- name: RetryJob :execrows
+UPDATE jobs
+SET status = 'pending', locked_until = NULL, last_error = sqlc.arg(last_error),
+  run_at = CAST(sqlc.arg(run_at) AS TEXT), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status = 'running' AND attempts = sqlc.arg(attempts);
+
+-- name: KillJob :execrows
+UPDATE jobs
+SET status = 'dead', locked_until = NULL, last_error = sqlc.arg(last_error), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status = 'running' AND attempts = sqlc.arg(attempts);
+
+-- name: RequeueDeadJob :execrows
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status = 'dead';
+
+-- name: DeleteDeadJob :execrows
+DELETE FROM jobs
+WHERE id = ? AND status = 'dead';
+
+-- name: ListJobsByStatus :many
+SELECT * FROM jobs
+WHERE status = ?
+ORDER BY updated_at DESC, id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountJobsByStatus :many
+SELECT status, COUNT(*) AS count FROM jobs
+GROUP BY status;
@@ -1,30 +1,44 @@
 -- name: CreateJournalEntry :one
-INSERT INTO journal_entries (title, content, user_id)
-VALUES (?, ?, ?)
+INSERT INTO journal_entries (title, content, user_id, seo_title, seo_description, format, content_html, word_count, reading_minutes, excerpt, visibility, share_token, encrypted)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetJournals :many
+-- Lists public entries, plus every entry owned by viewer_id. Pass 0 for
+-- anonymous visitors.
 SELECT * FROM journal_entries
+WHERE deleted_at IS NULL AND (visibility = 'public' OR user_id = sqlc.arg(viewer_id))
 ORDER BY id DESC
-LIMIT ? OFFSET ?;
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
 
 -- name: UpdateJournalEntry :exec
 UPDATE journal_entries
 set title = ?,
 content = ?,
+seo_title = ?,
+seo_description = ?,
+format = ?,
+content_html = ?,
+word_count = ?,
+reading_minutes = ?,
+excerpt = ?,
+visibility = ?,
+share_token = ?,
+encrypted = ?,
 updated_at = CURRENT_TIMESTAMP
-WHERE id = ?;
+WHERE id = ? AND deleted_at IS NULL;
 
 -- name: GetUsersJournal :one
 SELECT * FROM journal_entries
-WHERE id = ? AND user_id = ?;
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
 
 -- name: GetAllJournalsCount :one
-SELECT COUNT(*) as count FROM journal_entries;
+SELECT COUNT(*) as count FROM journal_entries
+WHERE deleted_at IS NULL AND (visibility = 'public' OR user_id = sqlc.arg(viewer_id));
 
 -- name: GetJournalEntry :one
 SELECT * FROM journal_entries
-WHERE id = ?;
+WHERE id = ? AND deleted_at IS NULL;
 
 -- name: GetPrevAndNextJournalIDs :one 
 WITH ordered AS (
@@ -33,10 +47,29 @@ WITH ordered AS (
     LAG(id) OVER (ORDER BY id) AS previous_id,
     LEAD(id) OVER (ORDER BY id) AS next_id
   FROM journal_entries
+  WHERE deleted_at IS NULL AND visibility = 'public'
 )
 SELECT * FROM ordered WHERE id = ?;
 
 
--- name: DeleteJournalEntry :exec
-DELETE FROM journal_entries
-WHERE id = ? AND user_id = ?;
+-- name: TrashJournalEntry :exec
+UPDATE journal_entries
+set deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND user_id = ? AND deleted_at IS NULL;
+
+-- name: SetJournalRendered :exec
+UPDATE journal_entries
+set content_html = ?,
+word_count = ?,
+reading_minutes = ?,
+excerpt = ?
+WHERE id = ?;
+
+-- name: GetJournalByShareToken :one
+SELECT * FROM journal_entries
+WHERE share_token = ? AND visibility = 'unlisted' AND deleted_at IS NULL;
+
+-- name: SetJournalShareToken :exec
+UPDATE journal_entries
+set share_token = ?
+WHERE id = ? AND deleted_at IS NULL;
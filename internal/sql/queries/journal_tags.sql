@@ -0,0 +1,13 @@
+-- name: CreateJournalTagIfNotExists :exec
+INSERT OR IGNORE INTO journal_tags (journal_id, tag_id)
+VALUES (?, ?);
+
+-- name: DeleteJournalTags :exec
+DELETE FROM journal_tags
+WHERE journal_id = ?;
+
+-- name: ListJournalTagNames :many
+SELECT tags.name FROM journal_tags
+JOIN tags ON tags.id = journal_tags.tag_id
+WHERE journal_tags.journal_id = ?
+ORDER BY tags.name;
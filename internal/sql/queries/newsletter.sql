@@ -0,0 +1,88 @@
+-- name: GetSubscriberByEmail :one
+SELECT * FROM subscribers
+WHERE email = ?;
+
+-- name: CreateSubscriber :one
+INSERT INTO subscribers (email, confirm_token, unsubscribe_token)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: ResubscribeSubscriber :one
+-- Starts a pending or unsubscribed address over with a fresh
+-- confirmation token.
+UPDATE subscribers
+SET status = 'pending', confirm_token = ?, unsubscribed_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: GetSubscriber :one
+SELECT * FROM subscribers
+WHERE id = ?;
+
+-- name: ConfirmSubscriber :execrows
+UPDATE subscribers
+SET status = 'confirmed', confirmed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE confirm_token = sqlc.arg(token) AND status = 'pending'
+  AND updated_at >= CAST(sqlc.arg(issued_after) AS TEXT);
+
+-- name: GetSubscriberByUnsubscribeToken :one
+SELECT * FROM subscribers
+WHERE unsubscribe_token = ?;
+
+-- name: UnsubscribeSubscriber :exec
+UPDATE subscribers
+SET status = 'unsubscribed', unsubscribed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND status != 'unsubscribed';
+
+-- name: CountSubscribersByStatus :many
+SELECT status, COUNT(*) AS count FROM subscribers
+GROUP BY status;
+
+-- name: ListConfirmedSubscribersAfter :many
+SELECT id, email, unsubscribe_token FROM subscribers
+WHERE status = 'confirmed' AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(limit);
+
+-- name: GetLastNewsletterSend :one
+SELECT * FROM newsletter_sends
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateNewsletterSend :one
+INSERT INTO newsletter_sends (subject, since, until, entry_count)
+VALUES (sqlc.arg(subject), CAST(sqlc.arg(since) AS TEXT), CAST(sqlc.arg(until) AS TEXT), sqlc.arg(entry_count))
+RETURNING *;
+
+-- name: GetNewsletterSend :one
+SELECT * FROM newsletter_sends
+WHERE id = ?;
+
+-- name: RecordNewsletterDelivery :exec
+UPDATE newsletter_sends
+SET last_subscriber_id = sqlc.arg(subscriber_id),
+  delivered_count = delivered_count + sqlc.arg(delivered),
+  failed_count = failed_count + sqlc.arg(failed)
+WHERE id = sqlc.arg(id);
+
+-- name: FinishNewsletterSend :exec
+UPDATE newsletter_sends
+SET status = 'sent', finished_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: ListNewsletterSends :many
+SELECT * FROM newsletter_sends
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountNewsletterSends :one
+SELECT COUNT(*) FROM newsletter_sends;
+
+-- name: ListJournalsForDigest :many
+-- Public entries created in (since, until], oldest first.
+SELECT id, title, excerpt, seo_description, created_at FROM journal_entries
+WHERE created_at > CAST(sqlc.arg(since) AS TEXT)
+  AND created_at <= CAST(sqlc.arg(until) AS TEXT)
+  AND deleted_at IS NULL
+  AND visibility = 'public'
+ORDER BY created_at, id;
@@ -1,15 +1,17 @@
 -- name: CreateProject :one
-INSERT INTO projects (title, description, image_url, link, github, status, user_id)
-VALUES (?, ?, ?, ?, ?, ?, ?)
+INSERT INTO projects (title, description, image_url, link, github, status, user_id, seo_title, seo_description)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetProjects :many
-SELECT * FROM projects 
+SELECT * FROM projects
+WHERE deleted_at IS NULL
 ORDER BY created_at DESC
 LIMIT ? OFFSET ?;
 
 -- name: GetProjectsCount :one
-SELECT COUNT(*) as count FROM projects;
+SELECT COUNT(*) as count FROM projects
+WHERE deleted_at IS NULL;
 
 -- name: GetProjectsNextAndPrevious :one
 WITH ordered AS (
@@ -18,6 +20,7 @@ WITH ordered AS (
     LAG(id) OVER (ORDER BY id) AS previous_id,
     LEAD(id) OVER (ORDER BY id) AS next_id
   FROM projects
+  WHERE deleted_at IS NULL
 )
 SELECT * FROM ordered WHERE id = ?;
 
@@ -31,18 +34,22 @@ SELECT
   projects.github,
   projects.status,
   projects.created_at,
+  projects.updated_at,
   projects.user_id,
+  projects.seo_title,
+  projects.seo_description,
   tags.id as tag_id,
-  GROUP_CONCAT(tags.name, ', ') as tags
+  CAST(COALESCE(GROUP_CONCAT(tags.name, ', '), '') AS TEXT) as tags
 FROM projects
 LEFT JOIN project_tags ON projects.id = project_tags.project_id
 LEFT JOIN tags ON project_tags.tag_id = tags.id
-WHERE projects.id = ?
+WHERE projects.id = ? AND projects.deleted_at IS NULL
 ORDER BY projects.created_at DESC;
 
--- name: DeleteProject :exec
-DELETE FROM projects
-WHERE id = ?;
+-- name: TrashProject :exec
+UPDATE projects
+set deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL;
 
 -- name: UpdateProject :exec
 UPDATE projects
@@ -51,5 +58,21 @@ description = ?,
 image_url = ?,
 link = ?,
 github = ?,
-status = ?
-WHERE id = ?;
\ No newline at end of file
+status = ?,
+seo_title = ?,
+seo_description = ?,
+updated_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL;
+-- name: GetProjectsByTag :many
+SELECT projects.* FROM projects
+JOIN project_tags ON project_tags.project_id = projects.id
+JOIN tags ON tags.id = project_tags.tag_id
+WHERE tags.name = ? AND projects.deleted_at IS NULL
+ORDER BY projects.created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: GetProjectsByTagCount :one
+SELECT COUNT(*) as count FROM projects
+JOIN project_tags ON project_tags.project_id = projects.id
+JOIN tags ON tags.id = project_tags.tag_id
+WHERE tags.name = ? AND projects.deleted_at IS NULL;
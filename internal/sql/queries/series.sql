@@ -0,0 +1,86 @@
+-- name: CreateSeries :one
+INSERT INTO series (title, description, user_id)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: UpdateSeries :exec
+UPDATE series
+set title = ?,
+description = ?,
+updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: GetSeries :one
+SELECT * FROM series
+WHERE id = ?;
+
+-- name: ListSeries :many
+SELECT series.*, COUNT(journal_entries.id) AS entry_count
+FROM series
+LEFT JOIN series_entries ON series_entries.series_id = series.id
+LEFT JOIN journal_entries ON journal_entries.id = series_entries.journal_id
+  AND journal_entries.deleted_at IS NULL
+  AND journal_entries.visibility = 'public'
+GROUP BY series.id
+ORDER BY series.id DESC;
+
+-- name: DeleteSeries :exec
+DELETE FROM series
+WHERE id = ?;
+
+-- name: ListSeriesEntries :many
+SELECT
+  series_entries.position,
+  journal_entries.id,
+  journal_entries.title,
+  journal_entries.excerpt,
+  journal_entries.reading_minutes,
+  journal_entries.created_at
+FROM series_entries
+JOIN journal_entries ON journal_entries.id = series_entries.journal_id
+WHERE series_entries.series_id = ?
+  AND journal_entries.deleted_at IS NULL
+  AND journal_entries.visibility = 'public'
+ORDER BY series_entries.position;
+
+-- name: ClearSeriesEntries :exec
+DELETE FROM series_entries
+WHERE series_id = ?;
+
+-- name: ClearUntrashedSeriesEntries :exec
+DELETE FROM series_entries
+WHERE series_id = ?
+  AND journal_id NOT IN (SELECT id FROM journal_entries WHERE deleted_at IS NOT NULL);
+
+-- name: ListTrashedSeriesPositions :many
+SELECT series_entries.position
+FROM series_entries
+JOIN journal_entries ON journal_entries.id = series_entries.journal_id
+WHERE series_entries.series_id = ?
+  AND journal_entries.deleted_at IS NOT NULL;
+
+-- name: RemoveJournalFromSeries :exec
+DELETE FROM series_entries
+WHERE journal_id = ?;
+
+-- name: AddSeriesEntry :exec
+INSERT INTO series_entries (series_id, journal_id, position)
+VALUES (?, ?, ?);
+
+-- name: GetJournalSeriesNav :one
+WITH ordered AS (
+  SELECT
+    series_entries.series_id,
+    series.title,
+    series_entries.journal_id,
+    ROW_NUMBER() OVER (PARTITION BY series_entries.series_id ORDER BY series_entries.position) AS part,
+    COUNT(*) OVER (PARTITION BY series_entries.series_id) AS total,
+    LAG(series_entries.journal_id) OVER (PARTITION BY series_entries.series_id ORDER BY series_entries.position) AS previous_id,
+    LEAD(series_entries.journal_id) OVER (PARTITION BY series_entries.series_id ORDER BY series_entries.position) AS next_id
+  FROM series_entries
+  JOIN series ON series.id = series_entries.series_id
+  JOIN journal_entries ON journal_entries.id = series_entries.journal_id
+  WHERE journal_entries.deleted_at IS NULL AND journal_entries.visibility = 'public'
+)
+SELECT series_id, title, CAST(part AS INTEGER) AS part, total, previous_id, next_id FROM ordered
+WHERE journal_id = ?;
@@ -0,0 +1,16 @@
+-- name: GetSiteSettings :one
+SELECT * FROM site_settings
+WHERE id = 1;
+
+-- name: UpdateSiteSettings :exec
+UPDATE site_settings
+set site_title = ?,
+tagline = ?,
+footer_text = ?,
+social_links = ?,
+default_og_image = ?,
+posts_per_page = ?,
+timezone = ?,
+robots_txt = ?,
+updated_at = CURRENT_TIMESTAMP
+WHERE id = 1;
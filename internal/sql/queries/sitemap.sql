@@ -0,0 +1,16 @@
+-- name: ListSitemapJournals :many
+SELECT id, created_at, updated_at FROM journal_entries
+WHERE deleted_at IS NULL AND visibility = 'public'
+ORDER BY id;
+
+-- name: ListSitemapProjects :many
+SELECT id, created_at, updated_at FROM projects
+WHERE deleted_at IS NULL
+ORDER BY id;
+
+-- name: ListSitemapTags :many
+SELECT tags.name, projects.updated_at FROM tags
+JOIN project_tags ON project_tags.tag_id = tags.id
+JOIN projects ON projects.id = project_tags.project_id
+WHERE projects.deleted_at IS NULL
+ORDER BY tags.name;
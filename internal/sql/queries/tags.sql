@@ -12,4 +12,10 @@ WHERE name = ?;
 
 -- name: SearchTags :many
 SELECT id, name as value FROM tags
-WHERE name LIKE ?;
\ No newline at end of file
+WHERE name LIKE ?;
+
+-- name: UpsertTag :one
+INSERT INTO tags (name)
+VALUES (?)
+ON CONFLICT (name) DO UPDATE SET name = excluded.name
+RETURNING *;
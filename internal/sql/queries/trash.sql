@@ -0,0 +1,37 @@
+-- name: ListTrashedJournals :many
+SELECT id, title, deleted_at FROM journal_entries
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: ListTrashedProjects :many
+SELECT id, title, deleted_at FROM projects
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreJournalEntry :execrows
+UPDATE journal_entries
+set deleted_at = NULL
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: RestoreProject :execrows
+UPDATE projects
+set deleted_at = NULL
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: PurgeJournalEntry :execrows
+DELETE FROM journal_entries
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: PurgeProject :execrows
+DELETE FROM projects
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: ListJournalsTrashedBefore :many
+-- Cutoffs are UTC "YYYY-MM-DD HH:MM:SS" strings, the format
+-- CURRENT_TIMESTAMP stores.
+SELECT id FROM journal_entries
+WHERE deleted_at IS NOT NULL AND deleted_at < CAST(sqlc.arg(cutoff) AS TEXT);
+
+-- name: ListProjectsTrashedBefore :many
+SELECT id FROM projects
+WHERE deleted_at IS NOT NULL AND deleted_at < CAST(sqlc.arg(cutoff) AS TEXT);
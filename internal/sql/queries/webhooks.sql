@@ -0,0 +1,87 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (url, secret, events, description)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = ?;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+ORDER BY id;
+
+-- name: ListActiveWebhooks :many
+SELECT * FROM webhooks
+WHERE active
+ORDER BY id;
+
+-- name: UpdateWebhook :execrows
+UPDATE webhooks
+SET url = ?, events = ?, description = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = ?;
+
+-- name: DeleteWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+WHERE webhook_id = ?;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+VALUES (?, ?, ?)
+RETURNING id;
+
+-- name: SetWebhookDeliveryJob :exec
+UPDATE webhook_deliveries
+SET job_id = ?
+WHERE id = ?;
+
+-- name: GetWebhookDeliveryToSend :one
+SELECT webhook_deliveries.id, webhook_deliveries.event, webhook_deliveries.payload,
+  webhook_deliveries.status, webhooks.url, webhooks.secret
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhook_deliveries.id = ?;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, response_status = ?, response_body = ?,
+  last_error = '', delivered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: RetryWebhookDelivery :exec
+-- A failed delivery whose job is retried from /api/jobs is pending again.
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = attempts + 1, response_status = ?, response_body = ?,
+  last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'failed', attempts = attempts + 1, response_status = ?, response_body = ?,
+  last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: ListWebhookDeliveries :many
+-- next_attempt_at is when the delivery's job runs next, if it is waiting.
+SELECT webhook_deliveries.id, webhook_deliveries.webhook_id, webhook_deliveries.event,
+  webhook_deliveries.payload, webhook_deliveries.status, webhook_deliveries.attempts,
+  webhook_deliveries.response_status, webhook_deliveries.response_body,
+  webhook_deliveries.last_error, webhook_deliveries.delivered_at, webhook_deliveries.created_at,
+  jobs.run_at AS next_attempt_at
+FROM webhook_deliveries
+LEFT JOIN jobs ON jobs.id = webhook_deliveries.job_id AND jobs.status = 'pending'
+WHERE webhook_deliveries.webhook_id = sqlc.arg(webhook_id)
+ORDER BY webhook_deliveries.id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: CountWebhookDeliveries :one
+SELECT COUNT(*) FROM webhook_deliveries
+WHERE webhook_id = ?;
+
+-- name: DeleteOldWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE status != 'pending' AND updated_at < CAST(sqlc.arg(cutoff) AS TEXT);
@@ -0,0 +1,50 @@
+-- name: UpsertWebmention :one
+INSERT INTO webmentions (journal_id, source, target)
+VALUES (?, ?, ?)
+ON CONFLICT (source, target) DO UPDATE SET
+  journal_id = excluded.journal_id,
+  status = 'pending',
+  updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: ListPendingWebmentions :many
+SELECT * FROM webmentions
+WHERE status = 'pending'
+ORDER BY updated_at, id
+LIMIT ?;
+
+-- name: MarkWebmentionVerified :exec
+UPDATE webmentions
+SET status = 'verified', title = ?, author_name = ?,
+  verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: MarkWebmentionInvalid :exec
+UPDATE webmentions
+SET status = 'invalid', updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: ListVerifiedWebmentions :many
+SELECT * FROM webmentions
+WHERE journal_id = ? AND status = 'verified'
+ORDER BY verified_at, id;
+
+-- name: ListWebmentions :many
+SELECT webmentions.id, webmentions.journal_id, webmentions.source, webmentions.target,
+  webmentions.status, webmentions.title, webmentions.author_name, webmentions.verified_at,
+  webmentions.created_at, journal_entries.title AS journal_title
+FROM webmentions
+JOIN journal_entries ON journal_entries.id = webmentions.journal_id
+ORDER BY webmentions.updated_at DESC, webmentions.id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountWebmentions :one
+SELECT COUNT(*) FROM webmentions;
+
+-- name: DeleteWebmention :execrows
+DELETE FROM webmentions
+WHERE id = ?;
+
+-- name: DeleteJournalWebmentions :exec
+DELETE FROM webmentions
+WHERE journal_id = ?;
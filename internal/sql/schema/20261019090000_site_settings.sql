@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE site_settings(
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  site_title TEXT NOT NULL DEFAULT 'My Journal',
+  tagline TEXT NOT NULL DEFAULT '',
+  footer_text TEXT NOT NULL DEFAULT '',
+  social_links TEXT NOT NULL DEFAULT '[]',
+  default_og_image TEXT NOT NULL DEFAULT '',
+  posts_per_page INTEGER NOT NULL DEFAULT 20,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO site_settings (id, site_title, footer_text)
+VALUES (1, 'Sianwa', 'Built with passion ❤️.');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS site_settings;
+-- +goose StatementEnd
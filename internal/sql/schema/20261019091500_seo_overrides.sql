@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN seo_title TEXT;
+ALTER TABLE journal_entries ADD COLUMN seo_description TEXT;
+ALTER TABLE projects ADD COLUMN seo_title TEXT;
+ALTER TABLE projects ADD COLUMN seo_description TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN seo_title;
+ALTER TABLE journal_entries DROP COLUMN seo_description;
+ALTER TABLE projects DROP COLUMN seo_title;
+ALTER TABLE projects DROP COLUMN seo_description;
+-- +goose StatementEnd
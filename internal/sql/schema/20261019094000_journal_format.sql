@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN format TEXT NOT NULL DEFAULT 'html' CHECK (format IN ('html', 'markdown'));
+ALTER TABLE journal_entries ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN content_html;
+ALTER TABLE journal_entries DROP COLUMN format;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cached HTML predates heading anchors; it is re-rendered on next view
+UPDATE journal_entries SET content_html = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN word_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE journal_entries ADD COLUMN reading_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE journal_entries ADD COLUMN excerpt TEXT NOT NULL DEFAULT '';
+-- Existing rows get their stats filled in when they are next rendered
+UPDATE journal_entries SET content_html = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN excerpt;
+ALTER TABLE journal_entries DROP COLUMN reading_minutes;
+ALTER TABLE journal_entries DROP COLUMN word_count;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN deleted_at DATETIME;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE projects ADD COLUMN deleted_at DATETIME;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- The archive queries filter on deleted_at too; keep them index-only.
+DROP INDEX IF EXISTS idx_journal_entries_created_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at, deleted_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_journal_entries_deleted_at ON journal_entries(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_projects_deleted_at ON projects(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_projects_deleted_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_journal_entries_deleted_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_journal_entries_created_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE projects DROP COLUMN deleted_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'unlisted', 'private'));
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE journal_entries ADD COLUMN share_token TEXT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX idx_journal_entries_share_token ON journal_entries(share_token) WHERE share_token IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Public pages only list public entries; keep the archive queries index-only.
+DROP INDEX IF EXISTS idx_journal_entries_created_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at, deleted_at, visibility);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_journal_entries_created_at;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_journal_entries_created_at ON journal_entries(created_at, deleted_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_journal_entries_share_token;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN share_token;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN visibility;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Encrypted entries hold an envelope in content that only the owner's
+-- browser can open, so they are never rendered or summarised.
+ALTER TABLE journal_entries ADD COLUMN encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE journal_entries DROP COLUMN encrypted;
+-- +goose StatementEnd
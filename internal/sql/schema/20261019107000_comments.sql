@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Reader comments wait in the moderation queue as 'pending'. Owner replies
+-- hang off a top-level comment through parent_id and go straight to
+-- 'approved'.
+CREATE TABLE comments (
+    id INTEGER PRIMARY KEY,
+    journal_id INTEGER NOT NULL,
+    parent_id INTEGER,
+    author_name TEXT NOT NULL,
+    author_email TEXT NOT NULL DEFAULT '',
+    author_url TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'spam')),
+    is_owner BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (journal_id) REFERENCES journal_entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_comments_journal_id ON comments(journal_id, status);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_comments_status ON comments(status, created_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE comments;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Received Webmentions start 'pending' and are checked in the background.
+-- A source that still links to the target becomes 'verified' and is shown
+-- under the entry; one that does not is kept as 'invalid'. Re-sending the
+-- same source and target resets the row to 'pending'.
+CREATE TABLE webmentions (
+    id INTEGER PRIMARY KEY,
+    journal_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    target TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'verified', 'invalid')),
+    title TEXT NOT NULL DEFAULT '',
+    author_name TEXT NOT NULL DEFAULT '',
+    verified_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (source, target),
+    FOREIGN KEY (journal_id) REFERENCES journal_entries(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_webmentions_journal_id ON webmentions(journal_id, status);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_webmentions_status ON webmentions(status, updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webmentions;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Journal entries share the tags table with projects. Micropub posts set
+-- them from the h-entry category property.
+CREATE TABLE journal_tags(
+  journal_id INTEGER NOT NULL,
+  tag_id INTEGER NOT NULL,
+  FOREIGN KEY (journal_id) REFERENCES journal_entries(id) ON DELETE CASCADE,
+  FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (journal_id, tag_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE journal_tags;
+-- +goose StatementEnd
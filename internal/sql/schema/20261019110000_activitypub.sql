@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The site owner's ActivityPub signing key. There is only ever one row;
+-- it is generated the first time it is needed.
+CREATE TABLE activitypub_keys (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    private_key_pem TEXT NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Remote actors following the site owner. shared_inbox is empty when the
+-- follower's server has none.
+CREATE TABLE activitypub_followers (
+    id INTEGER PRIMARY KEY,
+    actor_id TEXT NOT NULL UNIQUE,
+    inbox TEXT NOT NULL,
+    shared_inbox TEXT NOT NULL DEFAULT '',
+    follow_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Outgoing activities waiting to be POSTed to an inbox. Delivered rows are
+-- deleted; failed attempts are retried at next_attempt_at with backoff
+-- until the row is given up on and marked 'failed'.
+CREATE TABLE activitypub_deliveries (
+    id INTEGER PRIMARY KEY,
+    inbox TEXT NOT NULL,
+    activity TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_activitypub_deliveries_due ON activitypub_deliveries(status, next_attempt_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE activitypub_deliveries;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE activitypub_followers;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE activitypub_keys;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Newsletter subscribers. Addresses start 'pending' until the link sent to
+-- them is followed; unsubscribe_token is in every email so readers can
+-- leave without logging in.
+CREATE TABLE subscribers (
+    id INTEGER PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'unsubscribed')),
+    confirm_token TEXT NOT NULL UNIQUE,
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    confirmed_at DATETIME,
+    unsubscribed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- One row per digest. A digest covers public entries created after since
+-- and up to until; the next one starts where this one ended.
+-- last_subscriber_id records progress so an interrupted send resumes
+-- without emailing anyone twice.
+CREATE TABLE newsletter_sends (
+    id INTEGER PRIMARY KEY,
+    subject TEXT NOT NULL,
+    since DATETIME NOT NULL,
+    until DATETIME NOT NULL,
+    entry_count INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'sending' CHECK (status IN ('sending', 'sent')),
+    last_subscriber_id INTEGER NOT NULL DEFAULT 0,
+    delivered_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE newsletter_sends;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE subscribers;
+-- +goose StatementEnd
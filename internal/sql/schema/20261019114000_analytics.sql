@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Views recorded today and not yet rolled up. visitor is a hash of the
+-- visitor's IP address and user agent, salted with analytics_salts.salt
+-- for the day, so it counts unique visitors without identifying them.
+CREATE TABLE page_views (
+    id INTEGER PRIMARY KEY,
+    day TEXT NOT NULL,
+    path TEXT NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    visitor TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_page_views_day ON page_views(day);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- One salt per UTC day. Old salts are deleted so yesterday's hashes can't
+-- be recomputed from an IP address.
+CREATE TABLE analytics_salts (
+    day TEXT PRIMARY KEY,
+    salt TEXT NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Daily totals: dimension 'site' has one row per day for the whole site
+-- (value is empty), 'page' one per path and 'referrer' one per referring
+-- host.
+CREATE TABLE page_view_rollups (
+    day TEXT NOT NULL,
+    dimension TEXT NOT NULL CHECK (dimension IN ('site', 'page', 'referrer')),
+    value TEXT NOT NULL,
+    views INTEGER NOT NULL DEFAULT 0,
+    visitors INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, dimension, value)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE page_view_rollups;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE analytics_salts;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE page_views;
+-- +goose StatementEnd
@@ -0,0 +1,95 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ActivityPub deliveries move onto the job queue. Given-up deliveries
+-- become dead jobs so they can still be retried.
+INSERT INTO jobs (kind, payload, status, attempts, max_attempts, run_at, last_error, created_at, updated_at)
+SELECT 'activitypub.deliver', json_object('inbox', inbox, 'activity', json(activity)),
+  CASE status WHEN 'failed' THEN 'dead' ELSE 'pending' END,
+  attempts, 30, next_attempt_at, last_error, created_at, updated_at
+FROM activitypub_deliveries;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE activitypub_deliveries;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- webhook_deliveries stays as the delivery log; job_id is the job that
+-- sends it, whose run_at is when the next attempt is due.
+ALTER TABLE webhook_deliveries ADD COLUMN job_id INTEGER;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO jobs (kind, payload, attempts, max_attempts, run_at, created_at)
+SELECT 'webhook.deliver', json_object('delivery_id', id), attempts, 11, next_attempt_at, created_at
+FROM webhook_deliveries
+WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE webhook_deliveries
+SET job_id = (
+  SELECT jobs.id FROM jobs
+  WHERE jobs.kind = 'webhook.deliver' AND json_extract(jobs.payload, '$.delivery_id') = webhook_deliveries.id
+)
+WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP COLUMN next_attempt_at;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries ADD COLUMN next_attempt_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE webhook_deliveries
+SET next_attempt_at = (SELECT jobs.run_at FROM jobs WHERE jobs.id = webhook_deliveries.job_id)
+WHERE job_id IN (SELECT id FROM jobs);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP COLUMN job_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE activitypub_deliveries (
+    id INTEGER PRIMARY KEY,
+    inbox TEXT NOT NULL,
+    activity TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_activitypub_deliveries_due ON activitypub_deliveries(status, next_attempt_at);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO activitypub_deliveries (inbox, activity, status, attempts, last_error, next_attempt_at)
+SELECT json_extract(payload, '$.inbox'), json_extract(payload, '$.activity'),
+  CASE status WHEN 'dead' THEN 'failed' ELSE 'pending' END,
+  attempts, last_error, run_at
+FROM jobs
+WHERE kind = 'activitypub.deliver';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM jobs
+WHERE kind IN ('activitypub.deliver', 'webhook.deliver');
+-- +goose StatementEnd
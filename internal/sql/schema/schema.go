@@ -0,0 +1,110 @@
+// Package schema embeds the goose migrations so the running binary knows
+// which schema version it was built against.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var migrations embed.FS
+
+// Versions returns the version of every embedded migration in ascending order.
+func Versions() ([]int64, error) {
+	files, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int64, 0, len(files))
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q has no version prefix", file)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q has an invalid version: %v", file, err)
+		}
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// Pending returns the embedded migrations that goose has not applied to db.
+func Pending(ctx context.Context, db *sql.DB) ([]int64, error) {
+	versions, err := Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM goose_db_version ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// goose appends a row for every up and down, so the last row wins
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		applied[version] = isApplied
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pending := []int64{}
+	for _, version := range versions {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up runs the up section of every embedded migration against db, oldest
+// first. Unlike goose it records nothing in goose_db_version; it is for
+// throwaway databases, such as in tests, that need the production schema.
+func Up(ctx context.Context, db *sql.DB) error {
+	files, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+
+	// Versions share a width, so the file names sort in version order
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := fs.ReadFile(migrations, file)
+		if err != nil {
+			return err
+		}
+
+		_, up, ok := strings.Cut(string(content), "-- +goose Up")
+		if !ok {
+			return fmt.Errorf("migration %q has no up section", file)
+		}
+		up, _, _ = strings.Cut(up, "-- +goose Down")
+
+		if _, err := db.ExecContext(ctx, up); err != nil {
+			return fmt.Errorf("migration %q: %w", file, err)
+		}
+	}
+
+	return nil
+}
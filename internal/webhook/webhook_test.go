@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"journal.created"}`)
+	now := time.Unix(1760000000, 0)
+
+	header := http.Header{}
+	header.Set(HeaderTimestamp, "1760000000")
+	header.Set(HeaderSignature, Sign("secret", now.Unix(), body))
+
+	if err := Verify("secret", header, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		now    time.Time
+	}{
+		{"wrong secret", "other", string(body), now},
+		{"changed body", "secret", `{"event":"journal.deleted"}`, now},
+		{"stale", "secret", string(body), now.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.secret, header, []byte(tt.body), tt.now, 5*time.Minute); !errors.Is(err, ErrSignature) {
+			t.Errorf("%s: expected ErrSignature, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		filters []string
+		event   string
+		want    bool
+	}{
+		{[]string{"journal.created"}, "journal.created", true},
+		{[]string{"journal.created"}, "journal.deleted", false},
+		{[]string{"comment.*"}, "comment.updated", true},
+		{[]string{"comment.*"}, "project.created", false},
+		{[]string{"project.updated", "*"}, "journal.deleted", true},
+		{nil, "journal.created", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.filters, tt.event); got != tt.want {
+			t.Errorf("Matches(%v, %q) = %v, want %v", tt.filters, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	status := http.StatusOK
+	var verifyErr error
+	var gotEvent, gotDelivery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = Verify("secret", r.Header, body, time.Now(), time.Minute)
+		gotEvent = r.Header.Get(HeaderEvent)
+		gotDelivery = r.Header.Get(HeaderDelivery)
+		w.WriteHeader(status)
+		w.Write([]byte("thanks"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), "test-agent")
+	delivery := Delivery{ID: 7, URL: server.URL, Secret: "secret", Event: "project.created", Payload: []byte(`{}`)}
+
+	resp, err := client.Send(context.Background(), delivery)
+	if err != nil || resp.Status != http.StatusOK || resp.Body != "thanks" {
+		t.Fatalf("Unexpected response %+v, %v", resp, err)
+	}
+	if verifyErr != nil || gotEvent != "project.created" || gotDelivery != "7" {
+		t.Errorf("Unexpected request: verify %v, event %q, delivery %q", verifyErr, gotEvent, gotDelivery)
+	}
+
+	status = http.StatusInternalServerError
+	resp, err = client.Send(context.Background(), delivery)
+	if err == nil || resp.Status != http.StatusInternalServerError {
+		t.Errorf("Expected an error with the 500 response, got %+v, %v", resp, err)
+	}
+}
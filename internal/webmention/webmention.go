@@ -0,0 +1,376 @@
+// Package webmention implements both halves of the W3C Webmention
+// protocol: discovering a page's endpoint and notifying it, and verifying
+// that the source of a received mention really links to its target.
+//
+// Every request goes through a Doer so tests can point the client at
+// httptest servers; production uses a safehttp client.
+package webmention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxBodyBytes caps how much of a fetched page is read.
+const maxBodyBytes = 1 << 20
+
+var (
+	// ErrNoEndpoint means the target does not advertise a Webmention endpoint.
+	ErrNoEndpoint = errors.New("webmention: no endpoint found")
+
+	// ErrGone means the source answered 410 Gone, so any stored mention
+	// should be removed.
+	ErrGone = errors.New("webmention: source is gone")
+
+	// ErrNoLink means the source was fetched but does not link to the target.
+	ErrNoLink = errors.New("webmention: source does not link to target")
+)
+
+// Doer sends an HTTP request. *http.Client satisfies it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client discovers endpoints, sends mentions and verifies received ones.
+type Client struct {
+	http      Doer
+	userAgent string
+}
+
+// NewClient returns a Client that makes its requests through doer.
+func NewClient(doer Doer, userAgent string) *Client {
+	return &Client{http: doer, userAgent: userAgent}
+}
+
+// Source describes the page a verified mention came from.
+type Source struct {
+	Title  string
+	Author string
+}
+
+// Discover returns the Webmention endpoint advertised by target, resolved
+// to an absolute URL. The HTTP Link header wins over the document; within
+// the document the first <link> or <a> with rel="webmention" is used.
+func (c *Client) Discover(ctx context.Context, target string) (string, error) {
+	resp, err := c.get(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("webmention: fetching %s: %s", target, resp.Status)
+	}
+
+	// Relative endpoints resolve against the URL we ended up at
+	base := resp.Request.URL
+
+	for _, header := range resp.Header.Values("Link") {
+		if href, ok := linkHeaderEndpoint(header); ok {
+			return resolve(base, href)
+		}
+	}
+
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		return "", ErrNoEndpoint
+	}
+
+	href, ok := documentEndpoint(io.LimitReader(resp.Body, maxBodyBytes))
+	if !ok {
+		return "", ErrNoEndpoint
+	}
+	return resolve(base, href)
+}
+
+// Send tells target that source mentions it, discovering the endpoint
+// first. It returns ErrNoEndpoint when target takes no mentions.
+func (c *Client) Send(ctx context.Context, source, target string) error {
+	endpoint, err := c.Discover(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"source": {source}, "target": {target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.setUserAgent(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webmention: %s rejected mention: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Verify fetches source and checks that it links to target. It returns
+// ErrGone when the source has been deleted and ErrNoLink when it no longer
+// mentions the target.
+func (c *Client) Verify(ctx context.Context, source, target string) (Source, error) {
+	resp, err := c.get(ctx, source)
+	if err != nil {
+		return Source{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return Source{}, ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return Source{}, fmt.Errorf("webmention: fetching %s: %s", source, resp.Status)
+	}
+
+	body := io.LimitReader(resp.Body, maxBodyBytes)
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		// Plain text and JSON sources only need to contain the URL
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return Source{}, err
+		}
+		if !strings.Contains(string(content), target) {
+			return Source{}, ErrNoLink
+		}
+		return Source{}, nil
+	}
+
+	page, linked := inspect(body, resp.Request.URL, target)
+	if !linked {
+		return Source{}, ErrNoLink
+	}
+	return page, nil
+}
+
+// OutboundLinks returns the distinct http(s) links in renderedHTML that
+// point away from source's host, resolved against source.
+func OutboundLinks(renderedHTML, source string) []string {
+	base, err := url.Parse(source)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	seen := map[string]bool{}
+	z := html.NewTokenizer(strings.NewReader(renderedHTML))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return links
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := z.Token()
+		if token.DataAtom != atom.A {
+			continue
+		}
+		href, ok := attr(token, "href")
+		if !ok {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		if strings.EqualFold(u.Host, base.Host) {
+			continue
+		}
+
+		u.Fragment = ""
+		link := u.String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html, */*;q=0.5")
+	c.setUserAgent(req)
+	return c.http.Do(req)
+}
+
+func (c *Client) setUserAgent(req *http.Request) {
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
+func resolve(base *url.URL, href string) (string, error) {
+	u, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", fmt.Errorf("webmention: bad endpoint %q: %w", href, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("webmention: bad endpoint %q", href)
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// linkHeaderEndpoint finds the first rel="webmention" link in one Link
+// header value, which may hold several comma-separated links.
+func linkHeaderEndpoint(header string) (string, bool) {
+	for _, link := range splitOutside(header, ',') {
+		link = strings.TrimSpace(link)
+		if !strings.HasPrefix(link, "<") {
+			continue
+		}
+		end := strings.Index(link, ">")
+		if end < 0 {
+			continue
+		}
+
+		href := link[1:end]
+		for _, param := range splitOutside(link[end+1:], ';') {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+				continue
+			}
+			if hasRel(strings.Trim(strings.TrimSpace(value), `"`)) {
+				return href, true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitOutside splits s on sep, ignoring separators inside <...> or quotes.
+func splitOutside(s string, sep rune) []string {
+	var parts []string
+	inURL, inQuote := false, false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"' && !inURL:
+			inQuote = !inQuote
+		case r == '<' && !inQuote:
+			inURL = true
+		case r == '>' && !inQuote:
+			inURL = false
+		case r == sep && !inURL && !inQuote:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func hasRel(rel string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, "webmention") {
+			return true
+		}
+	}
+	return false
+}
+
+// documentEndpoint returns the href of the first <link> or <a> element
+// with rel="webmention". An empty href is valid and means the page itself.
+func documentEndpoint(r io.Reader) (string, bool) {
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return "", false
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := z.Token()
+		if token.DataAtom != atom.Link && token.DataAtom != atom.A {
+			continue
+		}
+		rel, _ := attr(token, "rel")
+		href, ok := attr(token, "href")
+		if ok && hasRel(rel) {
+			return href, true
+		}
+	}
+}
+
+// inspect reads an HTML source page, reporting whether any link or
+// embedded media points at target along with the page's title and author.
+func inspect(r io.Reader, base *url.URL, target string) (Source, bool) {
+	var page Source
+	linked := false
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			page.Title = strings.Join(strings.Fields(page.Title), " ")
+			return page, linked
+		case html.EndTagToken:
+			if name, _ := z.TagName(); atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				page.Title += string(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			switch token.DataAtom {
+			case atom.Title:
+				inTitle = page.Title == "" && tt == html.StartTagToken
+			case atom.Meta:
+				if name, _ := attr(token, "name"); strings.EqualFold(name, "author") {
+					page.Author, _ = attr(token, "content")
+				}
+			case atom.A:
+				linked = linked || refersTo(token, "href", base, target)
+			case atom.Img, atom.Video, atom.Audio, atom.Source:
+				linked = linked || refersTo(token, "src", base, target)
+			}
+		}
+	}
+}
+
+func refersTo(token html.Token, key string, base *url.URL, target string) bool {
+	value, ok := attr(token, key)
+	if !ok {
+		return false
+	}
+	u, err := base.Parse(strings.TrimSpace(value))
+	return err == nil && u.String() == target
+}
+
+func attr(token html.Token, key string) (string, bool) {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,170 @@
+package webmention
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name        string
+		link        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"absolute link header", `<https://endpoint.example/wm>; rel="webmention"`, "text/html", "", "https://endpoint.example/wm"},
+		{"relative link header", `</wm?x=1>; rel=webmention`, "text/html", "", "/wm?x=1"},
+		{"one of several links", `<https://a.example/>; rel="other", </wm>; rel="webmention other"`, "text/html", "", "/wm"},
+		{"link element", "", "text/html; charset=utf-8", `<html><head><link rel="webmention" href="/from-link"></head></html>`, "/from-link"},
+		{"anchor element", "", "text/html", `<p><a href="/nope">x</a> <a rel="webmention" href="from-a">y</a></p>`, "/posts/from-a"},
+		{"first in document wins", "", "text/html", `<link rel="webmention" href="/first"><a rel="webmention" href="/second">`, "/first"},
+		{"empty href is the page itself", "", "text/html", `<link rel="webmention" href="">`, "/posts/page"},
+		{"header beats document", `</header>; rel="webmention"`, "text/html", `<link rel="webmention" href="/body">`, "/header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/start" {
+					http.Redirect(w, r, "/posts/page", http.StatusFound)
+					return
+				}
+				if tt.link != "" {
+					w.Header().Set("Link", tt.link)
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := NewClient(srv.Client(), "test")
+			got, err := client.Discover(context.Background(), srv.URL+"/start")
+			if err != nil {
+				t.Fatalf("Discover: %v", err)
+			}
+
+			want := tt.want
+			if want[0] == '/' {
+				want = srv.URL + want
+			}
+			if got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/elsewhere">no endpoint here</a>`))
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.Client(), "").Discover(context.Background(), srv.URL)
+	if !errors.Is(err, ErrNoEndpoint) {
+		t.Errorf("Expected ErrNoEndpoint, got %v", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got struct{ source, target, contentType string }
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</webmention>; rel="webmention"`)
+	})
+	mux.HandleFunc("POST /webmention", func(w http.ResponseWriter, r *http.Request) {
+		got.contentType = r.Header.Get("Content-Type")
+		got.source = r.FormValue("source")
+		got.target = r.FormValue("target")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewClient(srv.Client(), "test")
+	if err := client.Send(context.Background(), "https://me.example/journals/1", srv.URL+"/post"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.source != "https://me.example/journals/1" || got.target != srv.URL+"/post" {
+		t.Errorf("Endpoint received source=%q target=%q", got.source, got.target)
+	}
+	if got.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Unexpected content type %q", got.contentType)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const target = "https://me.example/journals/1"
+
+	pages := map[string]string{
+		"/absolute": `<html><head><title> A reply </title><meta name="author" content="Ada"></head>` +
+			`<body><a href="https://me.example/journals/1">you said</a></body></html>`,
+		"/image":    `<img src="https://me.example/journals/1">`,
+		"/unlinked": `<a href="https://me.example/journals/2">wrong entry</a>`,
+		"/text":     "plain text mentioning " + target,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/deleted" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.Client(), "test")
+
+	source, err := client.Verify(context.Background(), srv.URL+"/absolute", target)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if source.Title != "A reply" || source.Author != "Ada" {
+		t.Errorf("Unexpected source details %+v", source)
+	}
+
+	tests := []struct {
+		path string
+		want error
+	}{
+		{"/image", nil},
+		{"/text", nil},
+		{"/unlinked", ErrNoLink},
+		{"/deleted", ErrGone},
+	}
+	for _, tt := range tests {
+		if _, err := client.Verify(context.Background(), srv.URL+tt.path, target); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.path, err, tt.want)
+		}
+	}
+
+	if _, err := client.Verify(context.Background(), srv.URL+"/missing", target); err == nil {
+		t.Error("Expected a 404 source to fail verification")
+	}
+}
+
+func TestOutboundLinks(t *testing.T) {
+	content := `<p><a href="https://a.example/post#frag">one</a> <a href="/journals/2">internal</a>
+		<a href="https://me.example/about">own site</a> <a href="mailto:x@y.z">mail</a>
+		<a href="https://a.example/post">dupe</a> <a href="http://b.example">two</a></p>`
+
+	got := OutboundLinks(content, "https://me.example/journals/1")
+	want := []string{"https://a.example/post", "http://b.example"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -1,20 +1,52 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/sianwa11/my-journal/internal/api/routes"
 )
 
+// shutdownTimeout is how long requests and background jobs get to finish
+// after a shutdown signal.
+const shutdownTimeout = 8 * time.Second
+
+// setupLogger configures the default slog logger from LOG_FORMAT ("json" or
+// "text") and LOG_LEVEL ("debug", "info", "warn" or "error").
+func setupLogger() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
 func main() {
+	setupLogger()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	routes := routes.SetupRoutes()
+	routes, stopBackground := routes.SetupRoutes()
 
 	server := &http.Server{
 		Addr:           ":" + port,
@@ -23,8 +55,34 @@ func main() {
 		WriteTimeout:   15 * time.Second, // Time to write response
 		IdleTimeout:    60 * time.Second, // Time to keep connection alive
 		MaxHeaderBytes: 1 << 20,          // 1 MB max header size
+		ErrorLog:       slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
 	}
 
-	log.Printf("Serving on: http://localhost:%s/api/\n", port)
-	log.Fatal(server.ListenAndServe())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "url", "http://localhost:"+port+"/api/")
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+
+	// Finish in-flight requests, then give running jobs the rest of the
+	// grace period; Cloud Run allows 10 seconds after SIGTERM
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("failed to shut down server", "error", err)
+	}
+	if err := stopBackground(shutdownCtx); err != nil {
+		slog.Warn("background jobs still running at shutdown", "error", err)
+	}
 }
@@ -3,6 +3,9 @@
 # Build CSS first
 npx tailwindcss -i ./static/css/input.css -o ./static/css/output.css --minify
 
+# Copy the Argon2 build used by encrypted entries into static/js
+npm run vendor
+
 
 # Enable CGO for SQLite support
 CGO_ENABLED=1 GOOS=linux GOARCH=amd64 go build -o my-journal
\ No newline at end of file